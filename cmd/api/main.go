@@ -8,10 +8,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/maqsatto/Notes-API/internal/auth"
 	"github.com/maqsatto/Notes-API/internal/config"
 	"github.com/maqsatto/Notes-API/internal/database"
 	"github.com/maqsatto/Notes-API/internal/http/router"
+	"github.com/maqsatto/Notes-API/internal/jobs"
 	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/mail"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/validator"
 )
 
 
@@ -39,12 +45,67 @@ func main() {
 	defer db.Close()
 	fmt.Println("DB connected")
 
+	var (
+		jwtm *auth.JWTManager
+		keys *auth.KeySet
+	)
+	if cfg.JWT.UsesKeys() {
+		keys, err = auth.LoadKeySet(cfg.JWT.KeysDir, cfg.JWT.KeyFiles, cfg.JWT.ActiveKID, cfg.JWT.AccessTTL)
+		if err != nil {
+			logg.Error("failed to load jwt keys", err)
+			return
+		}
+		jwtm = auth.NewKeyedJWTManager(keys, cfg.JWT.Issuer, cfg.JWT.AccessTTL)
+	} else {
+		jwtm = auth.NewJWTManager(cfg.JWT.Secret, cfg.JWT.Issuer, cfg.JWT.AccessTTL)
+	}
+
+	mailer, err := newMailer(cfg.Mail, logg)
+	if err != nil {
+		logg.Error("failed to set up mailer", err)
+		return
+	}
+
+	var breached *validator.BreachedPasswords
+	if cfg.Password.BreachedDir != "" {
+		breached, err = validator.NewBreachedPasswords(cfg.Password.BreachedDir, cfg.Password.BreachedMinCount)
+		if err != nil {
+			logg.Error("failed to open breached password list", err)
+			return
+		}
+	}
+	passwordPolicy := validator.NewPasswordPolicy(cfg.Password.RejectCommon, cfg.Password.RejectPersonal, breached)
+
+	var loginAttempts repository.LoginAttemptStore
+	if cfg.Lockout.Store == "postgres" {
+		loginAttempts = repository.NewLoginAttemptRepo(db)
+	} else {
+		loginAttempts = repository.NewMemoryLoginAttemptStore()
+	}
+
+	oidcClient := &http.Client{Timeout: 10 * time.Second}
+	var oidcProviders []*auth.OIDCProvider
+	for _, p := range cfg.OIDC.Providers {
+		oidcProviders = append(oidcProviders, auth.NewOIDCProvider(p.Name, p.Issuer, p.ClientID, p.ClientSecret, p.Scopes, cfg.OIDC.RedirectURL, oidcClient))
+	}
+
+	// collaborative editing sessions are shared by the router and the
+	// listener fanning edits out between instances
+	collabSvc := service.NewCollabService(repository.NewTxManager(db), repository.NewCollabRepo(db), repository.NewNoteRepo(db), repository.NewUserRepo(db), logg, cfg.Collab.PersistInterval, cfg.Collab.PresenceTTL)
+
 	// build Server
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
 	h := router.New(router.Deps{
 		Config: cfg,
 		Logger: logg,
-		DB: db,
+		DB:     db,
+		JWT:    jwtm,
+		Mailer: mailer,
+
+		PasswordPolicy: passwordPolicy,
+		LoginAttempts:  loginAttempts,
+		OIDCProviders:  oidcProviders,
+		Collab:         collabSvc,
 	})
 	srv := &http.Server{
 		Addr:         addr,
@@ -59,6 +120,93 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// background jobs
+	go database.Listen(ctx, cfg.Database, service.CollabChannel, logg, collabSvc.HandleNotification, collabSvc.Resync)
+
+	revisionRepo := repository.NewRevisionRepo(db)
+	go jobs.Every(ctx, cfg.Revision.PruneInterval, "prune note revisions", logg, func(ctx context.Context) error {
+		n, err := revisionRepo.Prune(ctx, cfg.Revision.KeepLast, cfg.Revision.KeepDays)
+		if err == nil && n > 0 {
+			logg.Info(fmt.Sprintf("pruned %d note revisions", n))
+		}
+		return err
+	})
+
+	noteRepo := repository.NewNoteRepo(db)
+	userRepo := repository.NewUserRepo(db)
+	go jobs.Every(ctx, cfg.Trash.PurgeInterval, "purge trash", logg, func(ctx context.Context) error {
+		notes, err := noteRepo.PurgeDeleted(ctx, cfg.Trash.NoteRetention)
+		if err != nil {
+			return err
+		}
+		users, err := userRepo.PurgeDeleted(ctx, cfg.Trash.UserRetention)
+		if err != nil {
+			return err
+		}
+		if notes > 0 || users > 0 {
+			logg.Info(fmt.Sprintf("purged %d notes and %d users from trash", notes, users))
+		}
+		return nil
+	})
+
+	if keys != nil {
+		go jobs.Every(ctx, cfg.JWT.KeysReloadEvery, "reload jwt keys", logg, func(context.Context) error {
+			return keys.Reload()
+		})
+	}
+
+	sessionRepo := repository.NewSessionRepo(db)
+	twoFactorRepo := repository.NewTwoFactorRepo(db)
+	resetRepo := repository.NewPasswordResetRepo(db)
+	emailTokenRepo := repository.NewEmailTokenRepo(db)
+	oidcStateRepo := repository.NewOIDCStateRepo(db)
+	oauthCodeRepo := repository.NewOAuthCodeRepo(db)
+	shareLinkRepo := repository.NewShareLinkRepo(db)
+	collabRepo := repository.NewCollabRepo(db)
+	go jobs.Every(ctx, cfg.Session.PurgeInterval, "delete expired sessions", logg, func(ctx context.Context) error {
+		sessions, err := sessionRepo.DeleteExpired(ctx)
+		if err != nil {
+			return err
+		}
+		challenges, err := twoFactorRepo.DeleteExpiredChallenges(ctx)
+		if err != nil {
+			return err
+		}
+		resets, err := resetRepo.DeleteExpired(ctx)
+		if err != nil {
+			return err
+		}
+		emailTokens, err := emailTokenRepo.DeleteExpired(ctx)
+		if err != nil {
+			return err
+		}
+		attempts, err := loginAttempts.DeleteStale(ctx, cfg.Lockout.Window)
+		if err != nil {
+			return err
+		}
+		oidcStates, err := oidcStateRepo.DeleteExpired(ctx)
+		if err != nil {
+			return err
+		}
+		oauthCodes, err := oauthCodeRepo.DeleteExpired(ctx)
+		if err != nil {
+			return err
+		}
+		shareLinks, err := shareLinkRepo.DeleteExpired(ctx)
+		if err != nil {
+			return err
+		}
+		edits, err := collabRepo.DeleteEditsBefore(ctx, cfg.Collab.EditRetention)
+		if err != nil {
+			return err
+		}
+		if sessions > 0 || challenges > 0 || resets > 0 || emailTokens > 0 || attempts > 0 || oidcStates > 0 || oauthCodes > 0 || shareLinks > 0 || edits > 0 {
+			logg.Info(fmt.Sprintf("deleted %d expired or revoked sessions, %d login challenges, %d password reset and %d email tokens, %d login attempt counters, %d abandoned OIDC logins, %d OAuth authorization codes, %d share links, %d collaborative edits",
+				sessions, challenges, resets, emailTokens, attempts, oidcStates, oauthCodes, shareLinks, edits))
+		}
+		return nil
+	})
+
 	go func() {
 		logg.Info("server started on " + addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -75,5 +223,27 @@ func main() {
 		logg.Error("server shutdown failed", err)
 		srv.Close()
 	}
+	if err := collabSvc.Shutdown(shutdownCtx); err != nil {
+		logg.Error("collaborative editing shutdown failed", err)
+	}
 	logg.Info("server exited")
 }
+
+// newMailer builds the configured mailer. Delivery happens in the
+// background so request latency does not reveal whether mail was sent.
+func newMailer(cfg config.MailConfig, logg *logger.Logger) (mail.Mailer, error) {
+	var m mail.Mailer
+	switch cfg.Driver {
+	case "smtp":
+		m = mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	case "file":
+		fm, err := mail.NewFileMailer(cfg.Dir, cfg.From)
+		if err != nil {
+			return nil, err
+		}
+		m = fm
+	default:
+		m = mail.NewLogMailer(logg)
+	}
+	return mail.NewAsyncMailer(m, logg), nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/config"
+	"github.com/maqsatto/Notes-API/internal/database"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+// bootstrap creates the first administrator. An existing account with the
+// email is promoted instead; the password is then left unchanged.
+func main() {
+	// usage: ADMIN_PASSWORD=... go run ./cmd/bootstrap -email=admin@example.com -username=admin
+	email := flag.String("email", "", "email of the administrator")
+	username := flag.String("username", "", "username of the administrator (new accounts only)")
+	flag.Parse()
+
+	*email = strings.ToLower(strings.TrimSpace(*email))
+	*username = strings.TrimSpace(*username)
+	if *email == "" {
+		log.Fatal("-email is required")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	ctx := context.Background()
+	db, err := database.NewPostgresDB(ctx, cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	users := repository.NewUserRepo(db)
+	txm := repository.NewTxManager(db)
+
+	err = txm.WithinTransaction(ctx, func(ctx context.Context) error {
+		if exists, err := users.ExistsWithRole(ctx, domain.RoleAdmin); err != nil {
+			return err
+		} else if exists {
+			return errors.New("an administrator already exists, assign further admins through the admin API")
+		}
+
+		user, err := users.GetByEmail(ctx, *email)
+		switch {
+		case err == nil:
+			log.Printf("Promoting existing user %s", user.Username)
+		case errors.Is(err, domain.ErrUserNotFound):
+			if user, err = createUser(ctx, cfg, users, *email, *username, os.Getenv("ADMIN_PASSWORD")); err != nil {
+				return err
+			}
+			log.Printf("Created user %s", user.Username)
+		default:
+			return err
+		}
+		return users.SetRole(ctx, user.ID, domain.RoleAdmin)
+	})
+	if err != nil {
+		log.Fatalf("Bootstrap failed: %v", err)
+	}
+	log.Printf("%s is now an administrator", *email)
+}
+
+func createUser(ctx context.Context, cfg *config.Config, users *repository.UserRepo, email, username, password string) (*domain.User, error) {
+	if username == "" || password == "" {
+		return nil, errors.New("-username and ADMIN_PASSWORD are required to create a new account")
+	}
+	if _, err := validator.IsValidUsername(username); err != nil {
+		return nil, err
+	}
+	if err := validator.ValidateUserRegister(email, username, password); err != nil {
+		return nil, err
+	}
+
+	var breached *validator.BreachedPasswords
+	if cfg.Password.BreachedDir != "" {
+		var err error
+		if breached, err = validator.NewBreachedPasswords(cfg.Password.BreachedDir, cfg.Password.BreachedMinCount); err != nil {
+			return nil, err
+		}
+	}
+	policy := validator.NewPasswordPolicy(cfg.Password.RejectCommon, cfg.Password.RejectPersonal, breached)
+	if err := policy.Check(password, email, username); err != nil {
+		return nil, err
+	}
+
+	hasher := auth.NewPasswordHasher(auth.Argon2Params{
+		Memory:      uint32(cfg.Password.Argon2Memory),
+		Time:        uint32(cfg.Password.Argon2Time),
+		Parallelism: uint8(cfg.Password.Argon2Parallelism),
+	})
+	hashed, err := hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{Email: email, Username: username, Password: hashed}
+	if err := users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	// the operator vouches for the address
+	if err := users.MarkEmailVerified(ctx, user.ID, email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -4,7 +4,16 @@ import "github.com/golang-jwt/jwt/v5"
 
 //Custom claims
 
+// Claims carries the user's role and its permissions as of when the token
+// was issued; role changes apply from the next refresh. Tokens issued to an
+// OAuth client carry the client and its granted scopes, space separated as
+// in RFC 9068, instead.
 type Claims struct {
-	UserID uint64 `json:"user_id"`
+	UserID      uint64   `json:"user_id"`
+	SessionID   string   `json:"sid"`
+	Role        string   `json:"role,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"crypto/rand"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,8 +11,12 @@ import (
 )
 
 //Token create/verify
+
+// JWTManager signs access tokens with HS256 and a shared secret, or, when
+// built with NewKeyedJWTManager, with the active key of a KeySet.
 type JWTManager struct {
 	secret []byte
+	keys   *KeySet
 	ttl time.Duration
 	issuer string
 }
@@ -23,16 +29,57 @@ func NewJWTManager(secret, issuer string, ttl time.Duration) *JWTManager {
 	}
 }
 
-func (m *JWTManager) GenerateToken(userID uint64) (string, error) {
+// NewKeyedJWTManager signs with asymmetric keys so other services can verify
+// tokens from the published JWKS without holding a secret.
+func NewKeyedJWTManager(keys *KeySet, issuer string, ttl time.Duration) *JWTManager {
+	return &JWTManager{
+		keys:   keys,
+		ttl:    ttl,
+		issuer: issuer,
+	}
+}
+
+func (m *JWTManager) TTL() time.Duration {
+	return m.ttl
+}
+
+// GenerateToken issues an access token for the session, granting the
+// permissions of the user's role. Every token gets a unique jti.
+func (m *JWTManager) GenerateToken(userID uint64, sessionID, role string, permissions []string) (string, error) {
+	return m.sign(Claims{
+		UserID:      userID,
+		SessionID:   sessionID,
+		Role:        role,
+		Permissions: permissions,
+	})
+}
+
+// GenerateClientToken issues an access token for an OAuth client's session,
+// limited to scopes.
+func (m *JWTManager) GenerateClientToken(userID uint64, sessionID, clientID string, scopes []string) (string, error) {
+	return m.sign(Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		ClientID:  clientID,
+		Scope:     strings.Join(scopes, " "),
+	})
+}
+
+func (m *JWTManager) sign(claims Claims) (string, error) {
 	now := time.Now()
-	claims := Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer: m.issuer,
-			IssuedAt: jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
-		},
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID: rand.Text(),
+		Issuer: m.issuer,
+		IssuedAt: jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+	}
+
+	if m.keys != nil {
+		key := m.keys.Active()
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.ID
+		return token.SignedString(key.private)
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -43,20 +90,25 @@ func (m *JWTManager) GenerateToken(userID uint64) (string, error) {
 	return signed, nil
 }
 
+// JWKS returns the public verification keys, empty when signing with a
+// shared secret.
+func (m *JWTManager) JWKS() JWKSet {
+	if m.keys == nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+	return m.keys.JWKS()
+}
+
 func (m *JWTManager) ParseAndValidate(tokenString string) (*Claims, error) {
+	keyFunc, methods := m.hmacKey, []string{jwt.SigningMethodHS256.Alg()}
+	if m.keys != nil {
+		keyFunc, methods = m.publicKey, m.keys.Methods()
+	}
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&Claims{},
-		func(t *jwt.Token) (any, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, domain.ErrInvalidToken
-			}
-			if t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-				return nil, domain.ErrExpiredToken
-			}
-			return m.secret, nil
-		},
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		keyFunc,
+		jwt.WithValidMethods(methods),
 		jwt.WithIssuer(m.issuer),
 	)
 	if err != nil {
@@ -76,9 +128,30 @@ func (m *JWTManager) ParseAndValidate(tokenString string) (*Claims, error) {
 	if claims.Issuer != m.issuer {
 		return nil, domain.ErrInvalidToken
 	}
-	if claims.UserID == 0 {
+	if claims.UserID == 0 || claims.SessionID == "" || claims.ID == "" {
 		return nil, domain.ErrInvalidToken
 	}
 
 	return claims, nil
 }
+
+func (m *JWTManager) hmacKey(t *jwt.Token) (any, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, domain.ErrInvalidSigningMethod
+	}
+	return m.secret, nil
+}
+
+// publicKey looks the verification key up by the kid header and insists on
+// the algorithm the key was loaded for.
+func (m *JWTManager) publicKey(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := m.keys.Verifier(kid)
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, domain.ErrInvalidSigningMethod
+	}
+	return key.public, nil
+}
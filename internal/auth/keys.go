@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+//Asymmetric signing keys
+
+// SigningKey is one key of a KeySet. Keys loaded from a public key PEM only
+// verify tokens.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	private crypto.Signer
+	public  crypto.PublicKey
+	// retireAt is set once the key disappeared from its source; it keeps
+	// verifying until then so tokens it signed can expire naturally.
+	retireAt time.Time
+}
+
+func (k *SigningKey) canSign() bool {
+	return k.private != nil
+}
+
+// KeySet holds the keys used to sign and verify access tokens. Keys are read
+// from PEM files, named <kid>.pem, listed explicitly or found in a directory.
+//
+// Rotation: the signing key is activeKID when set, otherwise the private key
+// with the greatest kid, so naming keys by date (2026-10-01.pem) rotates to a
+// new key as soon as it is added. Keys removed from the source keep verifying
+// for the grace period, which should be at least the access token TTL.
+type KeySet struct {
+	dir       string
+	files     []string
+	activeKID string
+	grace     time.Duration
+
+	mu     sync.RWMutex
+	keys   map[string]*SigningKey
+	active *SigningKey
+}
+
+// LoadKeySet reads the keys from dir and files. At least one private key is
+// required.
+func LoadKeySet(dir string, files []string, activeKID string, grace time.Duration) (*KeySet, error) {
+	ks := &KeySet{
+		dir:       dir,
+		files:     files,
+		activeKID: activeKID,
+		grace:     grace,
+		keys:      make(map[string]*SigningKey),
+	}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload rereads the key source. On error the current keys stay in use.
+func (ks *KeySet) Reload() error {
+	paths := append([]string(nil), ks.files...)
+	if ks.dir != "" {
+		matches, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+
+	loaded := make(map[string]*SigningKey, len(paths))
+	for _, path := range paths {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return err
+		}
+		if _, dup := loaded[key.ID]; dup {
+			return fmt.Errorf("jwt key %q: duplicate kid", key.ID)
+		}
+		loaded[key.ID] = key
+	}
+
+	active, err := pickActive(loaded, ks.activeKID)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	now := time.Now()
+	for id, old := range ks.keys {
+		if _, ok := loaded[id]; ok {
+			continue
+		}
+		if old.retireAt.IsZero() {
+			old.retireAt = now.Add(ks.grace)
+		}
+		if now.Before(old.retireAt) {
+			loaded[id] = old
+		}
+	}
+	ks.keys = loaded
+	ks.active = active
+	return nil
+}
+
+// Active returns the key new tokens are signed with.
+func (ks *KeySet) Active() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active
+}
+
+// Verifier returns the key with the given kid if it may still verify tokens.
+func (ks *KeySet) Verifier(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	if !ok || (!key.retireAt.IsZero() && time.Now().After(key.retireAt)) {
+		return nil, false
+	}
+	return key, true
+}
+
+// Methods lists the algorithms of the current keys.
+func (ks *KeySet) Methods() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	seen := make(map[string]bool)
+	var algs []string
+	for _, key := range ks.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+func pickActive(keys map[string]*SigningKey, activeKID string) (*SigningKey, error) {
+	if activeKID != "" {
+		key, ok := keys[activeKID]
+		if !ok || !key.canSign() {
+			return nil, fmt.Errorf("jwt key %q: active key not found or has no private part", activeKID)
+		}
+		return key, nil
+	}
+	var active *SigningKey
+	for _, key := range keys {
+		if key.canSign() && (active == nil || key.ID > active.ID) {
+			active = key
+		}
+	}
+	if active == nil {
+		return nil, errors.New("jwt keys: no private key to sign with")
+	}
+	return active, nil
+}
+
+func loadKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt key %q: no PEM block found", kid)
+	}
+
+	key := &SigningKey{ID: kid}
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+		}
+		key.public = pub
+	default:
+		priv, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+		}
+		key.private = priv
+		key.public = priv.Public()
+	}
+
+	key.Method, err = methodFor(key.public)
+	if err != nil {
+		return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+	}
+	return key, nil
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
+
+// methodFor picks the signing algorithm from the key type: RS256 for RSA,
+// ES256/ES384/ES512 by curve for ECDSA and EdDSA for Ed25519.
+func methodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, errors.New("unsupported elliptic curve")
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", pub)
+}
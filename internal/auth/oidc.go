@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+//OpenID Connect login
+
+const (
+	// oidcKeysMinRefresh limits how often an unknown kid refetches the
+	// provider's keys, so forged tokens cannot make us hammer it.
+	oidcKeysMinRefresh = time.Minute
+	// oidcClockSkew is tolerated between us and the provider.
+	oidcClockSkew = time.Minute
+	// oidcMaxResponse caps the size of provider responses.
+	oidcMaxResponse = 1 << 20
+)
+
+// oidcSigningMethods are the ID token algorithms accepted. Symmetric ones are
+// left out: they would make the client secret a signing key.
+var oidcSigningMethods = []string{
+	"RS256", "RS384", "RS512", "PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512", "EdDSA",
+}
+
+// OIDCIdentity is what a verified ID token says about the user.
+type OIDCIdentity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// OIDCProvider logs users in with an OpenID Connect identity provider using
+// the authorization code flow with PKCE. The discovery document is fetched
+// on first use; the signing keys are refetched when an ID token names a key
+// we do not know yet, which follows the provider's key rotation.
+type OIDCProvider struct {
+	name         string
+	issuer       string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	redirectURL  string
+	client       *http.Client
+
+	// mu guards the cached documents below. It is never held during a
+	// request to the provider.
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+	// keysFetch is closed when the key fetch in progress, if any, ends.
+	keysFetch chan struct{}
+}
+
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+func NewOIDCProvider(name, issuer, clientID, clientSecret string, scopes []string, redirectURL string, client *http.Client) *OIDCProvider {
+	return &OIDCProvider{
+		name:         name,
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+		redirectURL:  redirectURL,
+		client:       client,
+	}
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+// NewPKCEVerifier returns a random PKCE code verifier (RFC 7636).
+func NewPKCEVerifier() (string, error) {
+	verifier, _, err := NewOpaqueToken()
+	return verifier, err
+}
+
+// PKCEChallenge derives the S256 code challenge of verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider page the user is sent to. state and nonce
+// must be random and kept, along with verifier, until the user comes back.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc %s: authorization endpoint: %w", p.name, err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.clientID)
+	q.Set("redirect_uri", p.redirectURL)
+	q.Set("scope", strings.Join(p.scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", PKCEChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeems an authorization code and verifies the ID token it
+// yields. A code or token the provider or we reject gives
+// ErrExternalLoginFailed.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*OIDCIdentity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.clientID},
+	}
+	// client_secret_basic is the default when the provider does not say
+	basicAuth := p.clientSecret != "" &&
+		(len(d.TokenAuthMethods) == 0 || slices.Contains(d.TokenAuthMethods, "client_secret_basic"))
+	if p.clientSecret != "" && !basicAuth {
+		form.Set("client_secret", p.clientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basicAuth {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc %s: token request: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized:
+		// invalid_grant and friends: a bad, used or expired code
+		return nil, domain.ErrExternalLoginFailed
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("oidc %s: token request: %s", p.name, resp.Status)
+	}
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponse)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("oidc %s: token response: %w", p.name, err)
+	}
+	if body.IDToken == "" {
+		return nil, domain.ErrExternalLoginFailed
+	}
+	return p.VerifyIDToken(ctx, body.IDToken, nonce)
+}
+
+type idTokenClaims struct {
+	Nonce             string    `json:"nonce"`
+	AuthorizedParty   string    `json:"azp"`
+	Email             string    `json:"email"`
+	EmailVerified     claimBool `json:"email_verified"`
+	Name              string    `json:"name"`
+	PreferredUsername string    `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+// claimBool also accepts "true" and "false" strings, which some providers
+// send for email_verified.
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	v, err := strconv.ParseBool(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*b = claimBool(v)
+	return nil
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience, lifetime
+// and nonce. Tokens failing a check give ErrExternalLoginFailed.
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, raw, nonce string) (*OIDCIdentity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		claims idTokenClaims
+		keyErr error
+	)
+	_, err = jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := p.key(ctx, d, kid)
+		keyErr = err
+		return key, err
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockSkew),
+	)
+	if err != nil {
+		if keyErr != nil && !errors.Is(keyErr, domain.ErrExternalLoginFailed) {
+			return nil, keyErr
+		}
+		return nil, domain.ErrExternalLoginFailed
+	}
+	if claims.Subject == "" {
+		return nil, domain.ErrExternalLoginFailed
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.clientID {
+		return nil, domain.ErrExternalLoginFailed
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, domain.ErrExternalLoginFailed
+	}
+
+	return &OIDCIdentity{
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     bool(claims.EmailVerified),
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// discover fetches and caches the provider's discovery document, whose
+// issuer must be the configured one.
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	d := p.discovery
+	p.mu.Unlock()
+	if d != nil {
+		return d, nil
+	}
+	d, err := p.fetchDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// a concurrent first login may have stored it already
+	if p.discovery == nil {
+		p.discovery = d
+	}
+	return p.discovery, nil
+}
+
+func (p *OIDCProvider) fetchDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	var d oidcDiscovery
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.issuer, "/")+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != strings.TrimSuffix(p.issuer, "/") {
+		return nil, fmt.Errorf("oidc %s: discovery document is for issuer %q", p.name, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc %s: discovery document lacks an endpoint", p.name)
+	}
+	return &d, nil
+}
+
+// key returns the provider's key with the given kid. Tokens without a kid
+// are accepted when the provider publishes a single key. Logins needing the
+// keys while they are fetched wait for that fetch rather than starting
+// another.
+func (p *OIDCProvider) key(ctx context.Context, d *oidcDiscovery, kid string) (crypto.PublicKey, error) {
+	for {
+		p.mu.Lock()
+		if key, ok := p.lookupKey(kid); ok {
+			p.mu.Unlock()
+			return key, nil
+		}
+		if fetch := p.keysFetch; fetch != nil {
+			p.mu.Unlock()
+			select {
+			case <-fetch:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if p.keys != nil && time.Since(p.keysFetchedAt) < oidcKeysMinRefresh {
+			p.mu.Unlock()
+			return nil, domain.ErrExternalLoginFailed
+		}
+		fetch := make(chan struct{})
+		p.keysFetch = fetch
+		p.mu.Unlock()
+
+		keys, err := p.fetchKeys(ctx, d)
+
+		p.mu.Lock()
+		p.keysFetch = nil
+		close(fetch)
+		if err != nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+		p.keys = keys
+		p.keysFetchedAt = time.Now()
+		key, ok := p.lookupKey(kid)
+		p.mu.Unlock()
+		if !ok {
+			return nil, domain.ErrExternalLoginFailed
+		}
+		return key, nil
+	}
+}
+
+// fetchKeys downloads the provider's signing keys.
+func (p *OIDCProvider) fetchKeys(ctx context.Context, d *oidcDiscovery) (map[string]crypto.PublicKey, error) {
+	var set JWKSet
+	if err := p.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// keys of types we cannot use are skipped, not fatal
+		if key, err := jwk.PublicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (p *OIDCProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oidc %s: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc %s: GET %s: %s", p.name, url, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponse)).Decode(v); err != nil {
+		return fmt.Errorf("oidc %s: GET %s: %w", p.name, url, err)
+	}
+	return nil
+}
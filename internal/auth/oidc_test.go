@@ -0,0 +1,416 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+const (
+	testClientID     = "notes-api"
+	testClientSecret = "s3cret"
+	testRedirectURL  = "https://notes.example/api/auth/oidc/callback"
+)
+
+// mockIdP is an in-process OpenID Connect provider. It publishes the keys in
+// published and signs ID tokens with the key named by signingKID.
+type mockIdP struct {
+	t   *testing.T
+	srv *httptest.Server
+
+	mu            sync.Mutex
+	issuer        string
+	keys          map[string]ed25519.PrivateKey
+	published     []string
+	signingKID    string
+	codes         map[string]mockGrant
+	discoveryHits int
+	jwksHits      int
+	jwksGate      chan struct{}
+	idTokenClaims func(claims jwt.MapClaims)
+}
+
+// mockGrant is what the provider remembers about a code it handed out.
+type mockGrant struct {
+	challenge string
+	nonce     string
+	subject   string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	idp := &mockIdP{
+		t:     t,
+		keys:  map[string]ed25519.PrivateKey{},
+		codes: map[string]mockGrant{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("GET /jwks", idp.jwks)
+	mux.HandleFunc("POST /token", idp.token)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	idp.issuer = idp.srv.URL
+	idp.rotate("key-1")
+	return idp
+}
+
+func (idp *mockIdP) provider() *OIDCProvider {
+	return NewOIDCProvider("mock", idp.srv.URL, testClientID, testClientSecret,
+		[]string{"openid", "email"}, testRedirectURL, idp.srv.Client())
+}
+
+// rotate starts signing with a new key and publishes it next to the old ones.
+func (idp *mockIdP) rotate(kid string) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys[kid] = priv
+	idp.published = append(idp.published, kid)
+	idp.signingKID = kid
+}
+
+// hits returns how often the discovery document and the keys were fetched.
+func (idp *mockIdP) hits() (discovery, keys int) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.discoveryHits, idp.jwksHits
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	idp.discoveryHits++
+	issuer := idp.issuer
+	idp.mu.Unlock()
+	writeTestJSON(w, map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                idp.srv.URL + "/authorize",
+		"token_endpoint":                        idp.srv.URL + "/token",
+		"jwks_uri":                              idp.srv.URL + "/jwks",
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic"},
+	})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	idp.jwksHits++
+	gate := idp.jwksGate
+	var set JWKSet
+	for _, kid := range idp.published {
+		pub := idp.keys[kid].Public().(ed25519.PublicKey)
+		set.Keys = append(set.Keys, JWK{Kty: "OKP", Kid: kid, Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: b64(pub)})
+	}
+	idp.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	writeTestJSON(w, set)
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	user, pass, ok := r.BasicAuth()
+	if !ok || user != testClientID || pass != testClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	if !ok ||
+		r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != testRedirectURL ||
+		PKCEChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	writeTestJSON(w, map[string]string{
+		"access_token": "at",
+		"token_type":   "Bearer",
+		"id_token":     idp.idToken(grant.subject, grant.nonce),
+	})
+}
+
+// authorize plays the user approving the login the authorization URL asks
+// for and returns the code the provider redirects back with.
+func (idp *mockIdP) authorize(authURL, subject string) (code, state string) {
+	idp.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	q := u.Query()
+	if got := q.Get("code_challenge_method"); got != "S256" {
+		idp.t.Fatalf("code_challenge_method = %q, want S256", got)
+	}
+	if got := q.Get("redirect_uri"); got != testRedirectURL {
+		idp.t.Fatalf("redirect_uri = %q", got)
+	}
+	code, _, err = NewOpaqueToken()
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.codes[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), subject: subject}
+	idp.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (idp *mockIdP) idToken(subject, nonce string) string {
+	idp.mu.Lock()
+	issuer, kid, edit := idp.issuer, idp.signingKID, idp.idTokenClaims
+	key := idp.keys[kid]
+	idp.mu.Unlock()
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            issuer,
+		"sub":            subject,
+		"aud":            testClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          subject + "@example.com",
+		"email_verified": true,
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	return signTestToken(idp.t, kid, key, claims)
+}
+
+func signTestToken(t *testing.T, kid string, key ed25519.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	tok.Header["kid"] = kid
+	raw, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func writeTestJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCCodeExchangeWithPKCE(t *testing.T) {
+	idp := newMockIdP(t)
+	p := idp.provider()
+	ctx := context.Background()
+
+	verifier, err := NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := idp.authorize(authURL, "alice")
+	if state != "state-1" {
+		t.Fatalf("state = %q", state)
+	}
+
+	identity, err := p.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Subject != "alice" || identity.Email != "alice@example.com" || !identity.EmailVerified {
+		t.Fatalf("identity = %+v", identity)
+	}
+
+	// codes are single use
+	if _, err := p.Exchange(ctx, code, verifier, "nonce-1"); !errors.Is(err, domain.ErrExternalLoginFailed) {
+		t.Fatalf("reused code: err = %v", err)
+	}
+}
+
+func TestOIDCCodeExchangeWrongVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	p := idp.provider()
+	ctx := context.Background()
+
+	verifier, _ := NewPKCEVerifier()
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := idp.authorize(authURL, "alice")
+
+	other, _ := NewPKCEVerifier()
+	if _, err := p.Exchange(ctx, code, other, "nonce"); !errors.Is(err, domain.ErrExternalLoginFailed) {
+		t.Fatalf("err = %v, want ErrExternalLoginFailed", err)
+	}
+}
+
+func TestOIDCDiscoveryIsCached(t *testing.T) {
+	idp := newMockIdP(t)
+	p := idp.provider()
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := p.VerifyIDToken(ctx, idp.idToken("alice", "n"), "n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if discovery, keys := idp.hits(); discovery != 1 || keys != 1 {
+		t.Fatalf("discovery fetched %d times, keys %d times; want once each", discovery, keys)
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.mu.Lock()
+	idp.issuer = "https://evil.example"
+	idp.mu.Unlock()
+	p := idp.provider()
+
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Fatal("discovery for another issuer was accepted")
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	idp := newMockIdP(t)
+	p := idp.provider()
+	ctx := context.Background()
+
+	if _, err := p.VerifyIDToken(ctx, idp.idToken("alice", "n"), "n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a token signed with a key published after ours were fetched makes us
+	// fetch them again, once they are old enough
+	idp.rotate("key-2")
+	p.mu.Lock()
+	p.keysFetchedAt = time.Now().Add(-oidcKeysMinRefresh)
+	p.mu.Unlock()
+	if _, err := p.VerifyIDToken(ctx, idp.idToken("alice", "n"), "n"); err != nil {
+		t.Fatalf("token signed with the rotated key: %v", err)
+	}
+	if _, keys := idp.hits(); keys != 2 {
+		t.Fatalf("keys fetched %d times, want 2", keys)
+	}
+
+	// unknown kids right after a fetch do not reach the provider
+	_, stray, _ := ed25519.GenerateKey(rand.Reader)
+	raw := signTestToken(t, "key-unknown", stray, jwt.MapClaims{
+		"iss": idp.issuer, "sub": "alice", "aud": testClientID, "nonce": "n",
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix(),
+	})
+	if _, err := p.VerifyIDToken(ctx, raw, "n"); !errors.Is(err, domain.ErrExternalLoginFailed) {
+		t.Fatalf("unknown kid: err = %v", err)
+	}
+	if _, keys := idp.hits(); keys != 2 {
+		t.Fatalf("unknown kid refetched the keys: %d fetches", keys)
+	}
+}
+
+func TestOIDCVerifyIDTokenRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(jwt.MapClaims)
+		nonce string
+	}{
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, "n"},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "someone-else" }, "n"},
+		{"several audiences without azp", func(c jwt.MapClaims) { c["aud"] = []string{testClientID, "other"} }, "n"},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "n"},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }, "n"},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }, "n"},
+		{"wrong nonce", nil, "other-nonce"},
+		{"missing nonce", func(c jwt.MapClaims) { delete(c, "nonce") }, "n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.idTokenClaims = tt.edit
+			p := idp.provider()
+
+			_, err := p.VerifyIDToken(context.Background(), idp.idToken("alice", "n"), tt.nonce)
+			if !errors.Is(err, domain.ErrExternalLoginFailed) {
+				t.Fatalf("err = %v, want ErrExternalLoginFailed", err)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyIDTokenRejectsForgedSignature(t *testing.T) {
+	idp := newMockIdP(t)
+	p := idp.provider()
+
+	_, forger, _ := ed25519.GenerateKey(rand.Reader)
+	raw := signTestToken(t, "key-1", forger, jwt.MapClaims{
+		"iss": idp.issuer, "sub": "alice", "aud": testClientID, "nonce": "n",
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix(),
+	})
+	if _, err := p.VerifyIDToken(context.Background(), raw, "n"); !errors.Is(err, domain.ErrExternalLoginFailed) {
+		t.Fatalf("err = %v, want ErrExternalLoginFailed", err)
+	}
+}
+
+// A slow key fetch must not hold up logins that do not need the keys.
+func TestOIDCKeyFetchDoesNotBlockProvider(t *testing.T) {
+	idp := newMockIdP(t)
+	p := idp.provider()
+	ctx := context.Background()
+	if _, err := p.AuthCodeURL(ctx, "s", "n", "v"); err != nil {
+		t.Fatal(err)
+	}
+
+	gate := make(chan struct{})
+	idp.mu.Lock()
+	idp.jwksGate = gate
+	idp.mu.Unlock()
+	verified := make(chan error, 1)
+	go func() {
+		_, err := p.VerifyIDToken(ctx, idp.idToken("alice", "n"), "n")
+		verified <- err
+	}()
+	for {
+		if _, keys := idp.hits(); keys > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.AuthCodeURL(ctx, "s2", "n2", "v2")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("AuthCodeURL blocked behind the key fetch")
+	}
+
+	close(gate)
+	if err := <-verified; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+//Opaque tokens (refresh, reset, ...)
+
+const opaqueTokenBytes = 32
+
+// NewOpaqueToken returns a random URL-safe token for the client and the hash
+// to store in its place.
+func NewOpaqueToken() (token, hash string, err error) {
+	buf := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken returns the hex SHA-256 of token. The tokens carry 256 bits
+// of entropy, so a fast unsalted hash is enough to make a leaked table
+// useless.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+//API keys
+
+const (
+	// APIKeyPrefix starts every API key so they are recognizable, also to
+	// secret scanners.
+	APIKeyPrefix     = "nak_"
+	apiKeyLookupSize = 6
+)
+
+// NewAPIKey returns a key of the form nak_<lookup>_<secret>, the lookup part
+// to find it by and the hash to store in its place.
+func NewAPIKey() (key, lookup, hash string, err error) {
+	buf := make([]byte, apiKeyLookupSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	lookup = hex.EncodeToString(buf)
+	secret, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + lookup + "_" + secret
+	return key, lookup, HashOpaqueToken(key), nil
+}
+
+// APIKeyLookup extracts the lookup part of a key; ok is false if key is not
+// shaped like one.
+func APIKeyLookup(key string) (lookup string, ok bool) {
+	rest, found := strings.CutPrefix(key, APIKeyPrefix)
+	if !found {
+		return "", false
+	}
+	lookup, secret, found := strings.Cut(rest, "_")
+	if !found || len(lookup) != 2*apiKeyLookupSize || secret == "" {
+		return "", false
+	}
+	return lookup, true
+}
@@ -3,15 +3,32 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	Session   SessionConfig
+	TwoFactor TwoFactorConfig
+	Mail      MailConfig
+	Reset     PasswordResetConfig
+	Email     EmailConfig
+	Lockout   LockoutConfig
+	Password  PasswordConfig
+	OIDC      OIDCConfig
+	OAuth     OAuthConfig
+	Revision  RevisionConfig
+	Trash     TrashConfig
+	ShareLink ShareLinkConfig
+	Collab    CollabConfig
+	Notes     NotesConfig
 }
 
 type ServerConfig struct {
@@ -29,9 +46,170 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+// DSN is the lib/pq connection string for the database.
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode,
+	)
+}
+
+// JWTConfig selects how access tokens are signed: HS256 with Secret, or,
+// when KeysDir or KeyFiles is set, asymmetric keys loaded from <kid>.pem
+// files. ActiveKID pins the signing key; by default it is the greatest kid.
 type JWTConfig struct {
 	Secret     string
-	ExpiryHour int
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+
+	KeysDir         string
+	KeyFiles        []string
+	ActiveKID       string
+	KeysReloadEvery time.Duration
+}
+
+// UsesKeys reports whether tokens are signed with asymmetric keys.
+func (j JWTConfig) UsesKeys() bool {
+	return j.KeysDir != "" || len(j.KeyFiles) > 0
+}
+
+// SessionConfig controls login sessions. CacheTTL is how long a session is
+// trusted without checking the database for revocation; zero disables the
+// cache.
+type SessionConfig struct {
+	CacheTTL      time.Duration
+	PurgeInterval time.Duration
+}
+
+// TwoFactorConfig controls TOTP. Issuer is the account label shown in
+// authenticator apps; ChallengeTTL bounds the second login step.
+type TwoFactorConfig struct {
+	Issuer       string
+	ChallengeTTL time.Duration
+}
+
+// MailConfig selects how email is delivered: "log" (default) writes
+// messages to the application log, "file" stores them as .eml files in Dir
+// and "smtp" sends them through the SMTP server.
+type MailConfig struct {
+	Driver       string
+	From         string
+	Dir          string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// PasswordResetConfig controls the forgot password flow. URL is the page of
+// the frontend that receives the token as ?token=.
+type PasswordResetConfig struct {
+	URL string
+	TTL time.Duration
+}
+
+// EmailConfig controls address verification. VerifyURL and ChangeURL are
+// the frontend pages receiving the mailed token as ?token=. With
+// RequireVerified set, unverified users cannot create notes.
+type EmailConfig struct {
+	RequireVerified bool
+	VerifyURL       string
+	ChangeURL       string
+	TokenTTL        time.Duration
+}
+
+// LockoutConfig controls login throttling. Store is "memory" (default,
+// single instance) or "postgres" to share counters between instances.
+// Thresholds of zero disable the lockout.
+type LockoutConfig struct {
+	Store            string
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	AccountThreshold int
+	IPThreshold      int
+	LockDuration     time.Duration
+	Window           time.Duration
+}
+
+// PasswordConfig holds the argon2id parameters for new password hashes and
+// the password policy. Memory is in KiB. Stored hashes made with other
+// parameters, or with bcrypt, are upgraded when their owner logs in.
+// BreachedDir points to a Have I Been Pwned range file dump; empty disables
+// the breach check.
+type PasswordConfig struct {
+	Argon2Memory      int
+	Argon2Time        int
+	Argon2Parallelism int
+
+	RejectCommon     bool
+	RejectPersonal   bool
+	BreachedDir      string
+	BreachedMinCount int
+}
+
+// OIDCConfig lists the OpenID Connect identity providers users can log in
+// with. Every provider redirects back to RedirectURL, which must be
+// registered with it; StateTTL bounds how long a login may take.
+type OIDCConfig struct {
+	RedirectURL string
+	StateTTL    time.Duration
+	Providers   []OIDCProviderConfig
+}
+
+// OIDCProviderConfig is read from OIDC_<NAME>_* variables for every name in
+// OIDC_PROVIDERS.
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// OAuthConfig controls the OAuth2 authorization server third-party apps
+// use. CodeTTL bounds how long an authorization code can be redeemed.
+type OAuthConfig struct {
+	CodeTTL time.Duration
+}
+
+// ShareLinkConfig controls public share links. BaseURL is prefixed to a
+// link's token to build the URL handed to the owner.
+type ShareLinkConfig struct {
+	BaseURL string
+}
+
+// CollabConfig controls collaborative editing. PersistInterval is how often
+// a document with new edits is written back to its note, PresenceTTL how long
+// a client is shown as present without news from it, and EditRetention how
+// long edits are kept for clients to rebase on.
+type CollabConfig struct {
+	PersistInterval time.Duration
+	PresenceTTL     time.Duration
+	EditRetention   time.Duration
+}
+
+// NotesConfig controls note writes. With RequireIfMatch set, updates and
+// deletes must carry the note's ETag in If-Match, so that clients cannot
+// overwrite changes they have not seen.
+type NotesConfig struct {
+	RequireIfMatch bool
+}
+
+// RevisionConfig controls note revision retention. Zero disables a rule.
+type RevisionConfig struct {
+	KeepLast      int
+	KeepDays      int
+	PruneInterval time.Duration
+}
+
+// TrashConfig controls how long soft-deleted notes and users are kept before
+// the purger removes them for good.
+type TrashConfig struct {
+	NoteRetention time.Duration
+	UserRetention time.Duration
+	PurgeInterval time.Duration
 }
 
 func Load() (*Config, error) {
@@ -54,7 +232,90 @@ func Load() (*Config, error) {
 		},
 		JWT: JWTConfig{
 			Secret:     getEnv("JWT_SECRET", ""),
-			ExpiryHour: getEnvAsInt("JWT_EXPIRY_HOUR", 24),
+			Issuer:     getEnv("JWT_ISSUER", "notes-api"),
+			AccessTTL:  accessTTL(),
+			RefreshTTL: time.Duration(getEnvAsInt("JWT_REFRESH_TTL_HOUR", 720)) * time.Hour,
+
+			KeysDir:         getEnv("JWT_KEYS_DIR", ""),
+			KeyFiles:        getEnvAsList("JWT_KEY_FILES"),
+			ActiveKID:       getEnv("JWT_ACTIVE_KID", ""),
+			KeysReloadEvery: time.Duration(getEnvAsInt("JWT_KEYS_RELOAD_MIN", 5)) * time.Minute,
+		},
+		Session: SessionConfig{
+			CacheTTL:      time.Duration(getEnvAsInt("SESSION_CACHE_TTL_SEC", 30)) * time.Second,
+			PurgeInterval: time.Duration(getEnvAsInt("SESSION_PURGE_INTERVAL_MIN", 60)) * time.Minute,
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:       getEnv("TOTP_ISSUER", "Notes API"),
+			ChallengeTTL: time.Duration(getEnvAsInt("TOTP_CHALLENGE_TTL_MIN", 5)) * time.Minute,
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "Notes API <no-reply@localhost>"),
+			Dir:          getEnv("MAIL_DIR", "mail"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		},
+		Reset: PasswordResetConfig{
+			URL: getEnv("PASSWORD_RESET_URL", "http://localhost:8080/reset-password"),
+			TTL: time.Duration(getEnvAsInt("PASSWORD_RESET_TTL_MIN", 60)) * time.Minute,
+		},
+		Email: EmailConfig{
+			RequireVerified: getEnvAsBool("EMAIL_VERIFICATION_REQUIRED", false),
+			VerifyURL:       getEnv("EMAIL_VERIFY_URL", "http://localhost:8080/verify-email"),
+			ChangeURL:       getEnv("EMAIL_CHANGE_URL", "http://localhost:8080/confirm-email"),
+			TokenTTL:        time.Duration(getEnvAsInt("EMAIL_TOKEN_TTL_HOUR", 24)) * time.Hour,
+		},
+		Lockout: LockoutConfig{
+			Store:            getEnv("LOGIN_ATTEMPT_STORE", "memory"),
+			FreeAttempts:     getEnvAsInt("LOGIN_FREE_ATTEMPTS", 3),
+			BaseDelay:        time.Duration(getEnvAsInt("LOGIN_BASE_DELAY_SEC", 1)) * time.Second,
+			MaxDelay:         time.Duration(getEnvAsInt("LOGIN_MAX_DELAY_SEC", 60)) * time.Second,
+			AccountThreshold: getEnvAsInt("LOGIN_ACCOUNT_LOCK_THRESHOLD", 10),
+			IPThreshold:      getEnvAsInt("LOGIN_IP_LOCK_THRESHOLD", 50),
+			LockDuration:     time.Duration(getEnvAsInt("LOGIN_LOCK_DURATION_MIN", 15)) * time.Minute,
+			Window:           time.Duration(getEnvAsInt("LOGIN_ATTEMPT_WINDOW_MIN", 15)) * time.Minute,
+		},
+		Password: PasswordConfig{
+			Argon2Memory:      getEnvAsInt("PASSWORD_ARGON2_MEMORY_KIB", 64*1024),
+			Argon2Time:        getEnvAsInt("PASSWORD_ARGON2_TIME", 3),
+			Argon2Parallelism: getEnvAsInt("PASSWORD_ARGON2_PARALLELISM", 2),
+
+			RejectCommon:     getEnvAsBool("PASSWORD_REJECT_COMMON", true),
+			RejectPersonal:   getEnvAsBool("PASSWORD_REJECT_PERSONAL_INFO", true),
+			BreachedDir:      getEnv("PASSWORD_BREACHED_DIR", ""),
+			BreachedMinCount: getEnvAsInt("PASSWORD_BREACHED_MIN_COUNT", 1),
+		},
+		OIDC: OIDCConfig{
+			RedirectURL: getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/auth/oidc/callback"),
+			StateTTL:    time.Duration(getEnvAsInt("OIDC_STATE_TTL_MIN", 10)) * time.Minute,
+			Providers:   loadOIDCProviders(),
+		},
+		OAuth: OAuthConfig{
+			CodeTTL: time.Duration(getEnvAsInt("OAUTH_CODE_TTL_SEC", 60)) * time.Second,
+		},
+		Revision: RevisionConfig{
+			KeepLast:      getEnvAsInt("REVISION_KEEP_LAST", 50),
+			KeepDays:      getEnvAsInt("REVISION_KEEP_DAYS", 0),
+			PruneInterval: time.Duration(getEnvAsInt("REVISION_PRUNE_INTERVAL_MIN", 60)) * time.Minute,
+		},
+		Trash: TrashConfig{
+			NoteRetention: time.Duration(getEnvAsInt("TRASH_NOTE_RETENTION_DAYS", 30)) * 24 * time.Hour,
+			UserRetention: time.Duration(getEnvAsInt("TRASH_USER_RETENTION_DAYS", 30)) * 24 * time.Hour,
+			PurgeInterval: time.Duration(getEnvAsInt("TRASH_PURGE_INTERVAL_MIN", 60)) * time.Minute,
+		},
+		ShareLink: ShareLinkConfig{
+			BaseURL: getEnv("SHARE_LINK_BASE_URL", "http://localhost:8080/s/"),
+		},
+		Collab: CollabConfig{
+			PersistInterval: time.Duration(getEnvAsInt("COLLAB_PERSIST_INTERVAL_SEC", 10)) * time.Second,
+			PresenceTTL:     time.Duration(getEnvAsInt("COLLAB_PRESENCE_TTL_SEC", 30)) * time.Second,
+			EditRetention:   time.Duration(getEnvAsInt("COLLAB_EDIT_RETENTION_MIN", 60)) * time.Minute,
+		},
+		Notes: NotesConfig{
+			RequireIfMatch: getEnvAsBool("NOTES_REQUIRE_IF_MATCH", false),
 		},
 	}
 	if err := cfg.Validate(); err != nil {
@@ -67,12 +328,62 @@ func (c *Config) Validate() error {
 	if c.Database.Password == "" {
 		return fmt.Errorf("DB_PASSWORD is required")
 	}
-	if c.JWT.Secret == "" {
-		return fmt.Errorf("JWT_SECRET is required")
+	if c.JWT.Secret == "" && !c.JWT.UsesKeys() {
+		return fmt.Errorf("JWT_SECRET is required unless JWT_KEYS_DIR or JWT_KEY_FILES is set")
+	}
+	switch c.Mail.Driver {
+	case "log", "file", "smtp":
+	default:
+		return fmt.Errorf("MAIL_DRIVER must be log, file or smtp")
+	}
+	switch c.Lockout.Store {
+	case "memory", "postgres":
+	default:
+		return fmt.Errorf("LOGIN_ATTEMPT_STORE must be memory or postgres")
+	}
+	if p := c.Password; p.Argon2Time < 1 || p.Argon2Parallelism < 1 || p.Argon2Parallelism > 255 || p.Argon2Memory < 8*p.Argon2Parallelism {
+		return fmt.Errorf("PASSWORD_ARGON2_TIME must be positive, PASSWORD_ARGON2_PARALLELISM between 1 and 255 and PASSWORD_ARGON2_MEMORY_KIB at least 8 per thread")
+	}
+	for _, p := range c.OIDC.Providers {
+		if !oidcProviderName.MatchString(p.Name) {
+			return fmt.Errorf("OIDC_PROVIDERS: %q must be lowercase letters, digits and dashes", p.Name)
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			return fmt.Errorf("%s and %s are required", oidcEnv(p.Name, "ISSUER"), oidcEnv(p.Name, "CLIENT_ID"))
+		}
+	}
+	if c.Collab.PersistInterval <= 0 || c.Collab.PresenceTTL <= 0 {
+		return fmt.Errorf("COLLAB_PERSIST_INTERVAL_SEC and COLLAB_PRESENCE_TTL_SEC must be positive")
 	}
 	return nil
 }
 
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range getEnvAsList("OIDC_PROVIDERS") {
+		scopes := getEnvAsList(oidcEnv(name, "SCOPES"))
+		if len(scopes) == 0 {
+			scopes = []string{"openid", "email", "profile"}
+		}
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(oidcEnv(name, "ISSUER"), ""),
+			ClientID:     getEnv(oidcEnv(name, "CLIENT_ID"), ""),
+			ClientSecret: getEnv(oidcEnv(name, "CLIENT_SECRET"), ""),
+			Scopes:       scopes,
+		})
+	}
+	return providers
+}
+
+// oidcEnv names a provider's variable: OIDC_<NAME>_<key>, dashes in the name
+// becoming underscores.
+func oidcEnv(name, key string) string {
+	return "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_" + key
+}
+
 func (d DatabaseConfig) ConnectionString() string {
 	return fmt.Sprintf(
 		"postgresql://%s:%s@%s:%s/%s?sslmode=%s",
@@ -80,6 +391,18 @@ func (d DatabaseConfig) ConnectionString() string {
 	)
 }
 
+// accessTTL reads JWT_ACCESS_TTL_MIN. Deployments that predate it set the
+// lifetime of access tokens in hours with JWT_EXPIRY_HOUR, which is still
+// honoured when JWT_ACCESS_TTL_MIN is not set.
+func accessTTL() time.Duration {
+	if getEnv("JWT_ACCESS_TTL_MIN", "") == "" {
+		if hours := getEnvAsInt("JWT_EXPIRY_HOUR", 0); hours > 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return time.Duration(getEnvAsInt("JWT_ACCESS_TTL_MIN", 15)) * time.Minute
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -94,3 +417,21 @@ func getEnvAsInt(key string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+func getEnvAsBool(key string, defaultVal bool) bool {
+	if value, err := strconv.ParseBool(getEnv(key, "")); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+// getEnvAsList splits a comma separated value, dropping empty items.
+func getEnvAsList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(getEnv(key, ""), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/config"
+	"github.com/maqsatto/Notes-API/internal/logger"
+)
+
+const (
+	// listenerPing is how often an idle listener checks its connection.
+	listenerPing = 90 * time.Second
+
+	// A failed LISTEN is retried after listenerMinRetry, doubling up to
+	// listenerMaxRetry, as pq does when it reconnects.
+	listenerMinRetry = time.Second
+	listenerMaxRetry = time.Minute
+)
+
+// Listen receives the notifications published on channel until ctx is
+// cancelled, passing each payload to onNotify. Until the first LISTEN
+// succeeds it is retried with backoff. Later the listener reconnects on its
+// own when the connection drops. Either way onReconnect is called once it
+// listens again, since notifications sent meanwhile are lost.
+func Listen(ctx context.Context, cfg config.DatabaseConfig, channel string, log *logger.Logger, onNotify func(payload string), onReconnect func()) {
+	listener := pq.NewListener(cfg.DSN(), listenerMinRetry, listenerMaxRetry, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Error("listener on "+channel, err)
+		}
+	})
+	defer listener.Close()
+
+	for retry := listenerMinRetry; ; retry = min(2*retry, listenerMaxRetry) {
+		err := listener.Listen(channel)
+		if err == nil || errors.Is(err, pq.ErrChannelAlreadyOpen) {
+			if retry > listenerMinRetry {
+				// Notifications sent while not listening are lost.
+				onReconnect()
+			}
+			break
+		}
+		log.Error("listen on "+channel, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+
+	ping := time.NewTicker(listenerPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// A nil notification follows a reconnection.
+			if n == nil {
+				onReconnect()
+				continue
+			}
+			onNotify(n.Extra)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package diff produces unified line diffs between two texts.
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultContext is the number of unchanged lines shown around each
+	// change, as in diff -u.
+	DefaultContext = 3
+	// MaxEditDistance bounds the number of inserted and deleted lines a diff
+	// may have. Finding the edits takes O((N+M)·D) time and O(D²) memory.
+	MaxEditDistance = 1000
+)
+
+// ErrTooLarge is returned when the texts differ in more than
+// MaxEditDistance lines.
+var ErrTooLarge = errors.New("diff too large")
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+	// number of lines of a and b consumed before this op
+	aPos, bPos int
+}
+
+// Unified returns a unified diff turning a into b, or "" when they are
+// equal. aName and bName are used in the --- and +++ header lines. A negative
+// context selects DefaultContext. It fails with ErrTooLarge when the texts
+// differ too much.
+func Unified(aName, bName, a, b string, context int) (string, error) {
+	if a == b {
+		return "", nil
+	}
+	if context < 0 {
+		context = DefaultContext
+	}
+
+	ops, err := diffLines(splitLines(a), splitLines(b))
+	if err != nil {
+		return "", err
+	}
+	var aPos, bPos int
+	for i := range ops {
+		ops[i].aPos, ops[i].bPos = aPos, bPos
+		if ops[i].kind != opInsert {
+			aPos++
+		}
+		if ops[i].kind != opDelete {
+			bPos++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	for _, h := range hunks(ops, context) {
+		writeHunk(&sb, ops[h[0]:h[1]])
+	}
+	return sb.String(), nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes an edit script with Myers' O((N+M)D) algorithm after
+// stripping the common prefix and suffix.
+func diffLines(a, b []string) ([]op, error) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	middle, err := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	if err != nil {
+		return nil, err
+	}
+	ops := make([]op, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{kind: opEqual, line: line})
+	}
+	ops = append(ops, middle...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{kind: opEqual, line: line})
+	}
+	return ops, nil
+}
+
+func myers(a, b []string) ([]op, error) {
+	n, m := len(a), len(b)
+	total := n + m
+	if total == 0 {
+		return nil, nil
+	}
+
+	offset := total
+	v := make([]int, 2*total+1)
+	// trace[d] holds v for diagonals -d..d as it was before step d; the
+	// backtrack reads nothing outside them.
+	trace := make([][]int, 0, 16)
+
+	var d int
+search:
+	for d = 0; d <= total; d++ {
+		if d > MaxEditDistance {
+			return nil, ErrTooLarge
+		}
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// walk the trace backwards to recover the edit path
+	ops := make([]op, 0, n+m)
+	x, y := n, m
+	for ; d > 0; d-- {
+		vPrev := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && vPrev[d+k-1] < vPrev[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := vPrev[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, op{kind: opEqual, line: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, op{kind: opInsert, line: b[y-1]})
+		} else {
+			ops = append(ops, op{kind: opDelete, line: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, op{kind: opEqual, line: a[x-1]})
+		x--
+		y--
+	}
+
+	slices.Reverse(ops)
+	return ops, nil
+}
+
+// hunks groups changes separated by at most 2*context unchanged lines and
+// returns [start, end) index ranges into ops, context lines included.
+func hunks(ops []op, context int) [][2]int {
+	var out [][2]int
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = run
+		}
+		out = append(out, [2]int{start, end})
+		i = end
+	}
+	return out
+}
+
+func writeHunk(sb *strings.Builder, ops []op) {
+	var aCount, bCount int
+	for _, o := range ops {
+		if o.kind != opInsert {
+			aCount++
+		}
+		if o.kind != opDelete {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n",
+		hunkRange(ops[0].aPos, aCount), hunkRange(ops[0].bPos, bCount))
+	for _, o := range ops {
+		sb.WriteByte(byte(o.kind))
+		sb.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk range from the number of lines before it. As in
+// diff -u, an empty range is reported at the line preceding it.
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
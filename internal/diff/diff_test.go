@@ -0,0 +1,151 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lines returns the lines "1".."n" with changes applied, each ending in a
+// newline.
+func lines(n int, changes map[int]string) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		line, ok := changes[i]
+		if !ok {
+			line = fmt.Sprint(i)
+		}
+		if line != "" {
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "insertion",
+			a:    "a\nb\nc\n",
+			b:    "a\nb\nX\nc\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,4 @@\n a\n b\n+X\n c\n",
+		},
+		{
+			name: "deletion",
+			a:    "a\nb\nc\n",
+			b:    "a\nc\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+		{
+			name: "replacement",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "context is trimmed",
+			a:    lines(10, nil),
+			b:    lines(10, map[int]string{5: "five"}),
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			a:    lines(20, nil),
+			b:    lines(20, map[int]string{5: "five", 13: "thirteen"}),
+			want: "--- a\n+++ b\n" +
+				"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n" +
+				"@@ -10,7 +10,7 @@\n 10\n 11\n 12\n-13\n+thirteen\n 14\n 15\n 16\n",
+		},
+		{
+			name: "hunks closer than twice the context are merged",
+			a:    lines(20, nil),
+			b:    lines(20, map[int]string{5: "five", 12: "twelve"}),
+			want: "--- a\n+++ b\n" +
+				"@@ -2,14 +2,14 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n 9\n 10\n 11\n-12\n+twelve\n 13\n 14\n 15\n",
+		},
+		{
+			name: "adjacent changes",
+			a:    lines(6, nil),
+			b:    lines(6, map[int]string{3: "three", 4: ""}),
+			want: "--- a\n+++ b\n@@ -1,6 +1,5 @@\n 1\n 2\n-3\n-4\n+three\n 5\n 6\n",
+		},
+		{
+			name: "change at the start",
+			a:    lines(6, nil),
+			b:    "0\n" + lines(6, nil),
+			want: "--- a\n+++ b\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "x\ny\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name: "to empty",
+			a:    "x\ny\n",
+			b:    "",
+			want: "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-x\n-y\n",
+		},
+		{
+			name: "newline added at end of file",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "no newline at end of either file",
+			a:    "a\nb",
+			b:    "a\nc",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unified("a", "b", tt.a, tt.b, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedWithoutContext(t *testing.T) {
+	got, err := Unified("a", "b", lines(10, nil), lines(10, map[int]string{5: "five", 7: ""}), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- a\n+++ b\n@@ -5 +5 @@\n-5\n+five\n@@ -7 +6,0 @@\n-7\n"
+	if got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedEditDistanceBound(t *testing.T) {
+	a := lines(MaxEditDistance, nil)
+	if _, err := Unified("a", "b", a, "", -1); err != nil {
+		t.Fatalf("%d deleted lines: %v", MaxEditDistance, err)
+	}
+	if _, err := Unified("a", "b", a+"x\n", "", -1); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("%d deleted lines: err = %v, want %v", MaxEditDistance+1, err, ErrTooLarge)
+	}
+	// lines in common do not count towards the bound
+	common := lines(5*MaxEditDistance, nil)
+	if _, err := Unified("a", "b", common+"x\n", common+"y\n", -1); err != nil {
+		t.Fatalf("one changed line among many: %v", err)
+	}
+}
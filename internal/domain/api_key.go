@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// API key scopes
+const (
+	ScopeNotesRead  = "notes:read"
+	ScopeNotesWrite = "notes:write"
+)
+
+// Scopes lists every scope an API key can be granted.
+var Scopes = []string{ScopeNotesRead, ScopeNotesWrite}
+
+// APIKey is a long-lived credential for scripts. Only a hash of the key is
+// stored; Prefix is the public part used to look the key up.
+type APIKey struct {
+	ID         uint64
+	UserID     uint64
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Expired reports whether the key expired by now.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
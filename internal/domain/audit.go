@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Audit actions
+const (
+	AuditLoginLockout = "login.lockout"
+	AuditIdentityLink = "identity.link"
+
+	AuditUserSuspend    = "admin.user.suspend"
+	AuditUserUnsuspend  = "admin.user.unsuspend"
+	AuditUserForceReset = "admin.user.force_password_reset"
+	AuditUserRestore    = "admin.user.restore"
+	AuditUserSetRole    = "admin.user.set_role"
+	AuditRoleSave       = "admin.role.save"
+	AuditRoleDelete     = "admin.role.delete"
+)
+
+// AuditEntry records a security relevant event. UserID is the account the
+// event concerns and ActorID the administrator who caused it; either is nil
+// when not applicable.
+type AuditEntry struct {
+	ID        uint64
+	UserID    *uint64
+	ActorID   *uint64
+	Action    string
+	IP        string
+	Details   string
+	CreatedAt time.Time
+}
+
+// LoginAttempts is the failed login state of an account or client address.
+type LoginAttempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
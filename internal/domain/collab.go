@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CollabState is the shared document of a note that is being edited
+// collaboratively. Every accepted edit bumps Revision; the content reaches
+// the note itself when it is persisted, which records PersistedRevision.
+type CollabState struct {
+	NoteID            uint64
+	Revision          int64
+	Content           string
+	PersistedRevision int64
+	// SyncedHash is the hex SHA-256 of the note's content when the state
+	// last matched it. A note whose content hashes differently was changed
+	// outside the session.
+	SyncedHash string
+	// NoteContent is read from the note alongside the state.
+	NoteContent string
+}
+
+// Dirty reports whether the state has edits the note does not have yet.
+func (s *CollabState) Dirty() bool {
+	return s.PersistedRevision < s.Revision
+}
+
+// NoteEdit is an entry in a note's edit log. It carries either an operation
+// in the ot package's JSON format or, when the document was replaced as a
+// whole, its new Content.
+type NoteEdit struct {
+	NoteID    uint64
+	Revision  int64
+	UserID    uint64
+	ClientID  string
+	Operation json.RawMessage
+	Content   string
+	CreatedAt time.Time
+}
+
+// IsReset reports whether the edit replaced the whole document.
+func (e *NoteEdit) IsReset() bool {
+	return e.Operation == nil
+}
+
+// CollabPresence is where a client editing a note has its cursor. Positions
+// are in the same units as edit operations; nil means the client has not
+// reported one yet.
+type CollabPresence struct {
+	ClientID     string
+	UserID       uint64
+	Username     string
+	Cursor       *int
+	SelectionEnd *int
+}
+
+type CollabEventType string
+
+const (
+	// CollabEventInit is the first event of a session: the document, its
+	// revision and who else is editing.
+	CollabEventInit CollabEventType = "init"
+	// CollabEventEdit is an edit made by another client.
+	CollabEventEdit CollabEventType = "edit"
+	// CollabEventAck confirms the client's own edit and its revision.
+	CollabEventAck CollabEventType = "ack"
+	// CollabEventReset replaces the client's document. Pending edits must be
+	// dropped.
+	CollabEventReset    CollabEventType = "reset"
+	CollabEventPresence CollabEventType = "presence"
+	CollabEventLeave    CollabEventType = "leave"
+)
+
+// CollabEvent is sent to the clients of a collaborative editing session.
+// Which fields are set depends on Type.
+type CollabEvent struct {
+	Type      CollabEventType
+	Revision  int64
+	Content   string
+	Operation json.RawMessage
+	// Presence is the client an edit, presence or leave event is about.
+	Presence *CollabPresence
+	// ClientID, CanEdit and Peers describe the session in an init event.
+	ClientID string
+	CanEdit  bool
+	Peers    []CollabPresence
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // Common / Base errors
 
@@ -35,10 +39,41 @@ var (
 	ErrPasswordMissingDigit     = errors.New("password must contain at least one digit")
 	ErrPasswordMissingSpecial   = errors.New("password must contain at least one special character")
 
+	// Password policy errors
+	ErrPasswordTooCommon            = errors.New("password is too common, choose a less predictable one")
+	ErrPasswordBreached             = errors.New("password has appeared in a data breach, choose a different one")
+	ErrPasswordContainsPersonalInfo = errors.New("password must not contain your username or email address")
+
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrPasswordMismatch   = errors.New("password mismatch")
 
 	ErrUserDeleted = errors.New("user is deleted")
+
+	ErrEmailNotVerified     = errors.New("email address is not verified")
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+
+	ErrAccountSuspended      = errors.New("account is suspended")
+	ErrPasswordResetRequired = errors.New("password must be reset before logging in, check your email")
+)
+
+// Two-factor authentication errors
+
+var (
+	ErrInvalidTOTPCode         = errors.New("invalid two-factor code")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment has not been started")
+)
+
+// Role errors
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrInvalidPermission = errors.New("unknown permission")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrBuiltInRole       = errors.New("built-in roles cannot be changed")
+	ErrPermissionNotHeld = errors.New("cannot grant a permission you do not hold")
 )
 
 // Note errors
@@ -54,8 +89,36 @@ var (
 	ErrNoteAccessDenied = errors.New("access to note denied")
 	ErrNoteDeleted      = errors.New("note is deleted")
 
+	// ErrNoteVersionMismatch is a conflict: the note was changed since the
+	// version the client had.
+	ErrNoteVersionMismatch = fmt.Errorf("%w: note was modified since it was read", ErrConflict)
+	ErrNoteVersionRequired = errors.New("note version required, send the note's ETag in If-Match")
+
 	ErrInvalidTags = errors.New("invalid tags")
 	ErrTooManyTags = errors.New("too many tags")
+	ErrTagNotFound = errors.New("tag not found")
+
+	ErrRevisionNotFound     = errors.New("note revision not found")
+	ErrRevisionDiffTooLarge = errors.New("revisions differ too much to diff")
+
+	ErrNotebookNotFound     = errors.New("notebook not found")
+	ErrInvalidNotebookName  = errors.New("invalid notebook name")
+	ErrNotebookAccessDenied = errors.New("access to notebook denied")
+	ErrNotebookCycle        = errors.New("notebook cannot be moved into itself or one of its sub-notebooks")
+
+	ErrShareNotFound    = errors.New("share not found")
+	ErrInvalidShareRole = errors.New("role must be viewer, commenter or editor")
+	ErrShareWithOwner   = errors.New("cannot share with the owner")
+
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrInvalidComment  = errors.New("invalid comment")
+
+	ErrShareLinkNotFound         = errors.New("share link not found or expired")
+	ErrShareLinkPasswordRequired = errors.New("this link is password protected")
+	ErrShareLinkWrongPassword    = errors.New("wrong password for this link")
+
+	ErrInvalidEditOperation = errors.New("invalid edit operation")
+	ErrEditOutOfSync        = errors.New("document changed, reload it and reapply pending edits")
 )
 
 // Repository / persistence errors
@@ -75,6 +138,31 @@ var (
 	ErrExpiredToken   = errors.New("token expired")
 	ErrMissingToken   = errors.New("missing token")
 	ErrMalformedToken = errors.New("malformed token")
+	ErrRevokedToken   = errors.New("token revoked")
+	ErrTokenReused    = errors.New("refresh token reuse detected")
+
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrInvalidScope   = errors.New("invalid scope")
+
+	ErrOAuthClientNotFound       = errors.New("oauth client not found")
+	ErrOAuthGrantNotFound        = errors.New("authorized app not found")
+	ErrOAuthInvalidClient        = errors.New("client authentication failed")
+	ErrOAuthInvalidGrant         = errors.New("authorization grant is invalid, expired or revoked")
+	ErrOAuthInvalidRedirectURI   = errors.New("redirect uri is not registered for the client")
+	ErrOAuthUnsupportedGrantType = errors.New("unsupported grant type")
+	ErrOAuthPKCERequired         = errors.New("a PKCE code challenge with the S256 method is required")
+
+	ErrIdentityProviderNotFound = errors.New("identity provider not found")
+	ErrIdentityNotFound         = errors.New("linked identity not found")
+	ErrExternalLoginFailed      = errors.New("login with the identity provider failed")
+	ErrExternalEmailNotVerified = errors.New("the identity provider has not verified your email address")
+	ErrIdentityLinkUnverified   = errors.New("an account with this email address exists but the address is not verified, log in with your password and verify it first")
+
+	ErrTooManyAttempts = errors.New("too many failed login attempts, try again later")
+	ErrAccountLocked   = errors.New("account temporarily locked after too many failed login attempts")
 
 	ErrInvalidIssuer        = errors.New("invalid token issuer")
 	ErrInvalidSigningMethod = errors.New("invalid signing method")
@@ -83,9 +171,10 @@ var (
 // Pagination / filtering
 
 var (
-	ErrInvalidLimit       = errors.New("invalid limit")
-	ErrInvalidOffset      = errors.New("invalid offset")
-	ErrInvalidSearchQuery = errors.New("invalid search query")
+	ErrInvalidLimit          = errors.New("invalid limit")
+	ErrInvalidOffset         = errors.New("invalid offset")
+	ErrInvalidSearchQuery    = errors.New("invalid search query")
+	ErrInvalidSearchLanguage = errors.New("unsupported search language")
 )
 
 // Service-level errors
@@ -94,3 +183,13 @@ var (
 	ErrOperationNotAllowed = errors.New("operation not allowed")
 	ErrStateViolation      = errors.New("state violation")
 )
+
+// RetryAfterError tells the client when a throttled request may be retried.
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string { return e.Err.Error() }
+
+func (e *RetryAfterError) Unwrap() error { return e.Err }
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Identity links a user to their account at an external OpenID Connect
+// provider. Subject is the provider's stable id for that account.
+type Identity struct {
+	ID          uint64
+	UserID      uint64
+	Provider    string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+// OIDCLoginState remembers a login sent to a provider until it comes back.
+// StateHash is the hash of the state parameter that identifies it.
+type OIDCLoginState struct {
+	StateHash    string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
@@ -3,12 +3,27 @@ package domain
 import "time"
 
 type Note struct {
-	ID        uint64     `json:"id"`
-	UserID    uint64     `json:"user_id"`
-	Title     string     `json:"title"`
-	Content   string     `json:"content"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
-	Tags      []string   `json:"tags"`
+	ID     uint64 `json:"id"`
+	UserID uint64 `json:"user_id"`
+	// NotebookID is nil for notes outside any notebook.
+	NotebookID *uint64    `json:"notebook_id"`
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	Tags       []string   `json:"tags"`
+	// Version is bumped by every change to the note. Writes made with a
+	// stale version fail with ErrNoteVersionMismatch.
+	Version int64 `json:"version"`
+}
+
+// NoteSearchResult is a note matched by full-text search, with its relevance
+// and fragments of title and content where matches are wrapped in
+// <mark></mark>. The highlights are not HTML-escaped.
+type NoteSearchResult struct {
+	Note
+	Rank             float64 `json:"rank"`
+	TitleHighlight   string  `json:"title_highlight"`
+	ContentHighlight string  `json:"content_highlight"`
 }
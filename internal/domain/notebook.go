@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Notebook groups notes. Notebooks nest: ParentID is nil for top-level ones,
+// and Position orders a notebook among its siblings.
+type Notebook struct {
+	ID        uint64    `json:"id"`
+	UserID    uint64    `json:"user_id"`
+	ParentID  *uint64   `json:"parent_id"`
+	Name      string    `json:"name"`
+	Position  int       `json:"position"`
+	NoteCount int64     `json:"note_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NoteScope narrows note listings and searches to a notebook, with or
+// without its sub-notebooks. The zero value covers all of a user's notes.
+type NoteScope struct {
+	NotebookID uint64
+	Recursive  bool
+}
+
+// NotebookDeleteMode says what happens to the notes of a deleted notebook.
+type NotebookDeleteMode string
+
+const (
+	// NotebookDeleteMoveNotes moves the notes and sub-notebooks up into the
+	// deleted notebook's parent.
+	NotebookDeleteMoveNotes NotebookDeleteMode = "move"
+	// NotebookDeleteTrashNotes deletes the sub-notebooks too and moves every
+	// note in them to the trash.
+	NotebookDeleteTrashNotes NotebookDeleteMode = "trash"
+)
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// OAuthClient is a third-party app registered to request delegated access.
+// Confidential clients authenticate with a secret, stored hashed; public
+// ones, such as mobile apps, rely on PKCE alone.
+type OAuthClient struct {
+	ID           string
+	OwnerID      uint64
+	Name         string
+	SecretHash   string
+	RedirectURIs []string
+	Scopes       []string
+	CreatedAt    time.Time
+}
+
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// AuthorizationRequest is what a client asks the user to approve.
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OAuthAuthorizationCode is a stored, hashed single-use code a client trades
+// for tokens. CodeChallenge is the PKCE S256 challenge it was requested with.
+// RedirectURIGiven records whether the authorization request named
+// RedirectURI, in which case the token request must name it too.
+type OAuthAuthorizationCode struct {
+	CodeHash         string
+	ClientID         string
+	UserID           uint64
+	RedirectURI      string
+	RedirectURIGiven bool
+	Scopes           []string
+	CodeChallenge    string
+	ExpiresAt        time.Time
+	CreatedAt        time.Time
+}
+
+// OAuthGrant records the scopes a user approved for a client: an authorized
+// app.
+type OAuthGrant struct {
+	ID         uint64
+	UserID     uint64
+	ClientID   string
+	ClientName string
+	Scopes     []string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Covers reports whether the grant includes all of scopes.
+func (g *OAuthGrant) Covers(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(g.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// TokenIntrospection describes a token to the client holding it (RFC 7662).
+// Only Active is meaningful for inactive tokens.
+type TokenIntrospection struct {
+	Active    bool
+	TokenType string
+	ClientID  string
+	UserID    uint64
+	Username  string
+	Scopes    []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
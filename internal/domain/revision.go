@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// NoteRevision is a snapshot of a note taken every time it is written. The
+// revision with the highest number matches the note's current state.
+type NoteRevision struct {
+	ID        uint64    `json:"id"`
+	NoteID    uint64    `json:"note_id"`
+	Revision  int       `json:"revision"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Built-in roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Permissions checked by the admin API. PermAll grants every permission.
+const (
+	PermAll                = "*"
+	PermUsersRead          = "users:read"
+	PermUsersSuspend       = "users:suspend"
+	PermUsersResetPassword = "users:reset-password"
+	PermUsersRestore       = "users:restore"
+	PermUsersAssignRole    = "users:assign-role"
+	PermRolesManage        = "roles:manage"
+)
+
+// Permissions lists every permission a custom role can be granted. PermAll
+// is reserved for the built-in admin role, which only the bootstrap command
+// assigns.
+var Permissions = []string{
+	PermUsersRead,
+	PermUsersSuspend,
+	PermUsersResetPassword,
+	PermUsersRestore,
+	PermUsersAssignRole,
+	PermRolesManage,
+}
+
+// Role is a named set of permissions. Built-in roles cannot be changed or
+// deleted; custom roles are managed through the admin API.
+type Role struct {
+	Name        string
+	Description string
+	Permissions []string
+	BuiltIn     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// HasPermission reports whether granted includes perm, directly or through
+// PermAll.
+func HasPermission(granted []string, perm string) bool {
+	return slices.Contains(granted, PermAll) || slices.Contains(granted, perm)
+}
+
+// UserSummary is a user as seen by administrators.
+type UserSummary struct {
+	User
+	NoteCount int64
+}
+
+// UserStatus filters users in the admin API.
+type UserStatus string
+
+const (
+	UserStatusActive    UserStatus = "active"
+	UserStatusSuspended UserStatus = "suspended"
+	UserStatusDeleted   UserStatus = "deleted"
+	UserStatusAll       UserStatus = "all"
+)
+
+// UserFilter selects users in the admin API. Query matches email and
+// username; an empty Status means UserStatusAll except deleted users.
+type UserFilter struct {
+	Query  string
+	Status UserStatus
+	Role   string
+}
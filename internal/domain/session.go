@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// Session is one login of a user. Its ID is the family of the refresh tokens
+// issued for it and is carried in every access token as the "sid" claim.
+// Sessions opened for an OAuth client carry its ID and the granted scopes.
+type Session struct {
+	ID         string
+	UserID     uint64
+	ClientID   *string
+	Scopes     []string
+	Device     string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// ClientInfo describes the client a session is opened from.
+type ClientInfo struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
@@ -0,0 +1,95 @@
+package domain
+
+import "time"
+
+// ShareRole is what a share lets the user it is granted to do.
+type ShareRole string
+
+const (
+	ShareRoleViewer    ShareRole = "viewer"
+	ShareRoleCommenter ShareRole = "commenter"
+	ShareRoleEditor    ShareRole = "editor"
+	// ShareRoleOwner is never granted; it is reported for a user's own notes.
+	ShareRoleOwner ShareRole = "owner"
+)
+
+// Valid reports whether the role can be granted.
+func (r ShareRole) Valid() bool {
+	switch r {
+	case ShareRoleViewer, ShareRoleCommenter, ShareRoleEditor:
+		return true
+	}
+	return false
+}
+
+// NoteAccess is how much a user may do with a note. Levels are ordered, and
+// each allows everything the lower ones do.
+type NoteAccess int
+
+const (
+	AccessNone NoteAccess = iota
+	// AccessView allows reading the note, its revisions and comments.
+	AccessView
+	// AccessComment also allows commenting on it.
+	AccessComment
+	// AccessEdit also allows changing its title, content and tags.
+	AccessEdit
+	// AccessOwner also allows moving, deleting and sharing it.
+	AccessOwner
+)
+
+// Role returns the role that grants the access level.
+func (a NoteAccess) Role() ShareRole {
+	switch {
+	case a >= AccessOwner:
+		return ShareRoleOwner
+	case a == AccessEdit:
+		return ShareRoleEditor
+	case a == AccessComment:
+		return ShareRoleCommenter
+	case a == AccessView:
+		return ShareRoleViewer
+	}
+	return ""
+}
+
+// Share grants a user access to a note, or to a notebook and every note and
+// notebook nested in it. Exactly one of NoteID and NotebookID is set.
+type Share struct {
+	ID         uint64    `json:"id"`
+	NoteID     *uint64   `json:"note_id,omitempty"`
+	NotebookID *uint64   `json:"notebook_id,omitempty"`
+	UserID     uint64    `json:"user_id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	Role       ShareRole `json:"role"`
+	CreatedBy  uint64    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SharedNote is a note another user shared with the user, directly or
+// through one of the notebooks it is in.
+type SharedNote struct {
+	Note
+	Role          ShareRole `json:"role"`
+	OwnerUsername string    `json:"owner_username"`
+}
+
+// SharedNotebook is a notebook another user shared with the user.
+type SharedNotebook struct {
+	Notebook
+	Role          ShareRole `json:"role"`
+	OwnerUsername string    `json:"owner_username"`
+}
+
+// NoteComment is a comment left on a note by its owner or a user it is
+// shared with.
+type NoteComment struct {
+	ID        uint64    `json:"id"`
+	NoteID    uint64    `json:"note_id"`
+	UserID    uint64    `json:"user_id"`
+	Username  string    `json:"username"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// ShareLink lets anyone holding its token read a note without an account.
+// Only a hash of the token is stored. PasswordHash is empty for links that
+// need no password.
+type ShareLink struct {
+	ID           uint64
+	NoteID       uint64
+	CreatedBy    uint64
+	TokenHash    string
+	PasswordHash string
+	ExpiresAt    *time.Time
+	ViewCount    int64
+	LastViewedAt *time.Time
+	CreatedAt    time.Time
+}
+
+func (l *ShareLink) HasPassword() bool {
+	return l.PasswordHash != ""
+}
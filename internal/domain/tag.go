@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+type Tag struct {
+	ID        uint64    `json:"id"`
+	UserID    uint64    `json:"user_id"`
+	Name      string    `json:"name"`
+	NoteCount int64     `json:"note_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
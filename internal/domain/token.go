@@ -0,0 +1,59 @@
+package domain
+
+import "time"
+
+// RefreshToken is a stored, hashed refresh token. Tokens issued by rotating
+// one another share a FamilyID, which ties them to a single login.
+type RefreshToken struct {
+	ID        uint64
+	UserID    uint64
+	FamilyID  string
+	ParentID  *uint64
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// TokenPair is what a successful login or refresh hands back to the client.
+// Scopes is set for pairs issued to OAuth clients.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	Scopes           []string
+}
+
+// PasswordResetToken is a stored, hashed single-use token mailed to a user
+// who forgot their password.
+type PasswordResetToken struct {
+	ID        uint64
+	UserID    uint64
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type EmailTokenPurpose string
+
+const (
+	// EmailTokenVerify proves ownership of the account's current address.
+	EmailTokenVerify EmailTokenPurpose = "verify"
+	// EmailTokenChange confirms a new address before it replaces the old one.
+	EmailTokenChange EmailTokenPurpose = "change"
+)
+
+// EmailToken is a stored, hashed single-use token mailed to Email.
+type EmailToken struct {
+	ID        uint64
+	UserID    uint64
+	Purpose   EmailTokenPurpose
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
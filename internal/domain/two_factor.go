@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// TwoFactorEnrollment is handed to the user to set up an authenticator app.
+type TwoFactorEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// LoginChallenge is the pending second step of a login for a user with
+// two-factor authentication enabled.
+type LoginChallenge struct {
+	ID        uint64
+	UserID    uint64
+	TokenHash string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// LoginResult is the outcome of the password step of a login. Either Tokens
+// is set, or a second factor is required and ChallengeToken must be
+// exchanged together with a code.
+type LoginResult struct {
+	User               *User
+	Tokens             *TokenPair
+	ChallengeToken     string
+	ChallengeExpiresAt time.Time
+}
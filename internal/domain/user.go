@@ -3,11 +3,37 @@ package domain
 import "time"
 
 type User struct {
-	ID        uint64     `json:"id"`
-	Email     string     `json:"email"`
-	Username  string     `json:"username"`
-	Password  string     `json:"-"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	ID              uint64     `json:"id"`
+	Email           string     `json:"email"`
+	Username        string     `json:"username"`
+	Password        string     `json:"-"`
+	SearchLanguage  string     `json:"search_language"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+
+	// Role names the user's Role; SuspendedAt and PasswordResetRequired are
+	// set by administrators and block logins.
+	Role                  string     `json:"role"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	PasswordResetRequired bool       `json:"-"`
+
+	// TOTPSecret is set once enrollment starts; two-factor authentication is
+	// on only after TOTPEnabledAt is set by confirming a first code.
+	TOTPSecret    string     `json:"-"`
+	TOTPEnabledAt *time.Time `json:"-"`
+	TOTPLastStep  int64      `json:"-"`
+}
+
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
 }
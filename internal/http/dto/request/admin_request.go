@@ -0,0 +1,10 @@
+package request
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type SaveRoleRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
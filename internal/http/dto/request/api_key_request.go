@@ -0,0 +1,11 @@
+package request
+
+import "time"
+
+// CreateAPIKeyRequest creates a personal API key. ExpiresAt is optional; keys
+// without it stay valid until revoked.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,15 @@
+package request
+
+import "encoding/json"
+
+// CollabMessage is a message a client sends over a collaborative editing
+// connection. An "edit" carries an operation in ot.js format and the
+// revision it was made on; a "cursor" carries the client's cursor and
+// selection end.
+type CollabMessage struct {
+	Type         string          `json:"type"`
+	Revision     int64           `json:"revision"`
+	Operation    json.RawMessage `json:"operation"`
+	Cursor       *int            `json:"cursor"`
+	SelectionEnd *int            `json:"selection_end"`
+}
@@ -0,0 +1,20 @@
+package request
+
+type CreateNoteRequest struct {
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	Tags       []string `json:"tags"`
+	NotebookID *uint64  `json:"notebook_id"`
+}
+
+type UpdateNoteRequest struct {
+	Title   string   `json:"title"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags"`
+}
+
+// MoveNoteRequest files a note in a notebook; a null notebook_id takes it
+// out of any.
+type MoveNoteRequest struct {
+	NotebookID *uint64 `json:"notebook_id"`
+}
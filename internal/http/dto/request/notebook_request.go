@@ -0,0 +1,17 @@
+package request
+
+type CreateNotebookRequest struct {
+	Name     string  `json:"name"`
+	ParentID *uint64 `json:"parent_id"`
+}
+
+type RenameNotebookRequest struct {
+	Name string `json:"name"`
+}
+
+// MoveNotebookRequest places a notebook under parent_id, null for the top
+// level, at position among its siblings; without position it goes last.
+type MoveNotebookRequest struct {
+	ParentID *uint64 `json:"parent_id"`
+	Position *int    `json:"position"`
+}
@@ -0,0 +1,23 @@
+package request
+
+type RegisterOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	// Confidential clients, which can keep a secret, get one to
+	// authenticate with.
+	Confidential bool `json:"confidential"`
+}
+
+// AuthorizeDecisionRequest repeats the client's authorization request along
+// with the user's answer on the consent screen. Scope is space separated.
+type AuthorizeDecisionRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
@@ -0,0 +1,11 @@
+package request
+
+import "time"
+
+// CreateShareLinkRequest creates a public link to a note. Both fields are
+// optional: without a password anyone with the link can read the note, and
+// without ExpiresAt the link works until it is revoked.
+type CreateShareLinkRequest struct {
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
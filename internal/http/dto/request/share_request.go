@@ -0,0 +1,12 @@
+package request
+
+// ShareRequest shares a note or notebook with the user whose username or
+// email address is in user.
+type ShareRequest struct {
+	User string `json:"user"`
+	Role string `json:"role"`
+}
+
+type CreateCommentRequest struct {
+	Body string `json:"body"`
+}
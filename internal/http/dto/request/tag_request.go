@@ -0,0 +1,5 @@
+package request
+
+type RenameTagRequest struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,18 @@
+package request
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorReauthRequest re-authenticates with the password and a TOTP or
+// recovery code.
+type TwoFactorReauthRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	Device         string `json:"device"`
+}
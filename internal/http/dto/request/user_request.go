@@ -1 +1,47 @@
 package request
+
+type RegisterRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginRequest accepts either an email address or a username in Login.
+// Device optionally names the client in the session list.
+type LoginRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	Device   string `json:"device"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// EmailTokenRequest carries a token mailed to confirm an address.
+type EmailTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type UpdateProfileRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type UpdateSearchLanguageRequest struct {
+	Language string `json:"language"`
+}
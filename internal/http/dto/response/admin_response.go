@@ -0,0 +1,93 @@
+package response
+
+import (
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+// AdminUserResponse is a user as seen by administrators.
+type AdminUserResponse struct {
+	ID                    uint64     `json:"id"`
+	Email                 string     `json:"email"`
+	Username              string     `json:"username"`
+	Role                  string     `json:"role"`
+	EmailVerified         bool       `json:"email_verified"`
+	TwoFactorEnabled      bool       `json:"two_factor_enabled"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	NoteCount             int64      `json:"note_count"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty"`
+}
+
+type AdminUserListResponse struct {
+	Users  []AdminUserResponse `json:"users"`
+	Total  int64               `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+type RoleResponse struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	BuiltIn     bool      `json:"built_in"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type RoleListResponse struct {
+	Roles       []RoleResponse `json:"roles"`
+	Permissions []string       `json:"permissions"`
+}
+
+func NewAdminUserResponse(u *domain.UserSummary) AdminUserResponse {
+	return AdminUserResponse{
+		ID:                    u.ID,
+		Email:                 u.Email,
+		Username:              u.Username,
+		Role:                  u.Role,
+		EmailVerified:         u.EmailVerified(),
+		TwoFactorEnabled:      u.TwoFactorEnabled(),
+		PasswordResetRequired: u.PasswordResetRequired,
+		NoteCount:             u.NoteCount,
+		CreatedAt:             u.CreatedAt,
+		UpdatedAt:             u.UpdatedAt,
+		SuspendedAt:           u.SuspendedAt,
+		DeletedAt:             u.DeletedAt,
+	}
+}
+
+func NewAdminUserListResponse(users []*domain.UserSummary, total int64, limit, offset int) AdminUserListResponse {
+	out := make([]AdminUserResponse, 0, len(users))
+	for _, u := range users {
+		out = append(out, NewAdminUserResponse(u))
+	}
+	return AdminUserListResponse{Users: out, Total: total, Limit: limit, Offset: offset}
+}
+
+func NewRoleResponse(role *domain.Role) RoleResponse {
+	perms := role.Permissions
+	if perms == nil {
+		perms = []string{}
+	}
+	return RoleResponse{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: perms,
+		BuiltIn:     role.BuiltIn,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+// NewRoleListResponse also lists the permissions roles can be granted.
+func NewRoleListResponse(roles []*domain.Role) RoleListResponse {
+	out := make([]RoleResponse, 0, len(roles))
+	for _, r := range roles {
+		out = append(out, NewRoleResponse(r))
+	}
+	return RoleListResponse{Roles: out, Permissions: domain.Permissions}
+}
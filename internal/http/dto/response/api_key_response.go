@@ -0,0 +1,48 @@
+package response
+
+import (
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type APIKeyResponse struct {
+	ID         uint64     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse carries the key itself, which is only shown once.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type APIKeyListResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
+
+func NewAPIKeyResponse(k *domain.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     auth.APIKeyPrefix + k.Prefix,
+		Scopes:     k.Scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+func NewAPIKeyListResponse(keys []*domain.APIKey) APIKeyListResponse {
+	out := make([]APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, NewAPIKeyResponse(k))
+	}
+	return APIKeyListResponse{APIKeys: out}
+}
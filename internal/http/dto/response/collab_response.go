@@ -0,0 +1,97 @@
+package response
+
+import (
+	"encoding/json"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+// CollabPeerResponse is a client editing the same note.
+type CollabPeerResponse struct {
+	ClientID     string `json:"client_id"`
+	UserID       uint64 `json:"user_id"`
+	Username     string `json:"username"`
+	Cursor       *int   `json:"cursor"`
+	SelectionEnd *int   `json:"selection_end"`
+}
+
+type CollabInitMessage struct {
+	Type     string               `json:"type"`
+	Revision int64                `json:"revision"`
+	Content  string               `json:"content"`
+	ClientID string               `json:"client_id"`
+	CanEdit  bool                 `json:"can_edit"`
+	Peers    []CollabPeerResponse `json:"peers"`
+}
+
+type CollabEditMessage struct {
+	Type      string             `json:"type"`
+	Revision  int64              `json:"revision"`
+	Operation json.RawMessage    `json:"operation"`
+	Author    CollabPeerResponse `json:"author"`
+}
+
+type CollabAckMessage struct {
+	Type     string `json:"type"`
+	Revision int64  `json:"revision"`
+}
+
+type CollabResetMessage struct {
+	Type     string `json:"type"`
+	Revision int64  `json:"revision"`
+	Content  string `json:"content"`
+}
+
+// CollabPresenceMessage reports a peer's presence or, with type "leave",
+// that it left.
+type CollabPresenceMessage struct {
+	Type string             `json:"type"`
+	Peer CollabPeerResponse `json:"peer"`
+}
+
+type CollabErrorMessage struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+func NewCollabPeerResponse(p domain.CollabPresence) CollabPeerResponse {
+	return CollabPeerResponse{
+		ClientID:     p.ClientID,
+		UserID:       p.UserID,
+		Username:     p.Username,
+		Cursor:       p.Cursor,
+		SelectionEnd: p.SelectionEnd,
+	}
+}
+
+// NewCollabMessage returns the message sent to a client for ev.
+func NewCollabMessage(ev domain.CollabEvent) any {
+	switch ev.Type {
+	case domain.CollabEventInit:
+		peers := make([]CollabPeerResponse, 0, len(ev.Peers))
+		for _, p := range ev.Peers {
+			peers = append(peers, NewCollabPeerResponse(p))
+		}
+		return CollabInitMessage{
+			Type:     string(ev.Type),
+			Revision: ev.Revision,
+			Content:  ev.Content,
+			ClientID: ev.ClientID,
+			CanEdit:  ev.CanEdit,
+			Peers:    peers,
+		}
+	case domain.CollabEventEdit:
+		return CollabEditMessage{
+			Type:      string(ev.Type),
+			Revision:  ev.Revision,
+			Operation: ev.Operation,
+			Author:    NewCollabPeerResponse(*ev.Presence),
+		}
+	case domain.CollabEventAck:
+		return CollabAckMessage{Type: string(ev.Type), Revision: ev.Revision}
+	case domain.CollabEventReset:
+		return CollabResetMessage{Type: string(ev.Type), Revision: ev.Revision, Content: ev.Content}
+	default:
+		return CollabPresenceMessage{Type: string(ev.Type), Peer: NewCollabPeerResponse(*ev.Presence)}
+	}
+}
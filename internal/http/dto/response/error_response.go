@@ -0,0 +1,5 @@
+package response
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
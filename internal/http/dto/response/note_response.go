@@ -1 +1,93 @@
 package response
+
+import (
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type NoteResponse struct {
+	ID         uint64     `json:"id"`
+	NotebookID *uint64    `json:"notebook_id"`
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	Tags       []string   `json:"tags"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	Version    int64      `json:"version"`
+}
+
+type NoteListResponse struct {
+	Notes  []NoteResponse `json:"notes"`
+	Total  int64          `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+type NoteSearchResultResponse struct {
+	NoteResponse
+	Rank             float64 `json:"rank"`
+	TitleHighlight   string  `json:"title_highlight"`
+	ContentHighlight string  `json:"content_highlight"`
+}
+
+type NoteSearchResponse struct {
+	Results []NoteSearchResultResponse `json:"results"`
+	Total   int64                      `json:"total"`
+	Limit   int                        `json:"limit"`
+	Offset  int                        `json:"offset"`
+}
+
+type EmptyTrashResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+func NewNoteResponse(note *domain.Note) NoteResponse {
+	tags := note.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return NoteResponse{
+		ID:         note.ID,
+		NotebookID: note.NotebookID,
+		Title:      note.Title,
+		Content:    note.Content,
+		Tags:       tags,
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  note.UpdatedAt,
+		DeletedAt:  note.DeletedAt,
+		Version:    note.Version,
+	}
+}
+
+func NewNoteListResponse(notes []*domain.Note, total int64, limit, offset int) NoteListResponse {
+	items := make([]NoteResponse, 0, len(notes))
+	for _, note := range notes {
+		items = append(items, NewNoteResponse(note))
+	}
+	return NoteListResponse{
+		Notes:  items,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+}
+
+func NewNoteSearchResponse(results []*domain.NoteSearchResult, total int64, limit, offset int) NoteSearchResponse {
+	items := make([]NoteSearchResultResponse, 0, len(results))
+	for _, res := range results {
+		items = append(items, NoteSearchResultResponse{
+			NoteResponse:     NewNoteResponse(&res.Note),
+			Rank:             res.Rank,
+			TitleHighlight:   res.TitleHighlight,
+			ContentHighlight: res.ContentHighlight,
+		})
+	}
+	return NoteSearchResponse{
+		Results: items,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}
+}
@@ -0,0 +1,66 @@
+package response
+
+import (
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type NotebookResponse struct {
+	ID        uint64    `json:"id"`
+	ParentID  *uint64   `json:"parent_id"`
+	Name      string    `json:"name"`
+	Position  int       `json:"position"`
+	NoteCount int64     `json:"note_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotebookTreeResponse is a notebook with its sub-notebooks, in order.
+type NotebookTreeResponse struct {
+	NotebookResponse
+	Children []NotebookTreeResponse `json:"children"`
+}
+
+type NotebookListResponse struct {
+	Notebooks []NotebookTreeResponse `json:"notebooks"`
+}
+
+func NewNotebookResponse(nb *domain.Notebook) NotebookResponse {
+	return NotebookResponse{
+		ID:        nb.ID,
+		ParentID:  nb.ParentID,
+		Name:      nb.Name,
+		Position:  nb.Position,
+		NoteCount: nb.NoteCount,
+		CreatedAt: nb.CreatedAt,
+		UpdatedAt: nb.UpdatedAt,
+	}
+}
+
+// NewNotebookListResponse nests a flat list of notebooks, siblings in order,
+// into trees under the top-level ones.
+func NewNotebookListResponse(notebooks []*domain.Notebook) NotebookListResponse {
+	// top-level notebooks are keyed 0, which no notebook id is
+	children := make(map[uint64][]*domain.Notebook, len(notebooks))
+	for _, nb := range notebooks {
+		var parent uint64
+		if nb.ParentID != nil {
+			parent = *nb.ParentID
+		}
+		children[parent] = append(children[parent], nb)
+	}
+
+	var build func(parent uint64) []NotebookTreeResponse
+	build = func(parent uint64) []NotebookTreeResponse {
+		items := make([]NotebookTreeResponse, 0, len(children[parent]))
+		for _, nb := range children[parent] {
+			items = append(items, NotebookTreeResponse{
+				NotebookResponse: NewNotebookResponse(nb),
+				Children:         build(nb.ID),
+			})
+		}
+		return items
+	}
+	return NotebookListResponse{Notebooks: build(0)}
+}
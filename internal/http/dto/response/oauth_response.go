@@ -0,0 +1,143 @@
+package response
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type OAuthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CreatedOAuthClientResponse carries the client secret, which is only shown
+// once.
+type CreatedOAuthClientResponse struct {
+	OAuthClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type OAuthClientListResponse struct {
+	Clients []OAuthClientResponse `json:"clients"`
+}
+
+func NewOAuthClientResponse(c *domain.OAuthClient) OAuthClientResponse {
+	return OAuthClientResponse{
+		ClientID:     c.ID,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectURIs,
+		Scopes:       c.Scopes,
+		Confidential: c.Confidential(),
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+func NewOAuthClientListResponse(clients []*domain.OAuthClient) OAuthClientListResponse {
+	out := make([]OAuthClientResponse, 0, len(clients))
+	for _, c := range clients {
+		out = append(out, NewOAuthClientResponse(c))
+	}
+	return OAuthClientListResponse{Clients: out}
+}
+
+// ConsentResponse is what the consent screen shows. With PreviouslyApproved
+// set the user already granted every scope and the screen may be skipped.
+type ConsentResponse struct {
+	ClientID           string   `json:"client_id"`
+	ClientName         string   `json:"client_name"`
+	Scopes             []string `json:"scopes"`
+	PreviouslyApproved bool     `json:"previously_approved"`
+}
+
+// AuthorizeDecisionResponse tells the consent screen where to send the user.
+type AuthorizeDecisionResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// OAuthTokenResponse is the token endpoint's answer (RFC 6749 section 5.1).
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+func NewOAuthTokenResponse(tokens *domain.TokenPair) OAuthTokenResponse {
+	return OAuthTokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(tokens.AccessExpiresAt).Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		Scope:        strings.Join(tokens.Scopes, " "),
+	}
+}
+
+// OAuthErrorResponse is an error of the token, introspection and revocation
+// endpoints (RFC 6749 section 5.2).
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IntrospectionResponse follows RFC 7662 section 2.2; inactive tokens only
+// report active=false.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+}
+
+func NewIntrospectionResponse(t *domain.TokenIntrospection) IntrospectionResponse {
+	if !t.Active {
+		return IntrospectionResponse{}
+	}
+	return IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(t.Scopes, " "),
+		ClientID:  t.ClientID,
+		Username:  t.Username,
+		TokenType: t.TokenType,
+		Exp:       t.ExpiresAt.Unix(),
+		Iat:       t.IssuedAt.Unix(),
+		Sub:       strconv.FormatUint(t.UserID, 10),
+	}
+}
+
+type AuthorizedAppResponse struct {
+	ClientID  string    `json:"client_id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AuthorizedAppListResponse struct {
+	Apps []AuthorizedAppResponse `json:"authorized_apps"`
+}
+
+func NewAuthorizedAppListResponse(grants []*domain.OAuthGrant) AuthorizedAppListResponse {
+	out := make([]AuthorizedAppResponse, 0, len(grants))
+	for _, g := range grants {
+		out = append(out, AuthorizedAppResponse{
+			ClientID:  g.ClientID,
+			Name:      g.ClientName,
+			Scopes:    g.Scopes,
+			CreatedAt: g.CreatedAt,
+			UpdatedAt: g.UpdatedAt,
+		})
+	}
+	return AuthorizedAppListResponse{Apps: out}
+}
@@ -0,0 +1,5 @@
+package response
+
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
@@ -0,0 +1,67 @@
+package response
+
+import (
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type RevisionResponse struct {
+	Revision  int       `json:"revision"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RevisionListResponse struct {
+	Revisions []RevisionResponse `json:"revisions"`
+	Total     int64              `json:"total"`
+	Limit     int                `json:"limit"`
+	Offset    int                `json:"offset"`
+}
+
+type RevisionDiffResponse struct {
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	FromTitle string `json:"from_title"`
+	ToTitle   string `json:"to_title"`
+	Diff      string `json:"diff"`
+}
+
+func NewRevisionResponse(rev *domain.NoteRevision) RevisionResponse {
+	tags := rev.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return RevisionResponse{
+		Revision:  rev.Revision,
+		Title:     rev.Title,
+		Content:   rev.Content,
+		Tags:      tags,
+		CreatedAt: rev.CreatedAt,
+	}
+}
+
+func NewRevisionListResponse(revs []*domain.NoteRevision, total int64, limit, offset int) RevisionListResponse {
+	items := make([]RevisionResponse, 0, len(revs))
+	for _, rev := range revs {
+		items = append(items, NewRevisionResponse(rev))
+	}
+	return RevisionListResponse{
+		Revisions: items,
+		Total:     total,
+		Limit:     limit,
+		Offset:    offset,
+	}
+}
+
+func NewRevisionDiffResponse(from, to *domain.NoteRevision, diff string) RevisionDiffResponse {
+	return RevisionDiffResponse{
+		From:      from.Revision,
+		To:        to.Revision,
+		FromTitle: from.Title,
+		ToTitle:   to.Title,
+		Diff:      diff,
+	}
+}
@@ -0,0 +1,41 @@
+package response
+
+import (
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type SessionListResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+// NewSessionListResponse flags the session with ID currentID as the one
+// making the request.
+func NewSessionListResponse(sessions []*domain.Session, currentID string) SessionListResponse {
+	out := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, SessionResponse{
+			ID:         s.ID,
+			Device:     s.Device,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			Current:    s.ID == currentID,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+		})
+	}
+	return SessionListResponse{Sessions: out}
+}
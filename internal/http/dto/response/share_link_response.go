@@ -0,0 +1,71 @@
+package response
+
+import (
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type ShareLinkResponse struct {
+	ID           uint64     `json:"id"`
+	NoteID       uint64     `json:"note_id"`
+	HasPassword  bool       `json:"has_password"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	ViewCount    int64      `json:"view_count"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// CreatedShareLinkResponse carries the link's URL, which is only shown once.
+type CreatedShareLinkResponse struct {
+	ShareLinkResponse
+	URL string `json:"url"`
+}
+
+type ShareLinkListResponse struct {
+	Links []ShareLinkResponse `json:"links"`
+}
+
+// PublicNoteResponse is what a share link shows: the note without anything
+// that identifies its owner or where it is filed.
+type PublicNoteResponse struct {
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewShareLinkResponse(l *domain.ShareLink) ShareLinkResponse {
+	return ShareLinkResponse{
+		ID:           l.ID,
+		NoteID:       l.NoteID,
+		HasPassword:  l.HasPassword(),
+		ExpiresAt:    l.ExpiresAt,
+		ViewCount:    l.ViewCount,
+		LastViewedAt: l.LastViewedAt,
+		CreatedAt:    l.CreatedAt,
+	}
+}
+
+func NewShareLinkListResponse(links []*domain.ShareLink) ShareLinkListResponse {
+	out := make([]ShareLinkResponse, 0, len(links))
+	for _, l := range links {
+		out = append(out, NewShareLinkResponse(l))
+	}
+	return ShareLinkListResponse{Links: out}
+}
+
+func NewPublicNoteResponse(note *domain.Note) PublicNoteResponse {
+	tags := note.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return PublicNoteResponse{
+		Title:     note.Title,
+		Content:   note.Content,
+		Tags:      tags,
+		CreatedAt: note.CreatedAt,
+		UpdatedAt: note.UpdatedAt,
+	}
+}
@@ -0,0 +1,129 @@
+package response
+
+import (
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type ShareResponse struct {
+	UserID    uint64    `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ShareListResponse struct {
+	Shares []ShareResponse `json:"shares"`
+}
+
+type SharedNoteResponse struct {
+	NoteResponse
+	Role          string `json:"role"`
+	OwnerUsername string `json:"owner_username"`
+}
+
+type SharedNoteListResponse struct {
+	Notes  []SharedNoteResponse `json:"notes"`
+	Total  int64                `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+type SharedNotebookResponse struct {
+	NotebookResponse
+	Role          string `json:"role"`
+	OwnerUsername string `json:"owner_username"`
+}
+
+type SharedNotebookListResponse struct {
+	Notebooks []SharedNotebookResponse `json:"notebooks"`
+}
+
+type CommentResponse struct {
+	ID        uint64    `json:"id"`
+	UserID    uint64    `json:"user_id"`
+	Username  string    `json:"username"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CommentListResponse struct {
+	Comments []CommentResponse `json:"comments"`
+	Total    int64             `json:"total"`
+	Limit    int               `json:"limit"`
+	Offset   int               `json:"offset"`
+}
+
+func NewShareResponse(s *domain.Share) ShareResponse {
+	return ShareResponse{
+		UserID:    s.UserID,
+		Username:  s.Username,
+		Email:     s.Email,
+		Role:      string(s.Role),
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+}
+
+func NewShareListResponse(shares []*domain.Share) ShareListResponse {
+	items := make([]ShareResponse, 0, len(shares))
+	for _, s := range shares {
+		items = append(items, NewShareResponse(s))
+	}
+	return ShareListResponse{Shares: items}
+}
+
+func NewSharedNoteListResponse(notes []*domain.SharedNote, total int64, limit, offset int) SharedNoteListResponse {
+	items := make([]SharedNoteResponse, 0, len(notes))
+	for _, note := range notes {
+		items = append(items, SharedNoteResponse{
+			NoteResponse:  NewNoteResponse(&note.Note),
+			Role:          string(note.Role),
+			OwnerUsername: note.OwnerUsername,
+		})
+	}
+	return SharedNoteListResponse{
+		Notes:  items,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+}
+
+func NewSharedNotebookListResponse(notebooks []*domain.SharedNotebook) SharedNotebookListResponse {
+	items := make([]SharedNotebookResponse, 0, len(notebooks))
+	for _, nb := range notebooks {
+		items = append(items, SharedNotebookResponse{
+			NotebookResponse: NewNotebookResponse(&nb.Notebook),
+			Role:             string(nb.Role),
+			OwnerUsername:    nb.OwnerUsername,
+		})
+	}
+	return SharedNotebookListResponse{Notebooks: items}
+}
+
+func NewCommentResponse(c *domain.NoteComment) CommentResponse {
+	return CommentResponse{
+		ID:        c.ID,
+		UserID:    c.UserID,
+		Username:  c.Username,
+		Body:      c.Body,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+func NewCommentListResponse(comments []*domain.NoteComment, total int64, limit, offset int) CommentListResponse {
+	items := make([]CommentResponse, 0, len(comments))
+	for _, c := range comments {
+		items = append(items, NewCommentResponse(c))
+	}
+	return CommentListResponse{
+		Comments: items,
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}
+}
@@ -0,0 +1,32 @@
+package response
+
+import "github.com/maqsatto/Notes-API/internal/domain"
+
+type TagResponse struct {
+	Name      string `json:"name"`
+	NoteCount int64  `json:"note_count"`
+}
+
+type TagListResponse struct {
+	Tags []TagResponse `json:"tags"`
+}
+
+type RenameTagResponse struct {
+	Tag    TagResponse `json:"tag"`
+	Merged bool        `json:"merged"`
+}
+
+func NewTagResponse(tag *domain.Tag) TagResponse {
+	return TagResponse{
+		Name:      tag.Name,
+		NoteCount: tag.NoteCount,
+	}
+}
+
+func NewTagListResponse(tags []*domain.Tag) TagListResponse {
+	items := make([]TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		items = append(items, NewTagResponse(tag))
+	}
+	return TagListResponse{Tags: items}
+}
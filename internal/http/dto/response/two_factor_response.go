@@ -0,0 +1,28 @@
+package response
+
+import "github.com/maqsatto/Notes-API/internal/domain"
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorEnrollmentResponse carries the secret for manual entry and the
+// otpauth:// URI, which is also the payload to render as a QR code.
+type TwoFactorEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRPayload  string `json:"qr_payload"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func NewTwoFactorEnrollmentResponse(e *domain.TwoFactorEnrollment) TwoFactorEnrollmentResponse {
+	return TwoFactorEnrollmentResponse{
+		Secret:     e.Secret,
+		OTPAuthURI: e.URI,
+		QRPayload:  e.URI,
+	}
+}
@@ -1 +1,77 @@
 package response
+
+import (
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type UserResponse struct {
+	ID               uint64    `json:"id"`
+	Email            string    `json:"email"`
+	Username         string    `json:"username"`
+	EmailVerified    bool      `json:"email_verified"`
+	SearchLanguage   string    `json:"search_language"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type AuthResponse struct {
+	TokenResponse
+	User UserResponse `json:"user"`
+}
+
+type TokenResponse struct {
+	AccessToken      string    `json:"access_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int64     `json:"expires_in"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+func NewUserResponse(user *domain.User) UserResponse {
+	return UserResponse{
+		ID:               user.ID,
+		Email:            user.Email,
+		Username:         user.Username,
+		EmailVerified:    user.EmailVerified(),
+		SearchLanguage:   user.SearchLanguage,
+		TwoFactorEnabled: user.TwoFactorEnabled(),
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+	}
+}
+
+// TwoFactorChallengeResponse is returned by login instead of tokens when the
+// user has two-factor authentication enabled.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+func NewAuthResponse(user *domain.User, tokens *domain.TokenPair) AuthResponse {
+	return AuthResponse{
+		TokenResponse: NewTokenResponse(tokens),
+		User:          NewUserResponse(user),
+	}
+}
+
+func NewTokenResponse(tokens *domain.TokenPair) TokenResponse {
+	return TokenResponse{
+		AccessToken:      tokens.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(time.Until(tokens.AccessExpiresAt).Seconds()),
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}
+}
+
+func NewTwoFactorChallengeResponse(result *domain.LoginResult) TwoFactorChallengeResponse {
+	return TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    result.ChallengeToken,
+		ExpiresAt:         result.ChallengeExpiresAt,
+	}
+}
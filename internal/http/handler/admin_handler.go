@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+// AdminHandler serves /api/admin. Routes are wrapped in
+// middleware.RequirePermission by the router.
+type AdminHandler struct {
+	admin *service.AdminService
+	log   *logger.Logger
+}
+
+func NewAdminHandler(admin *service.AdminService, log *logger.Logger) *AdminHandler {
+	return &AdminHandler{
+		admin: admin,
+		log:   log,
+	}
+}
+
+// GET /api/admin/users?q=&status=&role=&limit=&offset=
+//
+// status is active, suspended, deleted or all; by default deleted users are
+// left out.
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	q := r.URL.Query()
+	filter := domain.UserFilter{
+		Query:  q.Get("q"),
+		Status: domain.UserStatus(q.Get("status")),
+		Role:   q.Get("role"),
+	}
+
+	users, total, err := h.admin.ListUsers(r.Context(), filter, limit, offset)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewAdminUserListResponse(users, total, limit, offset))
+}
+
+// GET /api/admin/users/{id}
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	user, err := h.admin.GetUser(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewAdminUserResponse(user))
+}
+
+// POST /api/admin/users/{id}/suspend
+func (h *AdminHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.admin.Suspend)
+}
+
+// POST /api/admin/users/{id}/unsuspend
+func (h *AdminHandler) Unsuspend(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.admin.Unsuspend)
+}
+
+// POST /api/admin/users/{id}/password-reset
+func (h *AdminHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.admin.ForcePasswordReset)
+}
+
+// POST /api/admin/users/{id}/restore
+func (h *AdminHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	user, err := h.admin.Restore(r.Context(), actorID, userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewAdminUserResponse(user))
+}
+
+// PUT /api/admin/users/{id}/role
+func (h *AdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	var req request.SetRoleRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+	h.userAction(w, r, func(ctx context.Context, actorID, userID uint64) error {
+		return h.admin.SetRole(ctx, actorID, userID, req.Role)
+	})
+}
+
+// userAction runs an admin action on the user in the path and answers 204.
+func (h *AdminHandler) userAction(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, actorID, userID uint64) error) {
+	actorID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	if err := action(r.Context(), actorID, userID); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/admin/roles
+func (h *AdminHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.admin.ListRoles(r.Context())
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewRoleListResponse(roles))
+}
+
+// PUT /api/admin/roles/{name}
+func (h *AdminHandler) SaveRole(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	var req request.SaveRoleRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	role := &domain.Role{
+		Name:        r.PathValue("name"),
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	if err := h.admin.SaveRole(r.Context(), actorID, role); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewRoleResponse(role))
+}
+
+// DELETE /api/admin/roles/{name}
+func (h *AdminHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	if err := h.admin.DeleteRole(r.Context(), actorID, r.PathValue("name")); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
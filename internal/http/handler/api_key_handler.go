@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type APIKeyHandler struct {
+	keys *service.APIKeyService
+	log  *logger.Logger
+}
+
+func NewAPIKeyHandler(keys *service.APIKeyService, log *logger.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys: keys,
+		log:  log,
+	}
+}
+
+// POST /api/me/api-keys
+//
+// The response holds the key; it cannot be retrieved later.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.CreateAPIKeyRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	record, key, err := h.keys.Create(r.Context(), userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response.CreatedAPIKeyResponse{
+		APIKeyResponse: response.NewAPIKeyResponse(record),
+		Key:            key,
+	})
+}
+
+// GET /api/me/api-keys
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	keys, err := h.keys.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewAPIKeyListResponse(keys))
+}
+
+// DELETE /api/me/api-keys/{id}
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	keyID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	if err := h.keys.Revoke(r.Context(), userID, keyID); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
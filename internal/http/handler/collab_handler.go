@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+)
+
+const (
+	collabWriteWait  = 10 * time.Second
+	collabPongWait   = 60 * time.Second
+	collabPingPeriod = collabPongWait * 9 / 10
+	// collabMaxMessage leaves room for an edit inserting a note's worth of
+	// JSON-escaped content.
+	collabMaxMessage = 1 << 20
+)
+
+type CollabHandler struct {
+	collab   *service.CollabService
+	log      *logger.Logger
+	upgrader websocket.Upgrader
+}
+
+func NewCollabHandler(collab *service.CollabService, log *logger.Logger) *CollabHandler {
+	return &CollabHandler{
+		collab: collab,
+		log:    log,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			// Connections authenticate with bearer tokens, never cookies, so
+			// any origin may connect, as with CORS.
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+	}
+}
+
+// GET /api/notes/{id}/collab (WebSocket)
+//
+// Joins the collaborative editing session of a note. Browsers pass the access
+// token as ?access_token=. The server sends init, edit, ack, reset, presence,
+// leave and error messages; clients send edit and cursor messages.
+func (h *CollabHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	canWrite := true
+	if scopes, ok := middleware.ScopesFromContext(r.Context()); ok {
+		canWrite = slices.Contains(scopes, domain.ScopeNotesWrite)
+	}
+
+	sess, err := h.collab.Join(r.Context(), userID, noteID, canWrite)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	defer sess.Close()
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already answered the request.
+		return
+	}
+	defer conn.Close()
+
+	errs := make(chan string, 16)
+	done := make(chan struct{})
+	go func() {
+		h.writeLoop(conn, sess, errs)
+		close(done)
+	}()
+	h.readLoop(r, conn, sess, errs)
+	sess.Close()
+	<-done
+}
+
+// readLoop handles the client's messages until the connection fails or the
+// note becomes unavailable.
+func (h *CollabHandler) readLoop(r *http.Request, conn *websocket.Conn, sess *service.CollabSession, errs chan<- string) {
+	conn.SetReadLimit(collabMaxMessage)
+	_ = conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg request.CollabMessage
+		if json.Unmarshal(data, &msg) != nil {
+			err = domain.ErrInvalidInput
+		} else {
+			switch msg.Type {
+			case "edit":
+				err = sess.Edit(r.Context(), msg.Revision, msg.Operation)
+			case "cursor":
+				err = sess.MoveCursor(r.Context(), msg.Cursor, msg.SelectionEnd)
+			default:
+				err = domain.ErrInvalidInput
+			}
+		}
+		if err == nil {
+			continue
+		}
+
+		msgErr := err.Error()
+		if statusFor(err) == http.StatusInternalServerError {
+			h.log.Error("collab message failed", err)
+			msgErr = domain.ErrInternal.Error()
+		}
+		select {
+		case errs <- msgErr:
+		default:
+		}
+		if errors.Is(err, domain.ErrNoteNotFound) || errors.Is(err, domain.ErrNoteAccessDenied) {
+			return
+		}
+	}
+}
+
+// writeLoop sends the session's events and errors to the client and keeps the
+// connection alive. It is the only writer on conn.
+func (h *CollabHandler) writeLoop(conn *websocket.Conn, sess *service.CollabSession, errs <-chan string) {
+	ping := time.NewTicker(collabPingPeriod)
+	defer ping.Stop()
+	// Closing the connection also stops readLoop.
+	defer conn.Close()
+
+	write := func(v any) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+		return conn.WriteJSON(v)
+	}
+	for {
+		select {
+		case ev, ok := <-sess.Events():
+			if !ok {
+				// Errors that ended the session go out before the close.
+				for len(errs) > 0 {
+					if err := write(response.CollabErrorMessage{Type: "error", Error: <-errs}); err != nil {
+						return
+					}
+				}
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session ended"),
+					time.Now().Add(collabWriteWait))
+				return
+			}
+			if err := write(response.NewCollabMessage(ev)); err != nil {
+				return
+			}
+		case msg := <-errs:
+			if err := write(response.CollabErrorMessage{Type: "error", Error: msg}); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(collabWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type CommentHandler struct {
+	comments *service.CommentService
+	log      *logger.Logger
+}
+
+func NewCommentHandler(comments *service.CommentService, log *logger.Logger) *CommentHandler {
+	return &CommentHandler{
+		comments: comments,
+		log:      log,
+	}
+}
+
+// GET /api/notes/{id}/comments?limit=&offset=
+func (h *CommentHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	comments, total, err := h.comments.List(r.Context(), userID, noteID, limit, offset)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewCommentListResponse(comments, total, limit, offset))
+}
+
+// POST /api/notes/{id}/comments
+func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	var req request.CreateCommentRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	c, err := h.comments.Add(r.Context(), userID, noteID, req.Body)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response.NewCommentResponse(c))
+}
+
+// DELETE /api/notes/{id}/comments/{comment_id}
+func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	commentID, err := pathID(r, "comment_id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	if err := h.comments.Delete(r.Context(), userID, noteID, commentID); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
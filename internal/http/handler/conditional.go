@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+// noteETag is the entity tag of a note. It is the note's version, which
+// every change to the note bumps.
+func noteETag(note *domain.Note) string {
+	return `"` + strconv.FormatInt(note.Version, 10) + `"`
+}
+
+// writeNote writes a note response carrying the note's ETag.
+func writeNote(w http.ResponseWriter, status int, note *domain.Note, v any) {
+	w.Header().Set("ETag", noteETag(note))
+	utils.WriteJSON(w, status, v)
+}
+
+// ifMatchVersion reads the note version a write is conditioned on from
+// If-Match. Zero means the write is unconditional: the header is missing or
+// "*". With required set a missing header is an error. Only a single strong
+// entity tag can match; anything else fails the precondition.
+func ifMatchVersion(r *http.Request, required bool) (int64, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch v {
+	case "":
+		if required {
+			return 0, domain.ErrNoteVersionRequired
+		}
+		return 0, nil
+	case "*":
+		return 0, nil
+	}
+
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return 0, domain.ErrNoteVersionMismatch
+	}
+	version, err := strconv.ParseInt(v[1:len(v)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, domain.ErrNoteVersionMismatch
+	}
+	return version, nil
+}
+
+// notModified sets etag on the response and, when the request's
+// If-None-Match names it, answers 304 Not Modified and reports true. Entity
+// tags are compared weakly, as RFC 9110 requires for If-None-Match.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	inm := r.Header.Get("If-None-Match")
+	if inm == "" {
+		return false
+	}
+	for _, tag := range strings.Split(inm, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// writeListJSON writes v as utils.WriteJSON does, with a weak ETag derived
+// from the body, so that clients polling a list that did not change get 304
+// Not Modified.
+func writeListJSON(w http.ResponseWriter, r *http.Request, log *logger.Logger, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, log, err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	if notModified(w, r, `W/"`+hex.EncodeToString(sum[:16])+`"`) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		ifMatch  string
+		required bool
+		want     int64
+		wantErr  error
+	}{
+		{"", false, 0, nil},
+		{"", true, 0, domain.ErrNoteVersionRequired},
+		{"*", true, 0, nil},
+		{`"3"`, false, 3, nil},
+		{` "3" `, false, 3, nil},
+		{`3"`, false, 0, domain.ErrNoteVersionMismatch},
+		{`"3`, false, 0, domain.ErrNoteVersionMismatch},
+		{`3`, false, 0, domain.ErrNoteVersionMismatch},
+		{`"`, false, 0, domain.ErrNoteVersionMismatch},
+		{`""`, false, 0, domain.ErrNoteVersionMismatch},
+		{`W/"3"`, false, 0, domain.ErrNoteVersionMismatch},
+		{`"3", "4"`, false, 0, domain.ErrNoteVersionMismatch},
+		{`"0"`, false, 0, domain.ErrNoteVersionMismatch},
+		{`"-1"`, false, 0, domain.ErrNoteVersionMismatch},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("PUT", "/api/notes/1", nil)
+		if tt.ifMatch != "" {
+			r.Header.Set("If-Match", tt.ifMatch)
+		}
+		got, err := ifMatchVersion(r, tt.required)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("If-Match %q (required %v) = %d, %v; want %d, %v", tt.ifMatch, tt.required, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type EmailHandler struct {
+	emails *service.EmailService
+	log    *logger.Logger
+}
+
+func NewEmailHandler(emails *service.EmailService, log *logger.Logger) *EmailHandler {
+	return &EmailHandler{
+		emails: emails,
+		log:    log,
+	}
+}
+
+// POST /api/auth/verify-email
+func (h *EmailHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var req request.EmailTokenRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.emails.Verify(r.Context(), req.Token); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/auth/confirm-email
+func (h *EmailHandler) ConfirmChange(w http.ResponseWriter, r *http.Request) {
+	var req request.EmailTokenRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.emails.ConfirmChange(r.Context(), req.Token); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/me/email/verification
+func (h *EmailHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	if err := h.emails.ResendVerification(r.Context(), userID); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
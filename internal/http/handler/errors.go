@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+// errorStatus maps domain errors to HTTP status codes. Anything not listed
+// here is treated as an internal error and its message is not exposed.
+var errorStatus = []struct {
+	err    error
+	status int
+}{
+	{domain.ErrInvalidID, http.StatusBadRequest},
+	{domain.ErrInvalidInput, http.StatusBadRequest},
+	{domain.ErrInvalidLimit, http.StatusBadRequest},
+	{domain.ErrInvalidOffset, http.StatusBadRequest},
+	{domain.ErrInvalidSearchQuery, http.StatusBadRequest},
+	{domain.ErrInvalidSearchLanguage, http.StatusBadRequest},
+
+	{domain.ErrInvalidNote, http.StatusBadRequest},
+	{domain.ErrNoteTitleEmpty, http.StatusBadRequest},
+	{domain.ErrNoteContentEmpty, http.StatusBadRequest},
+	{domain.ErrNoteTooLarge, http.StatusRequestEntityTooLarge},
+	{domain.ErrRevisionDiffTooLarge, http.StatusUnprocessableEntity},
+	{domain.ErrInvalidTags, http.StatusBadRequest},
+	{domain.ErrTooManyTags, http.StatusBadRequest},
+	{domain.ErrInvalidNotebookName, http.StatusBadRequest},
+	{domain.ErrInvalidShareRole, http.StatusBadRequest},
+	{domain.ErrShareWithOwner, http.StatusBadRequest},
+	{domain.ErrInvalidComment, http.StatusBadRequest},
+	{domain.ErrInvalidEditOperation, http.StatusBadRequest},
+
+	{domain.ErrInvalidUser, http.StatusBadRequest},
+	{domain.ErrInvalidEmail, http.StatusBadRequest},
+	{domain.ErrInvalidUsername, http.StatusBadRequest},
+	{domain.ErrInvalidPassword, http.StatusBadRequest},
+	{domain.ErrPasswordTooShort, http.StatusBadRequest},
+	{domain.ErrPasswordTooLong, http.StatusBadRequest},
+	{domain.ErrPasswordMissingUppercase, http.StatusBadRequest},
+	{domain.ErrPasswordMissingLowercase, http.StatusBadRequest},
+	{domain.ErrPasswordMissingDigit, http.StatusBadRequest},
+	{domain.ErrPasswordMissingSpecial, http.StatusBadRequest},
+	{domain.ErrPasswordTooCommon, http.StatusBadRequest},
+	{domain.ErrPasswordBreached, http.StatusBadRequest},
+	{domain.ErrPasswordContainsPersonalInfo, http.StatusBadRequest},
+	{domain.ErrPasswordMismatch, http.StatusBadRequest},
+	{domain.ErrInvalidTOTPCode, http.StatusUnauthorized},
+	{domain.ErrTwoFactorAlreadyEnabled, http.StatusConflict},
+	{domain.ErrTwoFactorNotEnabled, http.StatusConflict},
+	{domain.ErrTwoFactorNotEnrolled, http.StatusConflict},
+	{domain.ErrInvalidRole, http.StatusBadRequest},
+	{domain.ErrInvalidScope, http.StatusBadRequest},
+	{domain.ErrInvalidPermission, http.StatusBadRequest},
+	{domain.ErrOAuthInvalidRedirectURI, http.StatusBadRequest},
+	{domain.ErrOAuthPKCERequired, http.StatusBadRequest},
+	{domain.ErrOAuthInvalidGrant, http.StatusBadRequest},
+	{domain.ErrOAuthUnsupportedGrantType, http.StatusBadRequest},
+
+	{domain.ErrUnauthorized, http.StatusUnauthorized},
+	{domain.ErrInvalidCredentials, http.StatusUnauthorized},
+	{domain.ErrInvalidToken, http.StatusUnauthorized},
+	{domain.ErrExpiredToken, http.StatusUnauthorized},
+	{domain.ErrMissingToken, http.StatusUnauthorized},
+	{domain.ErrRevokedToken, http.StatusUnauthorized},
+	{domain.ErrTokenReused, http.StatusUnauthorized},
+	{domain.ErrInvalidAPIKey, http.StatusUnauthorized},
+	{domain.ErrExternalLoginFailed, http.StatusUnauthorized},
+	{domain.ErrOAuthInvalidClient, http.StatusUnauthorized},
+	{domain.ErrShareLinkPasswordRequired, http.StatusUnauthorized},
+	{domain.ErrShareLinkWrongPassword, http.StatusUnauthorized},
+	{domain.ErrForbidden, http.StatusForbidden},
+	{domain.ErrNoteAccessDenied, http.StatusForbidden},
+	{domain.ErrNotebookAccessDenied, http.StatusForbidden},
+	{domain.ErrOperationNotAllowed, http.StatusForbidden},
+	{domain.ErrPermissionNotHeld, http.StatusForbidden},
+	{domain.ErrEmailNotVerified, http.StatusForbidden},
+	{domain.ErrAccountSuspended, http.StatusForbidden},
+	{domain.ErrPasswordResetRequired, http.StatusForbidden},
+	{domain.ErrExternalEmailNotVerified, http.StatusForbidden},
+
+	{domain.ErrUserNotFound, http.StatusNotFound},
+	{domain.ErrNoteNotFound, http.StatusNotFound},
+	{domain.ErrTagNotFound, http.StatusNotFound},
+	{domain.ErrRevisionNotFound, http.StatusNotFound},
+	{domain.ErrNotebookNotFound, http.StatusNotFound},
+	{domain.ErrShareNotFound, http.StatusNotFound},
+	{domain.ErrCommentNotFound, http.StatusNotFound},
+	{domain.ErrShareLinkNotFound, http.StatusNotFound},
+	{domain.ErrSessionNotFound, http.StatusNotFound},
+	{domain.ErrRoleNotFound, http.StatusNotFound},
+	{domain.ErrAPIKeyNotFound, http.StatusNotFound},
+	{domain.ErrIdentityProviderNotFound, http.StatusNotFound},
+	{domain.ErrIdentityNotFound, http.StatusNotFound},
+	{domain.ErrOAuthClientNotFound, http.StatusNotFound},
+	{domain.ErrOAuthGrantNotFound, http.StatusNotFound},
+	{domain.ErrNoteDeleted, http.StatusGone},
+
+	// ErrNoteVersionMismatch is a conflict too, reported as a failed
+	// If-Match precondition.
+	{domain.ErrNoteVersionMismatch, http.StatusPreconditionFailed},
+	{domain.ErrNoteVersionRequired, http.StatusPreconditionRequired},
+
+	{domain.ErrConflict, http.StatusConflict},
+	{domain.ErrEditOutOfSync, http.StatusConflict},
+	{domain.ErrStateViolation, http.StatusConflict},
+	{domain.ErrNotebookCycle, http.StatusConflict},
+	{domain.ErrUserAlreadyExists, http.StatusConflict},
+	{domain.ErrEmailAlreadyExists, http.StatusConflict},
+	{domain.ErrUsernameAlreadyExists, http.StatusConflict},
+	{domain.ErrEmailAlreadyVerified, http.StatusConflict},
+	{domain.ErrRoleInUse, http.StatusConflict},
+	{domain.ErrBuiltInRole, http.StatusConflict},
+	{domain.ErrIdentityLinkUnverified, http.StatusConflict},
+	{domain.ErrNothingToUpdate, http.StatusBadRequest},
+
+	{domain.ErrTooManyAttempts, http.StatusTooManyRequests},
+	{domain.ErrAccountLocked, http.StatusTooManyRequests},
+	{domain.ErrNotImplemented, http.StatusNotImplemented},
+}
+
+func statusFor(err error) int {
+	for _, e := range errorStatus {
+		if errors.Is(err, e.err) {
+			return e.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, log *logger.Logger, err error) {
+	status := statusFor(err)
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Error("request failed", err)
+		msg = domain.ErrInternal.Error()
+	}
+	var retry *domain.RetryAfterError
+	if errors.As(err, &retry) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.After.Seconds()))))
+	}
+	utils.WriteJSON(w, status, response.ErrorResponse{Error: msg})
+}
+
+func writeBadRequest(w http.ResponseWriter, msg string) {
+	utils.WriteJSON(w, http.StatusBadRequest, response.ErrorResponse{Error: msg})
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type JWKSHandler struct {
+	jwt *auth.JWTManager
+}
+
+func NewJWKSHandler(jwt *auth.JWTManager) *JWKSHandler {
+	return &JWKSHandler{
+		jwt: jwt,
+	}
+}
+
+// GET /.well-known/jwks.json
+//
+// Verifiers may cache the set for a few minutes, so publish a new key while
+// JWT_ACTIVE_KID still pins the old one and switch afterwards.
+func (h *JWKSHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.WriteJSON(w, http.StatusOK, h.jwt.JWKS())
+}
@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type NoteHandler struct {
+	notes *service.NoteService
+	log   *logger.Logger
+
+	// requireIfMatch makes If-Match mandatory on updates and deletes.
+	requireIfMatch bool
+}
+
+func NewNoteHandler(notes *service.NoteService, log *logger.Logger, requireIfMatch bool) *NoteHandler {
+	return &NoteHandler{
+		notes:          notes,
+		log:            log,
+		requireIfMatch: requireIfMatch,
+	}
+}
+
+// POST /api/notes
+func (h *NoteHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.CreateNoteRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	note, err := h.notes.Create(r.Context(), userID, req.Title, req.Content, req.Tags, req.NotebookID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	writeNote(w, http.StatusCreated, note, response.NewNoteResponse(note))
+}
+
+// GET /api/notes/{id}
+//
+// The response carries the note's ETag; with a matching If-None-Match the
+// answer is 304 Not Modified.
+func (h *NoteHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	note, err := h.notes.GetByID(r.Context(), userID, noteID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	if notModified(w, r, noteETag(note)) {
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewNoteResponse(note))
+}
+
+// PUT /api/notes/{id}
+//
+// With If-Match set to the note's ETag the update is only made if nobody
+// changed the note since; otherwise the answer is 412 Precondition Failed.
+func (h *NoteHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	version, err := ifMatchVersion(r, h.requireIfMatch)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	var req request.UpdateNoteRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	note, err := h.notes.Update(r.Context(), userID, noteID, version, req.Title, req.Content, req.Tags)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	writeNote(w, http.StatusOK, note, response.NewNoteResponse(note))
+}
+
+// POST /api/notes/{id}/move
+func (h *NoteHandler) Move(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	var req request.MoveNoteRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	note, err := h.notes.Move(r.Context(), userID, noteID, req.NotebookID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	writeNote(w, http.StatusOK, note, response.NewNoteResponse(note))
+}
+
+// DELETE /api/notes/{id}?permanent=true
+//
+// If-Match works as for PUT.
+func (h *NoteHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	version, err := ifMatchVersion(r, h.requireIfMatch)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	if r.URL.Query().Get("permanent") == "true" {
+		err = h.notes.PermanentDelete(r.Context(), userID, noteID, version)
+	} else {
+		err = h.notes.Delete(r.Context(), userID, noteID, version)
+	}
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/notes?limit=&offset=&tags=a,b&notebook_id=&recursive=true
+//
+// With notebook_id only the notes filed directly in that notebook are
+// listed, or with recursive=true those in its sub-notebooks as well.
+func (h *NoteHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	scope, err := noteScope(r)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	var (
+		notes []*domain.Note
+		total int64
+	)
+	if tags := splitList(r.URL.Query().Get("tags")); len(tags) > 0 {
+		notes, total, err = h.notes.ListByTags(r.Context(), userID, tags, scope, limit, offset)
+	} else {
+		notes, total, err = h.notes.ListByUser(r.Context(), userID, scope, limit, offset)
+	}
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	writeListJSON(w, r, h.log, response.NewNoteListResponse(notes, total, limit, offset))
+}
+
+// GET /api/notes/search?q=&limit=&offset=&notebook_id=&recursive=true
+//
+// Without ?in= the query is a ranked full-text search supporting "phrases",
+// -negation, or and prefix* words. With ?in=title or ?in=content it is a
+// plain substring match on that field. notebook_id narrows the search as it
+// does the listing.
+func (h *NoteHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	scope, err := noteScope(r)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Get("in") == "" {
+		results, total, err := h.notes.Search(r.Context(), userID, q.Get("q"), scope, limit, offset)
+		if err != nil {
+			writeError(w, h.log, err)
+			return
+		}
+		writeListJSON(w, r, h.log, response.NewNoteSearchResponse(results, total, limit, offset))
+		return
+	}
+
+	var (
+		notes []*domain.Note
+		total int64
+	)
+	switch q.Get("in") {
+	case "title":
+		notes, total, err = h.notes.SearchByTitle(r.Context(), userID, q.Get("q"), scope, limit, offset)
+	case "content":
+		notes, total, err = h.notes.SearchByContent(r.Context(), userID, q.Get("q"), scope, limit, offset)
+	default:
+		err = domain.ErrInvalidSearchQuery
+	}
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	writeListJSON(w, r, h.log, response.NewNoteListResponse(notes, total, limit, offset))
+}
+
+// GET /api/trash?limit=&offset=
+func (h *NoteHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	notes, total, err := h.notes.ListTrash(r.Context(), userID, limit, offset)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	writeListJSON(w, r, h.log, response.NewNoteListResponse(notes, total, limit, offset))
+}
+
+// POST /api/trash/{id}/restore
+func (h *NoteHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	note, err := h.notes.Restore(r.Context(), userID, noteID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	writeNote(w, http.StatusOK, note, response.NewNoteResponse(note))
+}
+
+// DELETE /api/trash/{id}
+//
+// If-Match takes the version listed in the trash, quoted as an ETag.
+func (h *NoteHandler) DeleteFromTrash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	version, err := ifMatchVersion(r, h.requireIfMatch)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	if err := h.notes.PermanentDelete(r.Context(), userID, noteID, version); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/trash
+func (h *NoteHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	n, err := h.notes.EmptyTrash(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.EmptyTrashResponse{Deleted: n})
+}
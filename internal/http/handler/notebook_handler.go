@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type NotebookHandler struct {
+	notebooks *service.NotebookService
+	log       *logger.Logger
+}
+
+func NewNotebookHandler(notebooks *service.NotebookService, log *logger.Logger) *NotebookHandler {
+	return &NotebookHandler{
+		notebooks: notebooks,
+		log:       log,
+	}
+}
+
+// POST /api/notebooks
+func (h *NotebookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.CreateNotebookRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	nb, err := h.notebooks.Create(r.Context(), userID, req.Name, req.ParentID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response.NewNotebookResponse(nb))
+}
+
+// GET /api/notebooks
+//
+// Returns the user's notebooks as trees of nested children.
+func (h *NotebookHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	notebooks, err := h.notebooks.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewNotebookListResponse(notebooks))
+}
+
+// GET /api/notebooks/{id}
+func (h *NotebookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	notebookID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	nb, err := h.notebooks.GetByID(r.Context(), userID, notebookID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewNotebookResponse(nb))
+}
+
+// PUT /api/notebooks/{id}
+func (h *NotebookHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	notebookID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	var req request.RenameNotebookRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	nb, err := h.notebooks.Rename(r.Context(), userID, notebookID, req.Name)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewNotebookResponse(nb))
+}
+
+// POST /api/notebooks/{id}/move
+//
+// Moves the notebook with its notes and sub-notebooks; also reorders it
+// among its siblings when parent_id stays the same.
+func (h *NotebookHandler) Move(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	notebookID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	var req request.MoveNotebookRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	nb, err := h.notebooks.Move(r.Context(), userID, notebookID, req.ParentID, req.Position)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewNotebookResponse(nb))
+}
+
+// DELETE /api/notebooks/{id}?notes=move|trash
+//
+// With notes=move (the default) the notebook's notes and sub-notebooks move
+// up into its parent. With notes=trash its sub-notebooks are deleted as well
+// and every note in them goes to the trash.
+func (h *NotebookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	notebookID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	mode := domain.NotebookDeleteMode(r.URL.Query().Get("notes"))
+	if mode == "" {
+		mode = domain.NotebookDeleteMoveNotes
+	}
+	if err := h.notebooks.Delete(r.Context(), userID, notebookID, mode); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
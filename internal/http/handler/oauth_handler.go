@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+// oauthErrors maps domain errors to the error codes of RFC 6749 section 5.2
+// for the endpoints third-party apps call directly.
+var oauthErrors = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{domain.ErrOAuthInvalidClient, "invalid_client", http.StatusUnauthorized},
+	{domain.ErrOAuthInvalidGrant, "invalid_grant", http.StatusBadRequest},
+	{domain.ErrOAuthUnsupportedGrantType, "unsupported_grant_type", http.StatusBadRequest},
+	{domain.ErrInvalidScope, "invalid_scope", http.StatusBadRequest},
+	{domain.ErrInvalidInput, "invalid_request", http.StatusBadRequest},
+}
+
+type OAuthHandler struct {
+	oauth *service.OAuthService
+	log   *logger.Logger
+}
+
+func NewOAuthHandler(oauth *service.OAuthService, log *logger.Logger) *OAuthHandler {
+	return &OAuthHandler{
+		oauth: oauth,
+		log:   log,
+	}
+}
+
+// POST /api/oauth/clients
+//
+// The response holds the client secret of confidential clients; it cannot
+// be retrieved later.
+func (h *OAuthHandler) RegisterClient(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.RegisterOAuthClientRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	client, secret, err := h.oauth.RegisterClient(r.Context(), userID, req.Name, req.RedirectURIs, req.Scopes, req.Confidential)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response.CreatedOAuthClientResponse{
+		OAuthClientResponse: response.NewOAuthClientResponse(client),
+		ClientSecret:        secret,
+	})
+}
+
+// GET /api/oauth/clients
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	clients, err := h.oauth.ListClients(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewOAuthClientListResponse(clients))
+}
+
+// DELETE /api/oauth/clients/{id}
+func (h *OAuthHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	if err := h.oauth.DeleteClient(r.Context(), userID, r.PathValue("id")); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/oauth/authorize?response_type=code&client_id=&redirect_uri=&scope=&state=&code_challenge=&code_challenge_method=S256
+//
+// Backs the consent screen: the frontend page the app sends the user to
+// passes the query string on and shows what the app asks for.
+func (h *OAuthHandler) Consent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	client, scopes, approved, err := h.oauth.Authorize(r.Context(), userID, domain.AuthorizationRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scopes:              strings.Fields(q.Get("scope")),
+		State:               q.Get("state"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	})
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.ConsentResponse{
+		ClientID:           client.ID,
+		ClientName:         client.Name,
+		Scopes:             scopes,
+		PreviouslyApproved: approved,
+	})
+}
+
+// POST /api/oauth/authorize
+//
+// Records the user's answer; the consent screen then sends the browser to
+// redirect_to, which carries the code or the refusal back to the app.
+func (h *OAuthHandler) Decide(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.AuthorizeDecisionRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	redirectTo, err := h.oauth.Decide(r.Context(), userID, domain.AuthorizationRequest{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              strings.Fields(req.Scope),
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}, req.Approve)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.AuthorizeDecisionResponse{RedirectTo: redirectTo})
+}
+
+// POST /oauth/token
+//
+// Form encoded, with grant_type authorization_code (code, redirect_uri,
+// code_verifier) or refresh_token (refresh_token). Clients authenticate
+// with HTTP Basic or client_id and client_secret form fields.
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, domain.ErrInvalidInput)
+		return
+	}
+	clientID, clientSecret := clientCredentials(r)
+
+	var (
+		tokens *domain.TokenPair
+		err    error
+	)
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		tokens, err = h.oauth.Exchange(r.Context(), clientID, clientSecret,
+			r.PostForm.Get("code"), r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"), clientInfo(r, ""))
+	case "refresh_token":
+		tokens, err = h.oauth.Refresh(r.Context(), clientID, clientSecret, r.PostForm.Get("refresh_token"))
+	case "":
+		err = domain.ErrInvalidInput
+	default:
+		err = domain.ErrOAuthUnsupportedGrantType
+	}
+	if err != nil {
+		h.writeOAuthError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, http.StatusOK, response.NewOAuthTokenResponse(tokens))
+}
+
+// POST /oauth/introspect
+//
+// Form encoded token and optional token_type_hint (RFC 7662).
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, domain.ErrInvalidInput)
+		return
+	}
+	clientID, clientSecret := clientCredentials(r)
+
+	result, err := h.oauth.Introspect(r.Context(), clientID, clientSecret, r.PostForm.Get("token"), r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		h.writeOAuthError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, http.StatusOK, response.NewIntrospectionResponse(result))
+}
+
+// POST /oauth/revoke
+//
+// Form encoded token and optional token_type_hint (RFC 7009). Answers 200
+// for unknown tokens too.
+func (h *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, domain.ErrInvalidInput)
+		return
+	}
+	clientID, clientSecret := clientCredentials(r)
+
+	if err := h.oauth.Revoke(r.Context(), clientID, clientSecret, r.PostForm.Get("token"), r.PostForm.Get("token_type_hint")); err != nil {
+		h.writeOAuthError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GET /api/me/authorized-apps
+func (h *OAuthHandler) ListAuthorizedApps(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	grants, err := h.oauth.ListAuthorizedApps(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewAuthorizedAppListResponse(grants))
+}
+
+// DELETE /api/me/authorized-apps/{client_id}
+func (h *OAuthHandler) RevokeAuthorizedApp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	if err := h.oauth.RevokeAuthorizedApp(r.Context(), userID, r.PathValue("client_id")); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *OAuthHandler) writeOAuthError(w http.ResponseWriter, err error) {
+	for _, e := range oauthErrors {
+		if errors.Is(err, e.err) {
+			if e.status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			utils.WriteJSON(w, e.status, response.OAuthErrorResponse{Error: e.code, ErrorDescription: err.Error()})
+			return
+		}
+	}
+	h.log.Error("oauth request failed", err)
+	utils.WriteJSON(w, http.StatusInternalServerError, response.OAuthErrorResponse{Error: "server_error"})
+}
+
+// clientCredentials reads the client's id and secret from HTTP Basic auth,
+// whose parts are form encoded (RFC 6749 section 2.3.1), or from the form.
+func clientCredentials(r *http.Request) (string, string) {
+	if id, secret, ok := r.BasicAuth(); ok {
+		if uid, err := url.QueryUnescape(id); err == nil {
+			id = uid
+		}
+		if usecret, err := url.QueryUnescape(secret); err == nil {
+			secret = usecret
+		}
+		return id, secret
+	}
+	return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+}
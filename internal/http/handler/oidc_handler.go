@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type OIDCHandler struct {
+	oidc *service.OIDCService
+	log  *logger.Logger
+}
+
+func NewOIDCHandler(oidc *service.OIDCService, log *logger.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		oidc: oidc,
+		log:  log,
+	}
+}
+
+// GET /api/auth/oidc/providers
+func (h *OIDCHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, response.OIDCProvidersResponse{Providers: h.oidc.Providers()})
+}
+
+// GET /api/auth/oidc/{provider}/login
+//
+// Redirects the browser to the provider's login page.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, err := h.oidc.Start(r.Context(), r.PathValue("provider"))
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// GET /api/auth/oidc/callback?code=&state=
+//
+// The provider redirects here after the user logged in. The response is the
+// same as for POST /api/auth/login.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("error") != "" {
+		// the user declined or the provider refused the request
+		writeError(w, h.log, domain.ErrExternalLoginFailed)
+		return
+	}
+
+	result, err := h.oidc.Callback(r.Context(), q.Get("state"), q.Get("code"), clientInfo(r, ""))
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if result.Tokens == nil {
+		utils.WriteJSON(w, http.StatusOK, response.NewTwoFactorChallengeResponse(result))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewAuthResponse(result.User, result.Tokens))
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+const (
+	defaultLimit = 20
+)
+
+func pathID(r *http.Request, name string) (uint64, error) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, domain.ErrInvalidID
+	}
+	return id, nil
+}
+
+// pagination reads ?limit= and ?offset= from the query string. Range checks
+// are left to the service layer.
+func pagination(r *http.Request) (int, int, error) {
+	q := r.URL.Query()
+	limit, offset := defaultLimit, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, domain.ErrInvalidLimit
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, domain.ErrInvalidOffset
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// noteScope reads ?notebook_id= and ?recursive= from the query string.
+func noteScope(r *http.Request) (domain.NoteScope, error) {
+	q := r.URL.Query()
+	var scope domain.NoteScope
+	if v := q.Get("notebook_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || id == 0 {
+			return scope, domain.ErrInvalidID
+		}
+		scope.NotebookID = id
+	}
+	scope.Recursive = q.Get("recursive") == "true"
+	return scope, nil
+}
+
+// splitList splits a comma separated query value, dropping empty items.
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	parts := strings.Split(v, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// clientIP returns the address of the connecting peer. The server is not
+// expected to sit behind a proxy, so forwarding headers are not trusted.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientInfo describes the client opening a session; device is the optional
+// name it gave itself.
+func clientInfo(r *http.Request, device string) domain.ClientInfo {
+	return domain.ClientInfo{
+		Device:    strings.TrimSpace(device),
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	}
+}
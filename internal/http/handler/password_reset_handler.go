@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type PasswordResetHandler struct {
+	resets *service.PasswordResetService
+	log    *logger.Logger
+}
+
+func NewPasswordResetHandler(resets *service.PasswordResetService, log *logger.Logger) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		resets: resets,
+		log:    log,
+	}
+}
+
+// POST /api/auth/forgot-password
+//
+// Always answers 202 so the response does not tell whether the email is
+// registered.
+func (h *PasswordResetHandler) Forgot(w http.ResponseWriter, r *http.Request) {
+	var req request.ForgotPasswordRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.resets.RequestReset(r.Context(), req.Email); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"message": "if an account with that email exists, a reset link has been sent",
+	})
+}
+
+// POST /api/auth/reset-password
+func (h *PasswordResetHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	var req request.ResetPasswordRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.resets.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
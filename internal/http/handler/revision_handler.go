@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type RevisionHandler struct {
+	revisions *service.RevisionService
+	log       *logger.Logger
+}
+
+func NewRevisionHandler(revisions *service.RevisionService, log *logger.Logger) *RevisionHandler {
+	return &RevisionHandler{
+		revisions: revisions,
+		log:       log,
+	}
+}
+
+// GET /api/notes/{id}/revisions?limit=&offset=
+func (h *RevisionHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	revs, total, err := h.revisions.List(r.Context(), userID, noteID, limit, offset)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewRevisionListResponse(revs, total, limit, offset))
+}
+
+// GET /api/notes/{id}/revisions/{rev}
+func (h *RevisionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	rev, err := revisionNumber(r.PathValue("rev"))
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	revision, err := h.revisions.Get(r.Context(), userID, noteID, rev)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewRevisionResponse(revision))
+}
+
+// GET /api/notes/{id}/revisions/diff?from=&to=
+func (h *RevisionHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	q := r.URL.Query()
+	from, err := revisionNumber(q.Get("from"))
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	to, err := revisionNumber(q.Get("to"))
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	fromRev, toRev, diff, err := h.revisions.Diff(r.Context(), userID, noteID, from, to)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewRevisionDiffResponse(fromRev, toRev, diff))
+}
+
+// POST /api/notes/{id}/revisions/{rev}/restore
+func (h *RevisionHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	rev, err := revisionNumber(r.PathValue("rev"))
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	note, err := h.revisions.Restore(r.Context(), userID, noteID, rev)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewNoteResponse(note))
+}
+
+func revisionNumber(v string) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, domain.ErrInvalidID
+	}
+	return n, nil
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type SessionHandler struct {
+	tokens *service.TokenService
+	log    *logger.Logger
+}
+
+func NewSessionHandler(tokens *service.TokenService, log *logger.Logger) *SessionHandler {
+	return &SessionHandler{
+		tokens: tokens,
+		log:    log,
+	}
+}
+
+// GET /api/me/sessions
+func (h *SessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	current, _ := middleware.SessionIDFromContext(r.Context())
+
+	sessions, err := h.tokens.ListSessions(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewSessionListResponse(sessions, current))
+}
+
+// DELETE /api/me/sessions/{id}
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	if err := h.tokens.RevokeSession(r.Context(), userID, r.PathValue("id")); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/me/sessions
+//
+// Logs the user out everywhere, including the calling session.
+func (h *SessionHandler) RevokeAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	if err := h.tokens.RevokeAllSessions(r.Context(), userID); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type ShareHandler struct {
+	shares *service.ShareService
+	log    *logger.Logger
+}
+
+func NewShareHandler(shares *service.ShareService, log *logger.Logger) *ShareHandler {
+	return &ShareHandler{
+		shares: shares,
+		log:    log,
+	}
+}
+
+// POST /api/notes/{id}/shares
+//
+// Answers 201 for a new share and 200 when the user's role was changed.
+func (h *ShareHandler) ShareNote(w http.ResponseWriter, r *http.Request) {
+	h.share(w, r, h.shares.ShareNote)
+}
+
+// POST /api/notebooks/{id}/shares
+func (h *ShareHandler) ShareNotebook(w http.ResponseWriter, r *http.Request) {
+	h.share(w, r, h.shares.ShareNotebook)
+}
+
+// GET /api/notes/{id}/shares
+func (h *ShareHandler) ListNoteShares(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, h.shares.ListNoteShares)
+}
+
+// GET /api/notebooks/{id}/shares
+func (h *ShareHandler) ListNotebookShares(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, h.shares.ListNotebookShares)
+}
+
+// DELETE /api/notes/{id}/shares/{user_id}
+func (h *ShareHandler) RevokeNoteShare(w http.ResponseWriter, r *http.Request) {
+	h.revoke(w, r, h.shares.RevokeNoteShare)
+}
+
+// DELETE /api/notebooks/{id}/shares/{user_id}
+func (h *ShareHandler) RevokeNotebookShare(w http.ResponseWriter, r *http.Request) {
+	h.revoke(w, r, h.shares.RevokeNotebookShare)
+}
+
+// GET /api/shared-with-me?limit=&offset=
+//
+// Lists the notes shared with the user, including those in shared notebooks.
+func (h *ShareHandler) ListSharedNotes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	notes, total, err := h.shares.ListSharedNotes(r.Context(), userID, limit, offset)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	writeListJSON(w, r, h.log, response.NewSharedNoteListResponse(notes, total, limit, offset))
+}
+
+// GET /api/shared-with-me/notebooks
+func (h *ShareHandler) ListSharedNotebooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	notebooks, err := h.shares.ListSharedNotebooks(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewSharedNotebookListResponse(notebooks))
+}
+
+func (h *ShareHandler) share(
+	w http.ResponseWriter,
+	r *http.Request,
+	share func(ctx context.Context, ownerID, id uint64, who string, role domain.ShareRole) (*domain.Share, bool, error),
+) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	var req request.ShareRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	s, created, err := share(r.Context(), userID, id, req.User, domain.ShareRole(req.Role))
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	utils.WriteJSON(w, status, response.NewShareResponse(s))
+}
+
+func (h *ShareHandler) list(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(ctx context.Context, ownerID, id uint64) ([]*domain.Share, error),
+) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	shares, err := list(r.Context(), userID, id)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewShareListResponse(shares))
+}
+
+func (h *ShareHandler) revoke(
+	w http.ResponseWriter,
+	r *http.Request,
+	revoke func(ctx context.Context, userID, id, granteeID uint64) error,
+) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	granteeID, err := pathID(r, "user_id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	if err := revoke(r.Context(), userID, id, granteeID); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
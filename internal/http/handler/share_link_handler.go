@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+// maxShareLinkFormBytes bounds the password form posted to a share link.
+const maxShareLinkFormBytes = 4 << 10
+
+// shareLinkCSP locks the rendered note page down to its own inline styles
+// and the password form posting back to the same link.
+const shareLinkCSP = "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'"
+
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>{{if .Note}}{{.Note.Title}}{{else}}Shared note{{end}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; background: #f6f6f4; color: #222; }
+main { max-width: 46rem; margin: 3rem auto; padding: 2rem; background: #fff; border-radius: 8px; }
+h1 { margin-top: 0; }
+.tags span { display: inline-block; margin-right: .4rem; padding: .1rem .5rem; border-radius: 4px; background: #eee; font-size: .85rem; }
+.content { white-space: pre-wrap; overflow-wrap: anywhere; line-height: 1.5; }
+.meta { margin-top: 2rem; color: #777; font-size: .85rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<main>
+{{- if .Note}}
+<h1>{{.Note.Title}}</h1>
+{{- with .Note.Tags}}
+<p class="tags">{{range .}}<span>{{.}}</span>{{end}}</p>
+{{- end}}
+<div class="content">{{.Note.Content}}</div>
+<p class="meta">Last updated {{.Note.UpdatedAt.UTC.Format "2 Jan 2006 15:04 MST"}}</p>
+{{- else if .PasswordForm}}
+<h1>This note is password protected</h1>
+{{- with .Error}}
+<p class="error">{{.}}</p>
+{{- end}}
+<form method="post">
+<input type="password" name="password" aria-label="Password" required autofocus>
+<button type="submit">Open</button>
+</form>
+{{- else}}
+<h1>{{.Error}}</h1>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+type sharePage struct {
+	Note         *domain.Note
+	PasswordForm bool
+	Error        string
+}
+
+type ShareLinkHandler struct {
+	links *service.ShareLinkService
+	log   *logger.Logger
+}
+
+func NewShareLinkHandler(links *service.ShareLinkService, log *logger.Logger) *ShareLinkHandler {
+	return &ShareLinkHandler{
+		links: links,
+		log:   log,
+	}
+}
+
+// POST /api/notes/{id}/links
+func (h *ShareLinkHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	var req request.CreateShareLinkRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	link, url, err := h.links.Create(r.Context(), userID, noteID, req.Password, req.ExpiresAt)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response.CreatedShareLinkResponse{
+		ShareLinkResponse: response.NewShareLinkResponse(link),
+		URL:               url,
+	})
+}
+
+// GET /api/notes/{id}/links
+func (h *ShareLinkHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	links, err := h.links.List(r.Context(), userID, noteID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewShareLinkListResponse(links))
+}
+
+// DELETE /api/notes/{id}/links/{link_id}
+func (h *ShareLinkHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+	noteID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	linkID, err := pathID(r, "link_id")
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+
+	if err := h.links.Revoke(r.Context(), userID, noteID, linkID); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /s/{token}?format=json|html
+// POST /s/{token}
+//
+// Public. Answers with the note as JSON, or as an HTML page when asked for
+// with format=html or an Accept header that prefers text/html. Protected
+// links take the password in the X-Share-Password header or, from the HTML
+// page, in the posted password form field.
+func (h *ShareLinkHandler) Open(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Vary", "Accept")
+	asHTML := wantsHTML(r)
+
+	password := r.Header.Get("X-Share-Password")
+	if password == "" && r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxShareLinkFormBytes)
+		password = r.PostFormValue("password")
+	}
+
+	note, _, err := h.links.Open(r.Context(), r.PathValue("token"), password, clientIP(r))
+	if err != nil {
+		if asHTML {
+			h.writePageError(w, err)
+		} else {
+			writeError(w, h.log, err)
+		}
+		return
+	}
+	if asHTML {
+		h.writePage(w, http.StatusOK, sharePage{Note: note})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewPublicNoteResponse(note))
+}
+
+func wantsHTML(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// writePageError is writeError for the HTML page. Password errors show the
+// password form again.
+func (h *ShareLinkHandler) writePageError(w http.ResponseWriter, err error) {
+	status := statusFor(err)
+	page := sharePage{Error: err.Error()}
+	if status == http.StatusInternalServerError {
+		h.log.Error("request failed", err)
+		page.Error = domain.ErrInternal.Error()
+	}
+	var retry *domain.RetryAfterError
+	if errors.As(err, &retry) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.After.Seconds()))))
+	}
+	switch {
+	case errors.Is(err, domain.ErrShareLinkPasswordRequired):
+		page = sharePage{PasswordForm: true}
+	case errors.Is(err, domain.ErrShareLinkWrongPassword):
+		page.PasswordForm = true
+	}
+	h.writePage(w, status, page)
+}
+
+func (h *ShareLinkHandler) writePage(w http.ResponseWriter, status int, page sharePage) {
+	var buf bytes.Buffer
+	if err := sharePageTemplate.Execute(&buf, page); err != nil {
+		h.log.Error("render share page", err)
+		http.Error(w, domain.ErrInternal.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", shareLinkCSP)
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
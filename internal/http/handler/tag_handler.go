@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type TagHandler struct {
+	tags *service.TagService
+	log  *logger.Logger
+}
+
+func NewTagHandler(tags *service.TagService, log *logger.Logger) *TagHandler {
+	return &TagHandler{
+		tags: tags,
+		log:  log,
+	}
+}
+
+// GET /api/tags?sort=name|popular&limit=
+func (h *TagHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var (
+		tags []*domain.Tag
+		err  error
+	)
+	switch r.URL.Query().Get("sort") {
+	case "", "name":
+		tags, err = h.tags.ListTags(r.Context(), userID)
+	case "popular":
+		var limit int
+		limit, _, err = pagination(r)
+		if err == nil {
+			tags, err = h.tags.ListPopularTags(r.Context(), userID, limit)
+		}
+	default:
+		err = domain.ErrInvalidInput
+	}
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewTagListResponse(tags))
+}
+
+// PUT /api/tags/{name}
+//
+// Renames a tag on all of the user's notes. Renaming to an existing tag
+// merges the two.
+func (h *TagHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.RenameTagRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	tag, merged, err := h.tags.RenameTag(r.Context(), userID, r.PathValue("name"), req.Name)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.RenameTagResponse{
+		Tag:    response.NewTagResponse(tag),
+		Merged: merged,
+	})
+}
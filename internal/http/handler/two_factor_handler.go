@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type TwoFactorHandler struct {
+	twoFactor *service.TwoFactorService
+	log       *logger.Logger
+}
+
+func NewTwoFactorHandler(twoFactor *service.TwoFactorService, log *logger.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactor: twoFactor,
+		log:       log,
+	}
+}
+
+// POST /api/auth/2fa
+//
+// Second login step: exchanges the challenge token from /api/auth/login and
+// a TOTP or recovery code for tokens.
+func (h *TwoFactorHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var req request.TwoFactorLoginRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	result, err := h.twoFactor.CompleteLogin(r.Context(), req.ChallengeToken, req.Code, clientInfo(r, req.Device))
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewAuthResponse(result.User, result.Tokens))
+}
+
+// GET /api/me/2fa
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	enabled, left, err := h.twoFactor.Status(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.TwoFactorStatusResponse{Enabled: enabled, RecoveryCodesLeft: left})
+}
+
+// POST /api/me/2fa
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	enrollment, err := h.twoFactor.Enroll(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewTwoFactorEnrollmentResponse(enrollment))
+}
+
+// POST /api/me/2fa/confirm
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.TwoFactorCodeRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	codes, err := h.twoFactor.Confirm(r.Context(), userID, req.Code)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// POST /api/me/2fa/recovery-codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.TwoFactorReauthRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(r.Context(), userID, req.Password, req.Code)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DELETE /api/me/2fa
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.TwoFactorReauthRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.twoFactor.Disable(r.Context(), userID, req.Password, req.Code); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
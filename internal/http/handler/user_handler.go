@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/dto/request"
+	"github.com/maqsatto/Notes-API/internal/http/dto/response"
+	"github.com/maqsatto/Notes-API/internal/http/middleware"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/service"
+	"github.com/maqsatto/Notes-API/internal/utils"
+)
+
+type UserHandler struct {
+	users  *service.UserService
+	tokens *service.TokenService
+	log    *logger.Logger
+}
+
+func NewUserHandler(users *service.UserService, tokens *service.TokenService, log *logger.Logger) *UserHandler {
+	return &UserHandler{
+		users:  users,
+		tokens: tokens,
+		log:    log,
+	}
+}
+
+// POST /api/auth/register
+func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req request.RegisterRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	user, err := h.users.Register(r.Context(), req.Username, req.Email, req.Password)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response.NewUserResponse(user))
+}
+
+// POST /api/auth/login
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req request.LoginRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	result, err := h.users.Login(r.Context(), req.Login, req.Password, clientInfo(r, req.Device))
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	if result.Tokens == nil {
+		utils.WriteJSON(w, http.StatusOK, response.NewTwoFactorChallengeResponse(result))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewAuthResponse(result.User, result.Tokens))
+}
+
+// POST /api/auth/refresh
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req request.RefreshRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	tokens, err := h.tokens.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewTokenResponse(tokens))
+}
+
+// POST /api/auth/logout
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req request.RefreshRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.tokens.Revoke(r.Context(), req.RefreshToken); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/me
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	user, err := h.users.GetByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewUserResponse(user))
+}
+
+// PUT /api/me
+//
+// A changed email is only applied once confirmed from the link mailed to it.
+func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.UpdateProfileRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	user, err := h.users.UpdateProfile(r.Context(), userID, req.Username, req.Email)
+	if err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response.NewUserResponse(user))
+}
+
+// PUT /api/me/password
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.ChangePasswordRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.users.ChangePassword(r.Context(), userID, req.OldPassword, req.NewPassword); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /api/me/search-language
+func (h *UserHandler) UpdateSearchLanguage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	var req request.UpdateSearchLanguageRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	if err := h.users.UpdateSearchLanguage(r.Context(), userID, req.Language); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/me
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, h.log, domain.ErrUnauthorized)
+		return
+	}
+
+	if err := h.users.DeleteAccount(r.Context(), userID); err != nil {
+		writeError(w, h.log, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
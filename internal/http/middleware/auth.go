@@ -2,15 +2,29 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
 )
 
 type ctxKey string
 
-const userIDKey ctxKey = "user_id"
+const (
+	userIDKey      ctxKey = "user_id"
+	sessionIDKey   ctxKey = "session_id"
+	permissionsKey ctxKey = "permissions"
+	scopesKey      ctxKey = "scopes"
+)
+
+// SessionChecker reports whether the session an access token was issued for
+// is still live.
+type SessionChecker interface {
+	CheckSession(ctx context.Context, userID uint64, sessionID string) error
+}
 
 func UserIDFromContext(ctx context.Context) (uint64, bool) {
 	v := ctx.Value(userIDKey)
@@ -18,9 +32,45 @@ func UserIDFromContext(ctx context.Context) (uint64, bool) {
 	return id, ok
 }
 
-func AuthMiddleware(jwtm *auth.JWTManager) func(http.Handler) http.Handler {
+// SessionIDFromContext returns the session of the access token that
+// authenticated the request.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionIDKey).(string)
+	return id, ok
+}
+
+// PermissionsFromContext returns the permissions granted by the access token
+// that authenticated the request.
+func PermissionsFromContext(ctx context.Context) []string {
+	perms, _ := ctx.Value(permissionsKey).([]string)
+	return perms
+}
+
+// APIKeyAuthenticator resolves a personal API key to its owner and scopes.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (uint64, []string, error)
+}
+
+// ScopesFromContext returns the scopes of the API key or OAuth client token
+// that authenticated the request. ok is false for requests authenticated
+// with a login's access token, which are not limited by scopes.
+func ScopesFromContext(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(scopesKey).([]string)
+	return scopes, ok
+}
+
+// AuthMiddleware authenticates requests with an access token. When apiKeys
+// is not nil it also accepts delegated credentials: personal API keys, sent
+// in the X-API-Key header or as a Bearer token, and access tokens issued to
+// OAuth clients. Routes taking them must check scopes with RequireScope.
+func AuthMiddleware(jwtm *auth.JWTManager, sessions SessionChecker, apiKeys APIKeyAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get("X-API-Key"); key != "" {
+				authenticateAPIKey(w, r, next, apiKeys, strings.TrimSpace(key))
+				return
+			}
+
 			h := r.Header.Get("Authorization")
 			if h == "" {
 				http.Error(w, "missing Authorization header", http.StatusUnauthorized)
@@ -38,6 +88,10 @@ func AuthMiddleware(jwtm *auth.JWTManager) func(http.Handler) http.Handler {
 				http.Error(w, "empty token", http.StatusUnauthorized)
 				return
 			}
+			if strings.HasPrefix(tokenString, auth.APIKeyPrefix) {
+				authenticateAPIKey(w, r, next, apiKeys, tokenString)
+				return
+			}
 
 			claims, err := jwtm.ParseAndValidate(tokenString)
 			if err != nil {
@@ -45,9 +99,97 @@ func AuthMiddleware(jwtm *auth.JWTManager) func(http.Handler) http.Handler {
 				return
 			}
 
+			if err := sessions.CheckSession(r.Context(), claims.UserID, claims.SessionID); err != nil {
+				if errors.Is(err, domain.ErrRevokedToken) || errors.Is(err, domain.ErrInvalidToken) {
+					http.Error(w, "token revoked", http.StatusUnauthorized)
+					return
+				}
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, sessionIDKey, claims.SessionID)
+			ctx = context.WithValue(ctx, permissionsKey, claims.Permissions)
+			if claims.ClientID != "" {
+				if apiKeys == nil {
+					http.Error(w, "tokens issued to apps are not accepted for this endpoint", http.StatusUnauthorized)
+					return
+				}
+				ctx = context.WithValue(ctx, scopesKey, strings.Fields(claims.Scope))
+			}
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, apiKeys APIKeyAuthenticator, key string) {
+	if apiKeys == nil {
+		http.Error(w, "API keys are not accepted for this endpoint", http.StatusUnauthorized)
+		return
+	}
+	userID, scopes, err := apiKeys.Authenticate(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidAPIKey) {
+			http.Error(w, "invalid or expired API key", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	ctx := context.WithValue(r.Context(), userIDKey, userID)
+	ctx = context.WithValue(ctx, scopesKey, scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// WebSocketToken lets WebSocket handshakes carry the access token in the
+// access_token query parameter, since browsers cannot set headers on them.
+// It must run before AuthMiddleware.
+func WebSocketToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			q := r.URL.Query()
+			if token := q.Get("access_token"); token != "" {
+				r = r.Clone(r.Context())
+				r.Header.Set("Authorization", "Bearer "+token)
+				q.Del("access_token")
+				r.URL.RawQuery = q.Encode()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope rejects requests made with an API key or app token lacking
+// scope. Requests authenticated with a login's access token pass. It must
+// run after AuthMiddleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := ScopesFromContext(r.Context()); ok && !slices.Contains(scopes, scope) {
+				http.Error(w, "credentials lack the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission lets the request through only if the access token grants
+// perm. It must run after AuthMiddleware.
+func RequirePermission(perm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !domain.HasPermission(PermissionsFromContext(r.Context()), perm) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,20 +1,38 @@
 package router
 
 import (
+	"database/sql"
 	"net/http"
 
 	"github.com/maqsatto/Notes-API/internal/auth"
 	"github.com/maqsatto/Notes-API/internal/config"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/http/handler"
 	"github.com/maqsatto/Notes-API/internal/http/middleware"
 	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/mail"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/service"
 	"github.com/maqsatto/Notes-API/internal/utils"
+	"github.com/maqsatto/Notes-API/internal/validator"
 )
 
 type Deps struct {
 	Config *config.Config
 	Logger *logger.Logger
-	DB     any
+	DB     *sql.DB
 	JWT    *auth.JWTManager
+	Mailer mail.Mailer
+	// PasswordPolicy screens new passwords beyond the character rules.
+	PasswordPolicy *validator.PasswordPolicy
+	// LoginAttempts keeps failed login counters; it outlives the router so
+	// main can purge it.
+	LoginAttempts repository.LoginAttemptStore
+	// OIDCProviders are the identity providers users can log in with.
+	OIDCProviders []*auth.OIDCProvider
+	// Collab runs collaborative editing sessions; main feeds it the
+	// notifications of other instances and shuts it down.
+	Collab *service.CollabService
 }
 
 func New(d Deps) http.Handler {
@@ -24,19 +42,230 @@ func New(d Deps) http.Handler {
 		utils.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	})
 
+	txm := repository.NewTxManager(d.DB)
+	userRepo := repository.NewUserRepo(d.DB)
+	noteRepo := repository.NewNoteRepo(d.DB)
 
-	// db := d.DB.(*sql.DB)
+	hasher := auth.NewPasswordHasher(auth.Argon2Params{
+		Memory:      uint32(d.Config.Password.Argon2Memory),
+		Time:        uint32(d.Config.Password.Argon2Time),
+		Parallelism: uint8(d.Config.Password.Argon2Parallelism),
+	})
+
+	roleRepo := repository.NewRoleRepo(d.DB)
+	auditRepo := repository.NewAuditRepo(d.DB)
+
+	sessionRepo := repository.NewSessionRepo(d.DB)
+	refreshRepo := repository.NewRefreshTokenRepo(d.DB)
+	tokenSvc := service.NewTokenService(txm, userRepo, roleRepo, sessionRepo, refreshRepo, d.JWT, d.Config.JWT.RefreshTTL, d.Config.Session.CacheTTL)
+	sessionHandler := handler.NewSessionHandler(tokenSvc, d.Logger)
+
+	lockout := d.Config.Lockout
+	loginGuard := service.NewLoginGuard(d.LoginAttempts, auditRepo, service.LoginPolicy{
+		FreeAttempts:     lockout.FreeAttempts,
+		BaseDelay:        lockout.BaseDelay,
+		MaxDelay:         lockout.MaxDelay,
+		AccountThreshold: lockout.AccountThreshold,
+		IPThreshold:      lockout.IPThreshold,
+		LockDuration:     lockout.LockDuration,
+		Window:           lockout.Window,
+	})
+
+	twoFactorRepo := repository.NewTwoFactorRepo(d.DB)
+	twoFactorSvc := service.NewTwoFactorService(txm, userRepo, twoFactorRepo, tokenSvc, loginGuard, hasher, d.Config.TwoFactor.Issuer, d.Config.TwoFactor.ChallengeTTL)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorSvc, d.Logger)
+
+	resetRepo := repository.NewPasswordResetRepo(d.DB)
+	resetSvc := service.NewPasswordResetService(txm, userRepo, resetRepo, tokenSvc, hasher, d.PasswordPolicy, d.Mailer, d.Config.Reset.URL, d.Config.Reset.TTL)
+	resetHandler := handler.NewPasswordResetHandler(resetSvc, d.Logger)
+
+	emailTokenRepo := repository.NewEmailTokenRepo(d.DB)
+	emailSvc := service.NewEmailService(txm, userRepo, emailTokenRepo, d.Mailer, d.Config.Email.VerifyURL, d.Config.Email.ChangeURL, d.Config.Email.TokenTTL)
+	emailHandler := handler.NewEmailHandler(emailSvc, d.Logger)
+
+	userSvc := service.NewUserService(txm, userRepo, noteRepo, tokenSvc, twoFactorSvc, emailSvc, loginGuard, hasher, d.PasswordPolicy)
+	userHandler := handler.NewUserHandler(userSvc, tokenSvc, d.Logger)
+
+	identityRepo := repository.NewIdentityRepo(d.DB)
+	oidcStateRepo := repository.NewOIDCStateRepo(d.DB)
+	oidcSvc := service.NewOIDCService(txm, userRepo, identityRepo, oidcStateRepo, auditRepo, tokenSvc, twoFactorSvc, d.OIDCProviders, d.Config.OIDC.StateTTL)
+	oidcHandler := handler.NewOIDCHandler(oidcSvc, d.Logger)
+
+	apiKeyRepo := repository.NewAPIKeyRepo(d.DB)
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeySvc, d.Logger)
+
+	oauthClientRepo := repository.NewOAuthClientRepo(d.DB)
+	oauthCodeRepo := repository.NewOAuthCodeRepo(d.DB)
+	oauthGrantRepo := repository.NewOAuthGrantRepo(d.DB)
+	oauthSvc := service.NewOAuthService(txm, oauthClientRepo, oauthCodeRepo, oauthGrantRepo, userRepo, tokenSvc, d.Config.OAuth.CodeTTL)
+	oauthHandler := handler.NewOAuthHandler(oauthSvc, d.Logger)
+
+	adminSvc := service.NewAdminService(txm, userRepo, noteRepo, roleRepo, auditRepo, tokenSvc, resetSvc)
+	adminHandler := handler.NewAdminHandler(adminSvc, d.Logger)
+
+	notebookRepo := repository.NewNotebookRepo(d.DB)
+	notebookSvc := service.NewNotebookService(txm, notebookRepo)
+	notebookHandler := handler.NewNotebookHandler(notebookSvc, d.Logger)
+
+	noteSvc := service.NewNoteService(txm, noteRepo, notebookRepo, userRepo, d.Config.Email.RequireVerified)
+	noteHandler := handler.NewNoteHandler(noteSvc, d.Logger, d.Config.Notes.RequireIfMatch)
+
+	shareRepo := repository.NewShareRepo(d.DB)
+	shareSvc := service.NewShareService(shareRepo, noteRepo, notebookRepo, userRepo)
+	shareHandler := handler.NewShareHandler(shareSvc, d.Logger)
+
+	commentRepo := repository.NewCommentRepo(d.DB)
+	commentSvc := service.NewCommentService(commentRepo, noteRepo)
+	commentHandler := handler.NewCommentHandler(commentSvc, d.Logger)
+
+	shareLinkRepo := repository.NewShareLinkRepo(d.DB)
+	shareLinkSvc := service.NewShareLinkService(shareLinkRepo, noteRepo, hasher, loginGuard, d.Config.ShareLink.BaseURL)
+	shareLinkHandler := handler.NewShareLinkHandler(shareLinkSvc, d.Logger)
+
+	collabHandler := handler.NewCollabHandler(d.Collab, d.Logger)
+
+	revisionRepo := repository.NewRevisionRepo(d.DB)
+	revisionSvc := service.NewRevisionService(noteSvc, revisionRepo)
+	revisionHandler := handler.NewRevisionHandler(revisionSvc, d.Logger)
+
+	tagRepo := repository.NewTagRepo(d.DB)
+	tagSvc := service.NewTagService(tagRepo)
+	tagHandler := handler.NewTagHandler(tagSvc, d.Logger)
+
+	jwksHandler := handler.NewJWKSHandler(d.JWT)
+	mux.HandleFunc("GET /.well-known/jwks.json", jwksHandler.Get)
+
+	mux.HandleFunc("POST /api/auth/register", userHandler.Register)
+	mux.HandleFunc("POST /api/auth/login", userHandler.Login)
+	mux.HandleFunc("POST /api/auth/2fa", twoFactorHandler.Verify)
+	mux.HandleFunc("GET /api/auth/oidc/providers", oidcHandler.Providers)
+	mux.HandleFunc("GET /api/auth/oidc/callback", oidcHandler.Callback)
+	mux.HandleFunc("GET /api/auth/oidc/{provider}/login", oidcHandler.Login)
+	mux.HandleFunc("POST /api/auth/forgot-password", resetHandler.Forgot)
+	mux.HandleFunc("POST /api/auth/reset-password", resetHandler.Reset)
+	mux.HandleFunc("POST /api/auth/verify-email", emailHandler.Verify)
+	mux.HandleFunc("POST /api/auth/confirm-email", emailHandler.ConfirmChange)
+	mux.HandleFunc("POST /api/auth/refresh", userHandler.Refresh)
+	mux.HandleFunc("POST /api/auth/logout", userHandler.Logout)
+
+	// OAuth2 endpoints called by third-party apps, which authenticate as
+	// clients
+	mux.HandleFunc("POST /oauth/token", oauthHandler.Token)
+	mux.HandleFunc("POST /oauth/introspect", oauthHandler.Introspect)
+	mux.HandleFunc("POST /oauth/revoke", oauthHandler.Revoke)
+
+	// Public share links, opened by people without an account
+	mux.HandleFunc("GET /s/{token}", shareLinkHandler.Open)
+	mux.HandleFunc("POST /s/{token}", shareLinkHandler.Open)
+
+	//Protected routes
+	authMW := middleware.AuthMiddleware(d.JWT, tokenSvc, nil)
+
+	// Note routes also accept API keys holding the scope
+	keyAuthMW := middleware.AuthMiddleware(d.JWT, tokenSvc, apiKeySvc)
+	notesRead := func(h http.HandlerFunc) http.Handler {
+		return keyAuthMW(middleware.RequireScope(domain.ScopeNotesRead)(h))
+	}
+	notesWrite := func(h http.HandlerFunc) http.Handler {
+		return keyAuthMW(middleware.RequireScope(domain.ScopeNotesWrite)(h))
+	}
+
+	mux.Handle("GET /api/me", authMW(http.HandlerFunc(userHandler.Me)))
+	mux.Handle("PUT /api/me", authMW(http.HandlerFunc(userHandler.UpdateProfile)))
+	mux.Handle("PUT /api/me/password", authMW(http.HandlerFunc(userHandler.ChangePassword)))
+	mux.Handle("PUT /api/me/search-language", authMW(http.HandlerFunc(userHandler.UpdateSearchLanguage)))
+	mux.Handle("DELETE /api/me", authMW(http.HandlerFunc(userHandler.DeleteAccount)))
+
+	mux.Handle("POST /api/me/email/verification", authMW(http.HandlerFunc(emailHandler.ResendVerification)))
+
+	mux.Handle("GET /api/me/2fa", authMW(http.HandlerFunc(twoFactorHandler.Status)))
+	mux.Handle("POST /api/me/2fa", authMW(http.HandlerFunc(twoFactorHandler.Enroll)))
+	mux.Handle("POST /api/me/2fa/confirm", authMW(http.HandlerFunc(twoFactorHandler.Confirm)))
+	mux.Handle("POST /api/me/2fa/recovery-codes", authMW(http.HandlerFunc(twoFactorHandler.RegenerateRecoveryCodes)))
+	mux.Handle("DELETE /api/me/2fa", authMW(http.HandlerFunc(twoFactorHandler.Disable)))
+
+	mux.Handle("GET /api/me/sessions", authMW(http.HandlerFunc(sessionHandler.List)))
+	mux.Handle("DELETE /api/me/sessions", authMW(http.HandlerFunc(sessionHandler.RevokeAll)))
+	mux.Handle("DELETE /api/me/sessions/{id}", authMW(http.HandlerFunc(sessionHandler.Revoke)))
+
+	mux.Handle("GET /api/me/api-keys", authMW(http.HandlerFunc(apiKeyHandler.List)))
+	mux.Handle("POST /api/me/api-keys", authMW(http.HandlerFunc(apiKeyHandler.Create)))
+	mux.Handle("DELETE /api/me/api-keys/{id}", authMW(http.HandlerFunc(apiKeyHandler.Revoke)))
+
+	mux.Handle("GET /api/me/authorized-apps", authMW(http.HandlerFunc(oauthHandler.ListAuthorizedApps)))
+	mux.Handle("DELETE /api/me/authorized-apps/{client_id}", authMW(http.HandlerFunc(oauthHandler.RevokeAuthorizedApp)))
+
+	mux.Handle("GET /api/oauth/clients", authMW(http.HandlerFunc(oauthHandler.ListClients)))
+	mux.Handle("POST /api/oauth/clients", authMW(http.HandlerFunc(oauthHandler.RegisterClient)))
+	mux.Handle("DELETE /api/oauth/clients/{id}", authMW(http.HandlerFunc(oauthHandler.DeleteClient)))
+	mux.Handle("GET /api/oauth/authorize", authMW(http.HandlerFunc(oauthHandler.Consent)))
+	mux.Handle("POST /api/oauth/authorize", authMW(http.HandlerFunc(oauthHandler.Decide)))
+
+	// Admin routes
+	admin := func(perm string, h http.HandlerFunc) http.Handler {
+		return authMW(middleware.RequirePermission(perm)(h))
+	}
+	mux.Handle("GET /api/admin/users", admin(domain.PermUsersRead, adminHandler.ListUsers))
+	mux.Handle("GET /api/admin/users/{id}", admin(domain.PermUsersRead, adminHandler.GetUser))
+	mux.Handle("POST /api/admin/users/{id}/suspend", admin(domain.PermUsersSuspend, adminHandler.Suspend))
+	mux.Handle("POST /api/admin/users/{id}/unsuspend", admin(domain.PermUsersSuspend, adminHandler.Unsuspend))
+	mux.Handle("POST /api/admin/users/{id}/password-reset", admin(domain.PermUsersResetPassword, adminHandler.ForcePasswordReset))
+	mux.Handle("POST /api/admin/users/{id}/restore", admin(domain.PermUsersRestore, adminHandler.Restore))
+	mux.Handle("PUT /api/admin/users/{id}/role", admin(domain.PermUsersAssignRole, adminHandler.SetRole))
+	mux.Handle("GET /api/admin/roles", admin(domain.PermRolesManage, adminHandler.ListRoles))
+	mux.Handle("PUT /api/admin/roles/{name}", admin(domain.PermRolesManage, adminHandler.SaveRole))
+	mux.Handle("DELETE /api/admin/roles/{name}", admin(domain.PermRolesManage, adminHandler.DeleteRole))
+
+	mux.Handle("POST /api/notes", notesWrite(noteHandler.Create))
+	mux.Handle("GET /api/notes", notesRead(noteHandler.List))
+	mux.Handle("GET /api/notes/search", notesRead(noteHandler.Search))
+	mux.Handle("GET /api/notes/{id}", notesRead(noteHandler.Get))
+	mux.Handle("PUT /api/notes/{id}", notesWrite(noteHandler.Update))
+	mux.Handle("DELETE /api/notes/{id}", notesWrite(noteHandler.Delete))
+	mux.Handle("POST /api/notes/{id}/move", notesWrite(noteHandler.Move))
+
+	mux.Handle("GET /api/notebooks", notesRead(notebookHandler.List))
+	mux.Handle("POST /api/notebooks", notesWrite(notebookHandler.Create))
+	mux.Handle("GET /api/notebooks/{id}", notesRead(notebookHandler.Get))
+	mux.Handle("PUT /api/notebooks/{id}", notesWrite(notebookHandler.Rename))
+	mux.Handle("POST /api/notebooks/{id}/move", notesWrite(notebookHandler.Move))
+	mux.Handle("DELETE /api/notebooks/{id}", notesWrite(notebookHandler.Delete))
+
+	mux.Handle("GET /api/trash", notesRead(noteHandler.ListTrash))
+	mux.Handle("DELETE /api/trash", notesWrite(noteHandler.EmptyTrash))
+	mux.Handle("POST /api/trash/{id}/restore", notesWrite(noteHandler.Restore))
+	mux.Handle("DELETE /api/trash/{id}", notesWrite(noteHandler.DeleteFromTrash))
 
-	// // userRepo := repository.NewUserRepo(db)
-	// // userSvc := service.NewService(userRepo)
-	// // userHandler := handler.NewHandler(userSvc)
+	mux.Handle("GET /api/notes/{id}/revisions", notesRead(revisionHandler.List))
+	mux.Handle("GET /api/notes/{id}/revisions/diff", notesRead(revisionHandler.Diff))
+	mux.Handle("GET /api/notes/{id}/revisions/{rev}", notesRead(revisionHandler.Get))
+	mux.Handle("POST /api/notes/{id}/revisions/{rev}/restore", notesWrite(revisionHandler.Restore))
 
-		//Protected routes
-	// authMW := middleware.AuthMiddleware(d.JWT)
+	mux.Handle("GET /api/notes/{id}/comments", notesRead(commentHandler.List))
+	mux.Handle("POST /api/notes/{id}/comments", notesWrite(commentHandler.Create))
+	mux.Handle("DELETE /api/notes/{id}/comments/{comment_id}", notesWrite(commentHandler.Delete))
 
-	// mux.Handle("GET /api/notes", authMW(http.HandlerFunc(userHandler.List)))
+	// Collaborative editing over WebSocket; credentials without the write
+	// scope join read-only
+	mux.Handle("GET /api/notes/{id}/collab", middleware.WebSocketToken(notesRead(collabHandler.Connect)))
 
+	// Sharing is managed from sessions only; API keys and apps can read
+	// what was shared
+	mux.Handle("GET /api/notes/{id}/shares", authMW(http.HandlerFunc(shareHandler.ListNoteShares)))
+	mux.Handle("POST /api/notes/{id}/shares", authMW(http.HandlerFunc(shareHandler.ShareNote)))
+	mux.Handle("DELETE /api/notes/{id}/shares/{user_id}", authMW(http.HandlerFunc(shareHandler.RevokeNoteShare)))
+	mux.Handle("GET /api/notebooks/{id}/shares", authMW(http.HandlerFunc(shareHandler.ListNotebookShares)))
+	mux.Handle("POST /api/notebooks/{id}/shares", authMW(http.HandlerFunc(shareHandler.ShareNotebook)))
+	mux.Handle("DELETE /api/notebooks/{id}/shares/{user_id}", authMW(http.HandlerFunc(shareHandler.RevokeNotebookShare)))
+	mux.Handle("GET /api/notes/{id}/links", authMW(http.HandlerFunc(shareLinkHandler.List)))
+	mux.Handle("POST /api/notes/{id}/links", authMW(http.HandlerFunc(shareLinkHandler.Create)))
+	mux.Handle("DELETE /api/notes/{id}/links/{link_id}", authMW(http.HandlerFunc(shareLinkHandler.Revoke)))
+	mux.Handle("GET /api/shared-with-me", notesRead(shareHandler.ListSharedNotes))
+	mux.Handle("GET /api/shared-with-me/notebooks", notesRead(shareHandler.ListSharedNotebooks))
 
+	mux.Handle("GET /api/tags", notesRead(tagHandler.List))
+	mux.Handle("PUT /api/tags/{name}", notesWrite(tagHandler.Rename))
 
 	//lobal middleware chain
 	var h http.Handler = mux
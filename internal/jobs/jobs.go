@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/logger"
+)
+
+// Every runs fn once right away and then on every tick of interval until ctx
+// is cancelled. Errors are logged and do not stop the loop. A non-positive
+// interval disables the job.
+func Every(ctx context.Context, interval time.Duration, name string, log *logger.Logger, fn func(ctx context.Context) error) {
+	if interval <= 0 {
+		log.Info("job " + name + " disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(ctx); err != nil && ctx.Err() == nil {
+			log.Error("job "+name+" failed", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
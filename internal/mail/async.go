@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"context"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/logger"
+)
+
+const asyncSendTimeout = 30 * time.Second
+
+// AsyncMailer hands messages to another Mailer in the background and logs
+// failures. Callers return immediately, so response times do not depend on
+// whether a message was sent.
+type AsyncMailer struct {
+	next Mailer
+	log  *logger.Logger
+}
+
+func NewAsyncMailer(next Mailer, log *logger.Logger) *AsyncMailer {
+	return &AsyncMailer{
+		next: next,
+		log:  log,
+	}
+}
+
+func (m *AsyncMailer) Send(ctx context.Context, msg Message) error {
+	if err := Validate(msg); err != nil {
+		return err
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncSendTimeout)
+		defer cancel()
+		if err := m.next.Send(ctx, msg); err != nil {
+			m.log.Error("failed to send mail to "+msg.To, err)
+		}
+	}()
+	return nil
+}
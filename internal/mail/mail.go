@@ -0,0 +1,48 @@
+// Package mail sends transactional email such as password reset links.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer delivers a message or reports why it could not.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// format renders msg as a plain text RFC 5322 message.
+func format(from string, msg Message) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", from)
+	fmt.Fprintf(&sb, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&sb, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&sb, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+	return []byte(sb.String())
+}
+
+// validHeader rejects header values that would inject extra headers.
+func validHeader(v string) bool {
+	return !strings.ContainsAny(v, "\r\n")
+}
+
+// Validate rejects messages no Mailer would send. Mailers call it
+// themselves; it is exported for callers that send later.
+func Validate(msg Message) error {
+	if msg.To == "" || !validHeader(msg.To) || !validHeader(msg.Subject) {
+		return fmt.Errorf("mail: invalid recipient or subject")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"context"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+)
+
+// SMTPMailer sends through an SMTP server, upgrading to TLS when the server
+// offers STARTTLS. Username may be empty for servers without auth.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		from:     from,
+		username: username,
+		password: password,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := Validate(msg); err != nil {
+		return err
+	}
+	var a smtp.Auth
+	if m.username != "" {
+		a = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	// the envelope sender is the bare address of the From header
+	sender := m.from
+	if addr, err := netmail.ParseAddress(m.from); err == nil {
+		sender = addr.Address
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.addr, a, sender, []string{msg.To}, format(m.from, msg))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
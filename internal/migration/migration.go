@@ -129,6 +129,621 @@ var migrations = []Migration{
 			DROP TABLE IF EXISTS tags CASCADE;
 		`,
 	},
+	{
+		Version: 4,
+		Name:    "scope_tags_per_user",
+		Up: `
+			-- tags were global and never written by the application, so any
+			-- existing rows have no owner to migrate to
+			DELETE FROM tags;
+
+			ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
+			DROP INDEX IF EXISTS idx_tags_name;
+
+			ALTER TABLE tags
+				ADD COLUMN user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE;
+
+			CREATE UNIQUE INDEX IF NOT EXISTS uq_tags_user_name ON tags(user_id, name);
+		`,
+		Down: `
+			DELETE FROM tags;
+
+			DROP INDEX IF EXISTS uq_tags_user_name;
+			ALTER TABLE tags DROP COLUMN IF EXISTS user_id;
+
+			ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (name);
+			CREATE INDEX IF NOT EXISTS idx_tags_name ON tags(name);
+		`,
+	},
+	{
+		Version: 5,
+		Name:    "add_notes_full_text_search",
+		Up: `
+			ALTER TABLE users
+				ADD COLUMN search_language regconfig NOT NULL DEFAULT 'english';
+
+			-- copied from the owner so the generated column below only depends
+			-- on the row itself
+			ALTER TABLE notes
+				ADD COLUMN search_language regconfig NOT NULL DEFAULT 'english';
+
+			ALTER TABLE notes
+				ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector(search_language, coalesce(title, '')), 'A') ||
+					setweight(to_tsvector(search_language, coalesce(content, '')), 'B')
+				) STORED;
+
+			CREATE INDEX IF NOT EXISTS idx_notes_search_vector
+				ON notes USING GIN (search_vector);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_notes_search_vector;
+
+			ALTER TABLE notes DROP COLUMN IF EXISTS search_vector;
+			ALTER TABLE notes DROP COLUMN IF EXISTS search_language;
+			ALTER TABLE users DROP COLUMN IF EXISTS search_language;
+		`,
+	},
+	{
+		Version: 6,
+		Name:    "create_note_revisions_table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS note_revisions (
+				id BIGSERIAL PRIMARY KEY,
+				note_id BIGINT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+				revision INTEGER NOT NULL,
+				title VARCHAR(255) NOT NULL,
+				content TEXT NOT NULL,
+				tags TEXT[] NOT NULL DEFAULT '{}',
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				UNIQUE (note_id, revision)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_note_revisions_created_at ON note_revisions(created_at);
+
+			-- existing notes start their history at their current state
+			INSERT INTO note_revisions (note_id, revision, title, content, tags, created_at)
+			SELECT n.id, 1, n.title, n.content,
+				ARRAY(
+					SELECT t.name FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+					WHERE nt.note_id = n.id ORDER BY t.name
+				),
+				n.updated_at
+			FROM notes n;
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_note_revisions_created_at;
+			DROP TABLE IF EXISTS note_revisions;
+		`,
+	},
+	{
+		Version: 7,
+		Name:    "create_refresh_tokens_table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS refresh_tokens (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				family_id UUID NOT NULL,
+				parent_id BIGINT REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				expires_at TIMESTAMPTZ NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				used_at TIMESTAMPTZ,
+				revoked_at TIMESTAMPTZ
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+			CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+			CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
+			DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+			DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+			DROP TABLE IF EXISTS refresh_tokens;
+		`,
+	},
+	{
+		Version: 8,
+		Name:    "create_sessions_table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS sessions (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				device VARCHAR(100) NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL DEFAULT '',
+				ip VARCHAR(45) NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				expires_at TIMESTAMPTZ NOT NULL,
+				revoked_at TIMESTAMPTZ
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+			CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+
+			-- every existing refresh token family becomes a session
+			INSERT INTO sessions (id, user_id, created_at, last_seen_at, expires_at, revoked_at)
+			SELECT family_id, MIN(user_id), MIN(created_at), MAX(created_at), MAX(expires_at),
+				CASE WHEN bool_and(revoked_at IS NOT NULL) THEN MAX(revoked_at) END
+			FROM refresh_tokens
+			GROUP BY family_id
+			ON CONFLICT (id) DO NOTHING;
+
+			ALTER TABLE refresh_tokens
+				ADD CONSTRAINT fk_refresh_tokens_session
+				FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE;
+		`,
+		Down: `
+			ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_session;
+			DROP INDEX IF EXISTS idx_sessions_expires_at;
+			DROP INDEX IF EXISTS idx_sessions_user_id;
+			DROP TABLE IF EXISTS sessions;
+		`,
+	},
+	{
+		Version: 9,
+		Name:    "add_two_factor_auth",
+		Up: `
+			ALTER TABLE users
+				ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64) NOT NULL DEFAULT '',
+				ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ,
+				ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+			CREATE TABLE IF NOT EXISTS recovery_codes (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				code_hash CHAR(64) NOT NULL,
+				used_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				CONSTRAINT uq_recovery_codes_user_hash UNIQUE (user_id, code_hash)
+			);
+
+			CREATE TABLE IF NOT EXISTS login_challenges (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				attempts INT NOT NULL DEFAULT 0,
+				expires_at TIMESTAMPTZ NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_login_challenges_expires_at ON login_challenges(expires_at);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_login_challenges_expires_at;
+			DROP TABLE IF EXISTS login_challenges;
+			DROP TABLE IF EXISTS recovery_codes;
+			ALTER TABLE users
+				DROP COLUMN IF EXISTS totp_last_step,
+				DROP COLUMN IF EXISTS totp_enabled_at,
+				DROP COLUMN IF EXISTS totp_secret;
+		`,
+	},
+	{
+		Version: 10,
+		Name:    "create_password_reset_tokens_table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS password_reset_tokens (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				expires_at TIMESTAMPTZ NOT NULL,
+				used_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+			CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_password_reset_tokens_expires_at;
+			DROP INDEX IF EXISTS idx_password_reset_tokens_user_id;
+			DROP TABLE IF EXISTS password_reset_tokens;
+		`,
+	},
+	{
+		Version: 11,
+		Name:    "add_email_verification",
+		Up: `
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+			CREATE TABLE IF NOT EXISTS email_tokens (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify', 'change')),
+				email VARCHAR(255) NOT NULL,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				expires_at TIMESTAMPTZ NOT NULL,
+				used_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_email_tokens_user_id ON email_tokens(user_id);
+			CREATE INDEX IF NOT EXISTS idx_email_tokens_expires_at ON email_tokens(expires_at);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_email_tokens_expires_at;
+			DROP INDEX IF EXISTS idx_email_tokens_user_id;
+			DROP TABLE IF EXISTS email_tokens;
+			ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+		`,
+	},
+	{
+		Version: 12,
+		Name:    "create_login_attempts_and_audit_log",
+		Up: `
+			CREATE TABLE IF NOT EXISTS login_attempts (
+				key TEXT PRIMARY KEY,
+				failures INT NOT NULL DEFAULT 0,
+				last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				locked_until TIMESTAMPTZ
+			);
+
+			CREATE TABLE IF NOT EXISTS audit_log (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+				action VARCHAR(64) NOT NULL,
+				ip VARCHAR(45) NOT NULL DEFAULT '',
+				details TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log(user_id);
+			CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_audit_log_created_at;
+			DROP INDEX IF EXISTS idx_audit_log_user_id;
+			DROP TABLE IF EXISTS audit_log;
+			DROP TABLE IF EXISTS login_attempts;
+		`,
+	},
+	{
+		Version: 13,
+		Name:    "create_roles_and_user_admin_columns",
+		Up: `
+			CREATE TABLE IF NOT EXISTS roles (
+				name VARCHAR(50) PRIMARY KEY,
+				description TEXT NOT NULL DEFAULT '',
+				permissions TEXT[] NOT NULL DEFAULT '{}',
+				built_in BOOLEAN NOT NULL DEFAULT false,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			DROP TRIGGER IF EXISTS trg_roles_set_updated_at ON roles;
+			CREATE TRIGGER trg_roles_set_updated_at
+				BEFORE UPDATE ON roles
+				FOR EACH ROW
+				EXECUTE FUNCTION set_updated_at();
+
+			INSERT INTO roles (name, description, permissions, built_in) VALUES
+				('user', 'Regular account', '{}', true),
+				('admin', 'Full administrative access', '{*}', true)
+			ON CONFLICT (name) DO NOTHING;
+
+			ALTER TABLE users
+				ADD COLUMN IF NOT EXISTS role VARCHAR(50) NOT NULL DEFAULT 'user'
+					REFERENCES roles(name) ON UPDATE CASCADE,
+				ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ,
+				ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+			CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+
+			ALTER TABLE audit_log
+				ADD COLUMN IF NOT EXISTS actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+		`,
+		Down: `
+			ALTER TABLE audit_log DROP COLUMN IF EXISTS actor_id;
+			DROP INDEX IF EXISTS idx_users_role;
+			ALTER TABLE users
+				DROP COLUMN IF EXISTS password_reset_required,
+				DROP COLUMN IF EXISTS suspended_at,
+				DROP COLUMN IF EXISTS role;
+			DROP TRIGGER IF EXISTS trg_roles_set_updated_at ON roles;
+			DROP TABLE IF EXISTS roles;
+		`,
+	},
+	{
+		Version: 14,
+		Name:    "create_api_keys_table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS api_keys (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				prefix VARCHAR(32) NOT NULL,
+				key_hash CHAR(64) NOT NULL,
+				scopes TEXT[] NOT NULL DEFAULT '{}',
+				expires_at TIMESTAMPTZ,
+				last_used_at TIMESTAMPTZ,
+				revoked_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			CREATE UNIQUE INDEX IF NOT EXISTS uq_api_keys_prefix ON api_keys(prefix);
+			CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_api_keys_user_id;
+			DROP INDEX IF EXISTS uq_api_keys_prefix;
+			DROP TABLE IF EXISTS api_keys;
+		`,
+	},
+	{
+		Version: 15,
+		Name:    "create_oidc_tables",
+		Up: `
+			CREATE TABLE IF NOT EXISTS user_identities (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				provider VARCHAR(50) NOT NULL,
+				subject VARCHAR(255) NOT NULL,
+				email VARCHAR(255) NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				last_login_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				CONSTRAINT uq_user_identities_subject UNIQUE (provider, subject)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+			CREATE TABLE IF NOT EXISTS oidc_login_states (
+				state_hash CHAR(64) PRIMARY KEY,
+				provider VARCHAR(50) NOT NULL,
+				code_verifier VARCHAR(128) NOT NULL,
+				nonce VARCHAR(128) NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires_at ON oidc_login_states(expires_at);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_oidc_login_states_expires_at;
+			DROP TABLE IF EXISTS oidc_login_states;
+			DROP INDEX IF EXISTS idx_user_identities_user_id;
+			DROP TABLE IF EXISTS user_identities;
+		`,
+	},
+	{
+		Version: 16,
+		Name:    "create_oauth_tables",
+		Up: `
+			CREATE TABLE IF NOT EXISTS oauth_clients (
+				id VARCHAR(64) PRIMARY KEY,
+				owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				secret_hash CHAR(64),
+				redirect_uris TEXT[] NOT NULL,
+				scopes TEXT[] NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_oauth_clients_owner_id ON oauth_clients(owner_id);
+
+			CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+				code_hash CHAR(64) PRIMARY KEY,
+				client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				redirect_uri TEXT NOT NULL,
+				scopes TEXT[] NOT NULL,
+				code_challenge VARCHAR(128) NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			CREATE TABLE IF NOT EXISTS oauth_grants (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+				scopes TEXT[] NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				CONSTRAINT uq_oauth_grants_user_client UNIQUE (user_id, client_id)
+			);
+
+			ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS client_id VARCHAR(64) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+				ADD COLUMN IF NOT EXISTS scopes TEXT[];
+
+			CREATE INDEX IF NOT EXISTS idx_sessions_client_id ON sessions(client_id) WHERE client_id IS NOT NULL;
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_sessions_client_id;
+			DELETE FROM sessions WHERE client_id IS NOT NULL;
+			ALTER TABLE sessions DROP COLUMN IF EXISTS scopes, DROP COLUMN IF EXISTS client_id;
+			DROP TABLE IF EXISTS oauth_grants;
+			DROP TABLE IF EXISTS oauth_authorization_codes;
+			DROP INDEX IF EXISTS idx_oauth_clients_owner_id;
+			DROP TABLE IF EXISTS oauth_clients;
+		`,
+	},
+	{
+		Version: 17,
+		Name:    "create_notebooks_table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS notebooks (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				parent_id BIGINT REFERENCES notebooks(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				position INTEGER NOT NULL DEFAULT 0,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				CONSTRAINT chk_notebooks_parent CHECK (parent_id <> id)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_notebooks_user_parent ON notebooks(user_id, parent_id, position);
+			CREATE INDEX IF NOT EXISTS idx_notebooks_parent_id ON notebooks(parent_id);
+
+			DROP TRIGGER IF EXISTS trg_notebooks_set_updated_at ON notebooks;
+			CREATE TRIGGER trg_notebooks_set_updated_at
+				BEFORE UPDATE ON notebooks
+				FOR EACH ROW
+				EXECUTE FUNCTION set_updated_at();
+
+			ALTER TABLE notes
+				ADD COLUMN IF NOT EXISTS notebook_id BIGINT REFERENCES notebooks(id) ON DELETE SET NULL;
+
+			CREATE INDEX IF NOT EXISTS idx_notes_notebook_id ON notes(notebook_id) WHERE notebook_id IS NOT NULL;
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_notes_notebook_id;
+			ALTER TABLE notes DROP COLUMN IF EXISTS notebook_id;
+			DROP TRIGGER IF EXISTS trg_notebooks_set_updated_at ON notebooks;
+			DROP INDEX IF EXISTS idx_notebooks_parent_id;
+			DROP INDEX IF EXISTS idx_notebooks_user_parent;
+			DROP TABLE IF EXISTS notebooks;
+		`,
+	},
+	{
+		Version: 18,
+		Name:    "create_note_shares_and_comments_tables",
+		Up: `
+			CREATE TABLE IF NOT EXISTS note_shares (
+				id BIGSERIAL PRIMARY KEY,
+				note_id BIGINT REFERENCES notes(id) ON DELETE CASCADE,
+				notebook_id BIGINT REFERENCES notebooks(id) ON DELETE CASCADE,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				role VARCHAR(20) NOT NULL CHECK (role IN ('viewer', 'commenter', 'editor')),
+				created_by BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				CONSTRAINT chk_note_shares_target CHECK ((note_id IS NULL) <> (notebook_id IS NULL)),
+				CONSTRAINT uq_note_shares_note_user UNIQUE (note_id, user_id),
+				CONSTRAINT uq_note_shares_notebook_user UNIQUE (notebook_id, user_id)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_note_shares_user_id ON note_shares(user_id);
+
+			DROP TRIGGER IF EXISTS trg_note_shares_set_updated_at ON note_shares;
+			CREATE TRIGGER trg_note_shares_set_updated_at
+				BEFORE UPDATE ON note_shares
+				FOR EACH ROW
+				EXECUTE FUNCTION set_updated_at();
+
+			CREATE TABLE IF NOT EXISTS note_comments (
+				id BIGSERIAL PRIMARY KEY,
+				note_id BIGINT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				body TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_note_comments_note_id ON note_comments(note_id, created_at);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_note_comments_note_id;
+			DROP TABLE IF EXISTS note_comments;
+			DROP TRIGGER IF EXISTS trg_note_shares_set_updated_at ON note_shares;
+			DROP INDEX IF EXISTS idx_note_shares_user_id;
+			DROP TABLE IF EXISTS note_shares;
+		`,
+	},
+	{
+		Version: 19,
+		Name:    "create_share_links_table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS share_links (
+				id BIGSERIAL PRIMARY KEY,
+				note_id BIGINT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+				created_by BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				token_hash CHAR(64) NOT NULL,
+				password_hash TEXT,
+				expires_at TIMESTAMPTZ,
+				view_count BIGINT NOT NULL DEFAULT 0,
+				last_viewed_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				CONSTRAINT uq_share_links_token_hash UNIQUE (token_hash)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_share_links_note_id ON share_links(note_id);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_share_links_note_id;
+			DROP TABLE IF EXISTS share_links;
+		`,
+	},
+	{
+		Version: 20,
+		Name:    "create_note_collab_tables",
+		Up: `
+			CREATE TABLE IF NOT EXISTS note_collab_states (
+				note_id BIGINT PRIMARY KEY REFERENCES notes(id) ON DELETE CASCADE,
+				revision BIGINT NOT NULL DEFAULT 0,
+				content TEXT NOT NULL,
+				persisted_revision BIGINT NOT NULL DEFAULT 0,
+				synced_hash CHAR(64) NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+
+			DROP TRIGGER IF EXISTS trg_note_collab_states_set_updated_at ON note_collab_states;
+			CREATE TRIGGER trg_note_collab_states_set_updated_at
+				BEFORE UPDATE ON note_collab_states
+				FOR EACH ROW
+				EXECUTE FUNCTION set_updated_at();
+
+			CREATE TABLE IF NOT EXISTS note_edits (
+				note_id BIGINT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+				revision BIGINT NOT NULL,
+				user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+				client_id VARCHAR(64) NOT NULL,
+				operation JSONB,
+				content TEXT,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				PRIMARY KEY (note_id, revision),
+				CONSTRAINT chk_note_edits_kind CHECK ((operation IS NULL) <> (content IS NULL))
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_note_edits_created_at ON note_edits(created_at);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS idx_note_edits_created_at;
+			DROP TABLE IF EXISTS note_edits;
+			DROP TRIGGER IF EXISTS trg_note_collab_states_set_updated_at ON note_collab_states;
+			DROP TABLE IF EXISTS note_collab_states;
+		`,
+	},
+	{
+		Version: 21,
+		Name:    "add_notes_version",
+		Up: `
+			ALTER TABLE notes
+				ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+			CREATE OR REPLACE FUNCTION bump_version()
+			RETURNS TRIGGER AS $$
+			BEGIN
+				NEW.version = OLD.version + 1;
+				RETURN NEW;
+			END;
+			$$ LANGUAGE plpgsql;
+
+			DROP TRIGGER IF EXISTS trg_notes_bump_version ON notes;
+			CREATE TRIGGER trg_notes_bump_version
+				BEFORE UPDATE ON notes
+				FOR EACH ROW
+				EXECUTE FUNCTION bump_version();
+		`,
+		Down: `
+			DROP TRIGGER IF EXISTS trg_notes_bump_version ON notes;
+			DROP FUNCTION IF EXISTS bump_version;
+			ALTER TABLE notes DROP COLUMN IF EXISTS version;
+		`,
+	},
+	{
+		Version: 22,
+		Name:    "add_oauth_codes_redirect_uri_given",
+		Up: `
+			ALTER TABLE oauth_authorization_codes
+				ADD COLUMN IF NOT EXISTS redirect_uri_given BOOLEAN NOT NULL DEFAULT false;
+		`,
+		Down: `
+			ALTER TABLE oauth_authorization_codes DROP COLUMN IF EXISTS redirect_uri_given;
+		`,
+	},
 }
 
 func createMigrationsTable(db *sql.DB) error {
@@ -0,0 +1,289 @@
+// Package ot implements operational transformation for plain text. It uses
+// the operation format of ot.js, so browser editors can speak it directly:
+// an operation is a JSON array whose items are retains (positive numbers),
+// deletes (negative numbers) and inserts (strings). Positions and lengths
+// count UTF-16 code units, as JavaScript strings do.
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"unicode/utf16"
+
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+// maxLen bounds the documents operations read from JSON apply to and
+// produce. A note's content, limited in bytes, is never longer in UTF-16
+// code units.
+const maxLen = validator.MaxNoteContentLength
+
+var (
+	ErrInvalidOperation = errors.New("invalid operation")
+	// ErrLengthMismatch is returned when an operation does not span the
+	// whole document it is applied to, or two operations that should be
+	// concurrent do not start from the same document.
+	ErrLengthMismatch = errors.New("operation does not match the document length")
+)
+
+// component is one step of an operation: it retains, deletes or inserts.
+// Exactly one field is set.
+type component struct {
+	retain int
+	delete int
+	insert string
+}
+
+func (c component) isRetain() bool { return c.retain > 0 }
+func (c component) isDelete() bool { return c.delete > 0 }
+func (c component) isInsert() bool { return c.insert != "" }
+
+// Operation is a sequence of components that walks over a whole document.
+// The zero value is the empty operation, which applies to the empty document.
+type Operation struct {
+	ops []component
+	// baseLen is the length of the documents the operation applies to and
+	// targetLen the length of the result, both in UTF-16 code units.
+	baseLen   int
+	targetLen int
+}
+
+// BaseLen is the length of the documents o applies to.
+func (o *Operation) BaseLen() int { return o.baseLen }
+
+// TargetLen is the length of the documents o produces.
+func (o *Operation) TargetLen() int { return o.targetLen }
+
+// IsNoop reports whether o leaves documents unchanged.
+func (o *Operation) IsNoop() bool {
+	return len(o.ops) == 0 || len(o.ops) == 1 && o.ops[0].isRetain()
+}
+
+// Retain skips over n code units.
+func (o *Operation) Retain(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.baseLen += n
+	o.targetLen += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isRetain() {
+		o.ops[last].retain += n
+	} else {
+		o.ops = append(o.ops, component{retain: n})
+	}
+	return o
+}
+
+// Insert inserts s at the current position. Inserts are kept ahead of an
+// adjacent delete so that equal operations have equal components.
+func (o *Operation) Insert(s string) *Operation {
+	if s == "" {
+		return o
+	}
+	o.targetLen += length(s)
+	last := len(o.ops) - 1
+	switch {
+	case last >= 0 && o.ops[last].isInsert():
+		o.ops[last].insert += s
+	case last >= 0 && o.ops[last].isDelete():
+		if last > 0 && o.ops[last-1].isInsert() {
+			o.ops[last-1].insert += s
+		} else {
+			o.ops = append(o.ops, o.ops[last])
+			o.ops[last] = component{insert: s}
+		}
+	default:
+		o.ops = append(o.ops, component{insert: s})
+	}
+	return o
+}
+
+// Delete removes n code units at the current position.
+func (o *Operation) Delete(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.baseLen += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isDelete() {
+		o.ops[last].delete += n
+	} else {
+		o.ops = append(o.ops, component{delete: n})
+	}
+	return o
+}
+
+// Apply returns doc with o applied to it.
+func (o *Operation) Apply(doc string) (string, error) {
+	src := utf16.Encode([]rune(doc))
+	if len(src) != o.baseLen {
+		return "", ErrLengthMismatch
+	}
+
+	dst := make([]uint16, 0, o.targetLen)
+	pos := 0
+	for _, c := range o.ops {
+		// Components must not cut a character encoded as a surrogate pair
+		// in half.
+		if splitsPair(src, pos) {
+			return "", ErrInvalidOperation
+		}
+		switch {
+		case c.isRetain():
+			if c.retain > len(src)-pos {
+				return "", ErrInvalidOperation
+			}
+			dst = append(dst, src[pos:pos+c.retain]...)
+			pos += c.retain
+		case c.isDelete():
+			if c.delete > len(src)-pos {
+				return "", ErrInvalidOperation
+			}
+			pos += c.delete
+		default:
+			dst = append(dst, utf16.Encode([]rune(c.insert))...)
+		}
+	}
+	return string(utf16.Decode(dst)), nil
+}
+
+// Transform takes two operations a and b made concurrently on the same
+// document and returns a' and b' such that applying a then b' gives the same
+// document as applying b then a'. When both insert at the same position, a's
+// insert ends up first.
+func Transform(a, b *Operation) (*Operation, *Operation, error) {
+	if a.baseLen != b.baseLen {
+		return nil, nil, ErrLengthMismatch
+	}
+
+	aPrime, bPrime := &Operation{}, &Operation{}
+	as, bs := a.ops, b.ops
+	var ac, bc component
+	next := func(ops *[]component, c *component) {
+		if len(*ops) == 0 {
+			*c = component{}
+			return
+		}
+		*c, *ops = (*ops)[0], (*ops)[1:]
+	}
+	next(&as, &ac)
+	next(&bs, &bc)
+
+	for {
+		aDone, bDone := ac == component{}, bc == component{}
+		if aDone && bDone {
+			return aPrime, bPrime, nil
+		}
+
+		if ac.isInsert() {
+			aPrime.Insert(ac.insert)
+			bPrime.Retain(length(ac.insert))
+			next(&as, &ac)
+			continue
+		}
+		if bc.isInsert() {
+			aPrime.Retain(length(bc.insert))
+			bPrime.Insert(bc.insert)
+			next(&bs, &bc)
+			continue
+		}
+		if aDone || bDone {
+			return nil, nil, ErrLengthMismatch
+		}
+
+		n := min(ac.retain+ac.delete, bc.retain+bc.delete)
+		switch {
+		case ac.isRetain() && bc.isRetain():
+			aPrime.Retain(n)
+			bPrime.Retain(n)
+		case ac.isDelete() && bc.isRetain():
+			aPrime.Delete(n)
+		case ac.isRetain() && bc.isDelete():
+			bPrime.Delete(n)
+		}
+		// When both delete the same range there is nothing left to do.
+
+		if consume(&ac, n) {
+			next(&as, &ac)
+		}
+		if consume(&bc, n) {
+			next(&bs, &bc)
+		}
+	}
+}
+
+// consume shortens a retain or delete by n and reports whether it is used up.
+func consume(c *component, n int) bool {
+	if c.isRetain() {
+		c.retain -= n
+		return c.retain == 0
+	}
+	c.delete -= n
+	return c.delete == 0
+}
+
+func (o *Operation) MarshalJSON() ([]byte, error) {
+	items := make([]any, 0, len(o.ops))
+	for _, c := range o.ops {
+		switch {
+		case c.isRetain():
+			items = append(items, c.retain)
+		case c.isDelete():
+			items = append(items, -c.delete)
+		default:
+			items = append(items, c.insert)
+		}
+	}
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON reads an operation, rejecting any that applies to or
+// produces documents longer than a note may be.
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return ErrInvalidOperation
+	}
+
+	*o = Operation{}
+	for _, item := range items {
+		var s string
+		if err := json.Unmarshal(item, &s); err == nil {
+			if s == "" || length(s) > maxLen-o.targetLen {
+				return ErrInvalidOperation
+			}
+			o.Insert(s)
+			continue
+		}
+		var n int
+		if err := json.Unmarshal(item, &n); err != nil || n == 0 || n > maxLen || n < -maxLen {
+			return ErrInvalidOperation
+		}
+		if n > 0 {
+			if n > maxLen-o.baseLen || n > maxLen-o.targetLen {
+				return ErrInvalidOperation
+			}
+			o.Retain(n)
+		} else {
+			if -n > maxLen-o.baseLen {
+				return ErrInvalidOperation
+			}
+			o.Delete(-n)
+		}
+	}
+	return nil
+}
+
+// length returns the length of s in UTF-16 code units.
+func length(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// splitsPair reports whether position i of s falls between the two halves
+// of a surrogate pair.
+func splitsPair(s []uint16, i int) bool {
+	return i > 0 && i < len(s) && s[i-1] >= 0xd800 && s[i-1] < 0xdc00
+}
@@ -0,0 +1,168 @@
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, s string) *Operation {
+	t.Helper()
+	var o Operation
+	if err := json.Unmarshal([]byte(s), &o); err != nil {
+		t.Fatalf("unmarshal %s: %v", s, err)
+	}
+	return &o
+}
+
+func apply(t *testing.T, o *Operation, doc string) string {
+	t.Helper()
+	out, err := o.Apply(doc)
+	if err != nil {
+		t.Fatalf("apply %v to %q: %v", o.ops, doc, err)
+	}
+	return out
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		op      string
+		want    string
+		wantErr error
+	}{
+		{"empty", "", `[]`, "", nil},
+		{"insert into empty", "", `["hello"]`, "hello", nil},
+		{"retain all", "abc", `[3]`, "abc", nil},
+		{"insert in the middle", "abc", `[1,"X",2]`, "aXbc", nil},
+		{"delete", "abcdef", `[1,-3,2]`, "aef", nil},
+		{"replace", "abc", `[1,"X",-1,1]`, "aXc", nil},
+		{"delete all", "abc", `[-3]`, "", nil},
+		{"surrogate pair counts as two", "a😀b", `[1,-2,1]`, "ab", nil},
+		{"insert after surrogate pair", "😀", `[2,"é"]`, "😀é", nil},
+		{"too short", "abc", `[2]`, "", ErrLengthMismatch},
+		{"too long", "abc", `[4]`, "", ErrLengthMismatch},
+		{"retain splits surrogate pair", "😀b", `[1,"X",2]`, "", ErrInvalidOperation},
+		{"delete splits surrogate pair", "a😀", `[2,-1]`, "", ErrInvalidOperation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(t, tt.op).Apply(tt.doc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformConverges(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		a, b string
+		want string
+	}{
+		{"independent inserts", "abc", `["X",3]`, `[3,"Y"]`, "XabcY"},
+		{"inserts at the same position put a first", "abc", `[1,"X",2]`, `[1,"Y",2]`, "aXYbc"},
+		{"inserts into the empty document", "", `["X"]`, `["Y"]`, "XY"},
+		{"insert and delete elsewhere", "abcdef", `[1,"X",5]`, `[3,-2,1]`, "aXbcf"},
+		{"insert inside a deleted range", "abcdef", `[1,-4,1]`, `[3,"X",3]`, "aXf"},
+		{"overlapping deletes", "abcdef", `[1,-3,2]`, `[2,-3,1]`, "af"},
+		{"same delete", "abcdef", `[1,-3,2]`, `[1,-3,2]`, "aef"},
+		{"nested deletes", "abcdef", `[-6]`, `[2,-2,2]`, ""},
+		{"replace against replace", "abc", `[1,"X",-1,1]`, `[1,"Y",-1,1]`, "aXYc"},
+		{"surrogate pairs", "a😀b", `[3,"X",1]`, `[1,-2,"🎉",1]`, "a🎉Xb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := parse(t, tt.a), parse(t, tt.b)
+			aPrime, bPrime, err := Transform(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ab := apply(t, bPrime, apply(t, a, tt.doc))
+			ba := apply(t, aPrime, apply(t, b, tt.doc))
+			if ab != tt.want || ba != tt.want {
+				t.Fatalf("a then b' = %q, b then a' = %q, want %q", ab, ba, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformLengthMismatch(t *testing.T) {
+	if _, _, err := Transform(parse(t, `[3]`), parse(t, `[4]`)); !errors.Is(err, ErrLengthMismatch) {
+		t.Fatalf("err = %v, want %v", err, ErrLengthMismatch)
+	}
+}
+
+func TestInsertKeptAheadOfDelete(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(o *Operation)
+		want  string
+	}{
+		{"delete then insert", func(o *Operation) { o.Delete(2).Insert("x") }, `["x",-2]`},
+		{"inserts around a delete", func(o *Operation) { o.Retain(1).Insert("a").Delete(1).Insert("b") }, `[1,"ab",-1]`},
+		{"merged components", func(o *Operation) { o.Retain(1).Retain(2).Delete(1).Delete(1) }, `[3,-2]`},
+		{"empty components are dropped", func(o *Operation) { o.Retain(0).Insert("").Delete(0) }, `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o Operation
+			tt.build(&o)
+			got, err := json.Marshal(&o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+			if again := parse(t, tt.want); again.baseLen != o.baseLen || again.targetLen != o.targetLen {
+				t.Fatalf("lengths after round trip = %d/%d, want %d/%d", again.baseLen, again.targetLen, o.baseLen, o.targetLen)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSONBounds(t *testing.T) {
+	n := strconv.Itoa
+	long := `"` + strings.Repeat("x", maxLen) + `"`
+	tests := []struct {
+		name string
+		json string
+		ok   bool
+	}{
+		{"longest retain", `[` + n(maxLen) + `]`, true},
+		{"longest insert", `[` + long + `]`, true},
+		{"longest delete", `[` + n(-maxLen) + `]`, true},
+		{"retain too long", `[` + n(maxLen+1) + `]`, false},
+		{"delete too long", `[` + n(-maxLen-1) + `]`, false},
+		{"retains add up too long", `[` + n(maxLen) + `,"x",1]`, false},
+		{"deletes add up too long", `[` + n(-maxLen) + `,"x",-1]`, false},
+		{"inserts add up too long", `[` + long + `,1,"x"]`, false},
+		{"huge number", `[1e30]`, false},
+		{"zero", `[0]`, false},
+		{"fraction", `[1.5]`, false},
+		{"empty insert", `[""]`, false},
+		{"not an array", `{"retain":1}`, false},
+		{"nested array", `[[1]]`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o Operation
+			err := json.Unmarshal([]byte(tt.json), &o)
+			if tt.ok && err != nil {
+				t.Fatalf("err = %v, want none", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidOperation) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidOperation)
+			}
+		})
+	}
+}
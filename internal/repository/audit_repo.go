@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type AuditRepo struct {
+	db *sql.DB
+}
+
+func NewAuditRepo(db *sql.DB) *AuditRepo {
+	return &AuditRepo{
+		db: db,
+	}
+}
+
+func (r *AuditRepo) Record(ctx context.Context, entry *domain.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (user_id, actor_id, action, ip, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return conn(ctx, r.db).QueryRowContext(ctx, query, entry.UserID, entry.ActorID, entry.Action, entry.IP, entry.Details).
+		Scan(&entry.ID, &entry.CreatedAt)
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+// CollabRepo stores the documents of collaborative editing sessions and
+// their edit logs. The state row of a note serializes edits to it across API
+// instances: edits lock it with LockState inside a transaction.
+type CollabRepo struct {
+	db *sql.DB
+}
+
+func NewCollabRepo(db *sql.DB) *CollabRepo {
+	return &CollabRepo{
+		db: db,
+	}
+}
+
+// EnsureState starts a collaborative document from the note's content
+// unless the note already has one.
+func (r *CollabRepo) EnsureState(ctx context.Context, noteID uint64) error {
+	query := `
+		INSERT INTO note_collab_states (note_id, content, synced_hash)
+		SELECT id, content, encode(sha256(convert_to(content, 'UTF8')), 'hex') FROM notes
+		WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (note_id) DO NOTHING
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, noteID)
+	return err
+}
+
+// LockState loads the note's collaborative document and locks it until the
+// transaction ends. It returns ErrNoteNotFound when the note has no document
+// or is in the trash.
+func (r *CollabRepo) LockState(ctx context.Context, noteID uint64) (*domain.CollabState, error) {
+	query := `
+		SELECT s.note_id, s.revision, s.content, s.persisted_revision, s.synced_hash, n.content
+		FROM note_collab_states s
+		JOIN notes n ON n.id = s.note_id
+		WHERE s.note_id = $1 AND n.deleted_at IS NULL
+		FOR UPDATE OF s
+	`
+	var s domain.CollabState
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, noteID).Scan(
+		&s.NoteID, &s.Revision, &s.Content, &s.PersistedRevision, &s.SyncedHash, &s.NoteContent,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNoteNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *CollabRepo) SaveState(ctx context.Context, s *domain.CollabState) error {
+	query := `
+		UPDATE note_collab_states
+		SET revision = $1, content = $2, persisted_revision = $3, synced_hash = $4
+		WHERE note_id = $5
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		s.Revision, s.Content, s.PersistedRevision, s.SyncedHash, s.NoteID)
+	return err
+}
+
+func (r *CollabRepo) AppendEdit(ctx context.Context, e *domain.NoteEdit) error {
+	query := `
+		INSERT INTO note_edits (note_id, revision, user_id, client_id, operation, content)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6)
+		RETURNING created_at
+	`
+	operation := sql.NullString{String: string(e.Operation), Valid: e.Operation != nil}
+	content := sql.NullString{String: e.Content, Valid: e.IsReset()}
+	return conn(ctx, r.db).QueryRowContext(ctx, query,
+		e.NoteID, e.Revision, e.UserID, e.ClientID, operation, content,
+	).Scan(&e.CreatedAt)
+}
+
+// ListEditsSince returns the note's logged edits after revision, oldest
+// first. The log is pruned, so it may start later than revision+1.
+func (r *CollabRepo) ListEditsSince(ctx context.Context, noteID uint64, revision int64) ([]*domain.NoteEdit, error) {
+	query := `
+		SELECT note_id, revision, COALESCE(user_id, 0), client_id, operation, COALESCE(content, ''), created_at
+		FROM note_edits
+		WHERE note_id = $1 AND revision > $2
+		ORDER BY revision
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, noteID, revision)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := make([]*domain.NoteEdit, 0)
+	for rows.Next() {
+		var e domain.NoteEdit
+		var operation []byte
+		if err := rows.Scan(&e.NoteID, &e.Revision, &e.UserID, &e.ClientID, &operation, &e.Content, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Operation = operation
+		edits = append(edits, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// Notify publishes payload on channel to every API instance listening. Inside
+// a transaction the notification is only delivered once it commits.
+func (r *CollabRepo) Notify(ctx context.Context, channel, payload string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, payload)
+	return err
+}
+
+// DeleteEditsBefore prunes edit log entries older than retention. Documents
+// are kept, so that revisions keep counting up for clients still connected.
+func (r *CollabRepo) DeleteEditsBefore(ctx context.Context, retention time.Duration) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM note_edits WHERE created_at < now() - make_interval(secs => $1)`,
+		retention.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
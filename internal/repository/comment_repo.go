@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type CommentRepo struct {
+	db *sql.DB
+}
+
+func NewCommentRepo(db *sql.DB) *CommentRepo {
+	return &CommentRepo{
+		db: db,
+	}
+}
+
+func (r *CommentRepo) Create(ctx context.Context, c *domain.NoteComment) error {
+	query := `
+		INSERT INTO note_comments (note_id, user_id, body)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, (SELECT username FROM users WHERE id = $2)
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, c.NoteID, c.UserID, c.Body).
+		Scan(&c.ID, &c.CreatedAt, &c.Username)
+	if err != nil {
+		if isForeignKeyViolation(err, "note_comments_note_id_fkey") {
+			return domain.ErrNoteNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *CommentRepo) GetByID(ctx context.Context, noteID, id uint64) (*domain.NoteComment, error) {
+	query := `
+		SELECT c.id, c.note_id, c.user_id, u.username, c.body, c.created_at
+		FROM note_comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1 AND c.note_id = $2
+	`
+	var c domain.NoteComment
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id, noteID).Scan(
+		&c.ID, &c.NoteID, &c.UserID, &c.Username, &c.Body, &c.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrCommentNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListByNote returns a note's comments, oldest first.
+func (r *CommentRepo) ListByNote(ctx context.Context, noteID uint64, limit, offset int) ([]*domain.NoteComment, int64, error) {
+	var total int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM note_comments WHERE note_id = $1`, noteID,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT c.id, c.note_id, c.user_id, u.username, c.body, c.created_at
+		FROM note_comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.note_id = $1
+		ORDER BY c.created_at, c.id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, noteID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	comments := make([]*domain.NoteComment, 0, limit)
+	for rows.Next() {
+		var c domain.NoteComment
+		if err := rows.Scan(&c.ID, &c.NoteID, &c.UserID, &c.Username, &c.Body, &c.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		comments = append(comments, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return comments, total, nil
+}
+
+func (r *CommentRepo) Delete(ctx context.Context, id uint64) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM note_comments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrCommentNotFound
+	}
+	return nil
+}
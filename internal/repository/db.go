@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// dbtx is the subset of *sql.DB and *sql.Tx used by the repositories, so
+// helpers can run either standalone or as part of a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// withTx runs fn in a transaction, joining the one carried by ctx if any.
+func withTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context, q dbtx) error) error {
+	return NewTxManager(db).WithinTransaction(ctx, func(ctx context.Context) error {
+		return fn(ctx, conn(ctx, db))
+	})
+}
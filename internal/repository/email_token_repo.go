@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type EmailTokenRepo struct {
+	db *sql.DB
+}
+
+func NewEmailTokenRepo(db *sql.DB) *EmailTokenRepo {
+	return &EmailTokenRepo{
+		db: db,
+	}
+}
+
+// Create stores a token, discarding the user's earlier unused tokens of the
+// same purpose so only the latest mailed link works.
+func (r *EmailTokenRepo) Create(ctx context.Context, token *domain.EmailToken) error {
+	return withTx(ctx, r.db, func(ctx context.Context, q dbtx) error {
+		if _, err := q.ExecContext(ctx,
+			`DELETE FROM email_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+			token.UserID, token.Purpose,
+		); err != nil {
+			return err
+		}
+		query := `
+			INSERT INTO email_tokens (user_id, purpose, email, token_hash, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		`
+		return q.QueryRowContext(ctx, query, token.UserID, token.Purpose, token.Email, token.TokenHash, token.ExpiresAt).
+			Scan(&token.ID, &token.CreatedAt)
+	})
+}
+
+// GetByHashForUpdate loads a token of the given purpose and locks it so it
+// can be used only once.
+func (r *EmailTokenRepo) GetByHashForUpdate(ctx context.Context, purpose domain.EmailTokenPurpose, hash string) (*domain.EmailToken, error) {
+	query := `
+		SELECT id, user_id, purpose, email, token_hash, expires_at, used_at, created_at
+		FROM email_tokens
+		WHERE token_hash = $1 AND purpose = $2
+		FOR UPDATE
+	`
+	var t domain.EmailToken
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, hash, purpose).Scan(
+		&t.ID, &t.UserID, &t.Purpose, &t.Email, &t.TokenHash, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *EmailTokenRepo) MarkUsed(ctx context.Context, id uint64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE email_tokens SET used_at = now() WHERE id = $1`, id)
+	return err
+}
+
+// DeleteExpired removes used and expired tokens.
+func (r *EmailTokenRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM email_tokens WHERE expires_at < now() OR used_at IS NOT NULL`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
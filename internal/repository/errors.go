@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+// isUniqueViolation reports whether err is a Postgres unique violation on the
+// given constraint or index. An empty name matches any unique violation.
+func isUniqueViolation(err error, name string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != pgUniqueViolation {
+		return false
+	}
+	return name == "" || pqErr.Constraint == name
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key
+// violation on the given constraint. An empty name matches any.
+func isForeignKeyViolation(err error, name string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != pgForeignKeyViolation {
+		return false
+	}
+	return name == "" || pqErr.Constraint == name
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type IdentityRepo struct {
+	db *sql.DB
+}
+
+func NewIdentityRepo(db *sql.DB) *IdentityRepo {
+	return &IdentityRepo{
+		db: db,
+	}
+}
+
+// Create links an identity to a user. It fails with ErrConflict if the
+// identity is already linked, e.g. by a concurrent first login.
+func (r *IdentityRepo) Create(ctx context.Context, identity *domain.Identity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, last_login_at
+	`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		identity.UserID, identity.Provider, identity.Subject, identity.Email,
+	).Scan(&identity.ID, &identity.CreatedAt, &identity.LastLoginAt); err != nil {
+		if isUniqueViolation(err, "uq_user_identities_subject") {
+			return domain.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *IdentityRepo) GetBySubject(ctx context.Context, provider, subject string) (*domain.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+	var i domain.Identity
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, provider, subject).Scan(
+		&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &i.LastLoginAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+// TouchLogin records a login with the identity and the email address the
+// provider reported for it.
+func (r *IdentityRepo) TouchLogin(ctx context.Context, id uint64, email string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE user_identities SET email = $1, last_login_at = now() WHERE id = $2`, email, id)
+	return err
+}
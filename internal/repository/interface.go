@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/maqsatto/Notes-API/internal/domain"
 )
@@ -14,22 +15,45 @@ import (
 // 	ErrInvalidData       = errors.New("invalid data")
 // )
 
-// type Transactor interface {
-// 	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
-// }
+// Transactor runs fn atomically. Repository calls made with the context
+// passed to fn take part in the same transaction.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// LoginAttemptStore keeps failed login counters. Keys identify an account or
+// a client address. MemoryLoginAttemptStore suits a single instance;
+// LoginAttemptRepo shares the counters between instances.
+type LoginAttemptStore interface {
+	// Get returns the state of key, zero valued if there is none.
+	Get(ctx context.Context, key string) (*domain.LoginAttempts, error)
+	// RecordFailure counts a failure and returns the new state. The count
+	// restarts when the previous failure is older than window.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempts, error)
+	// Lock blocks key until the given time and restarts its count.
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	// DeleteStale forgets keys without failures within window and without
+	// an active lock.
+	DeleteStale(ctx context.Context, window time.Duration) (int64, error)
+}
 
 type noteRepository interface {
 	Create(ctx context.Context, note *domain.Note) error
 	Update(ctx context.Context, note *domain.Note) error
-	SoftDelete(ctx context.Context, id uint64) error
-	HardDelete(ctx context.Context, id uint64) error
+	SoftDelete(ctx context.Context, id uint64, version int64) error
+	SoftDeleteByUserID(ctx context.Context, userID uint64) error
+	HardDelete(ctx context.Context, id uint64, version int64) error
+	Move(ctx context.Context, id uint64, notebookID *uint64) error
 
 	GetByID(ctx context.Context, id uint64) (*domain.Note, error)
-	ListByUserID(ctx context.Context, userID uint64, limit, offset int) ([]*domain.Note, int64, error)
-	ListByTags(ctx context.Context, userID uint64, tags []string, limit, offset int) ([]*domain.Note, int64, error)
-	SearchByTitle(ctx context.Context, userID uint64, titleQuery string, limit, offset int) ([]*domain.Note, int64, error)
-	SearchByContent(ctx context.Context, userID uint64, contentQuery string, limit, offset int) ([]*domain.Note, int64, error)
+	ListByUserID(ctx context.Context, userID uint64, notebookIDs []uint64, limit, offset int) ([]*domain.Note, int64, error)
+	ListByTags(ctx context.Context, userID uint64, tags []string, notebookIDs []uint64, limit, offset int) ([]*domain.Note, int64, error)
+	SearchByTitle(ctx context.Context, userID uint64, titleQuery string, notebookIDs []uint64, limit, offset int) ([]*domain.Note, int64, error)
+	SearchByContent(ctx context.Context, userID uint64, contentQuery string, notebookIDs []uint64, limit, offset int) ([]*domain.Note, int64, error)
+	ListSharedWith(ctx context.Context, userID uint64, limit, offset int) ([]*domain.SharedNote, int64, error)
 
+	AccessLevel(ctx context.Context, userID, noteID uint64) (domain.NoteAccess, error)
 	CountByUserID(ctx context.Context, userID uint64) (int64, error)
 }
 
@@ -53,9 +77,72 @@ type userRepository interface {
 	Count(ctx context.Context) (uint64, error)
 }
 
-// type TagRepository interface {
-// 	GetOrCreateTags(ctx context.Context, tagNames []string) ([]int64, error)
-// 	ListPopularTags(ctx context.Context, limit int) ([]string, error)
-// 	ListUserTags(ctx context.Context, userID uint64) ([]string, error)
-// 	DeleteUnusedTags(ctx context.Context) error
-// }
+type tagRepository interface {
+	GetOrCreateTags(ctx context.Context, userID uint64, tagNames []string) ([]uint64, error)
+	GetByName(ctx context.Context, userID uint64, name string) (*domain.Tag, error)
+	ListPopularTags(ctx context.Context, userID uint64, limit int) ([]*domain.Tag, error)
+	ListUserTags(ctx context.Context, userID uint64) ([]*domain.Tag, error)
+	Rename(ctx context.Context, userID uint64, oldName, newName string) (bool, error)
+	DeleteUnusedTags(ctx context.Context) error
+}
+
+type notebookRepository interface {
+	LockTree(ctx context.Context, userID uint64) error
+	AccessLevel(ctx context.Context, userID, notebookID uint64) (domain.NoteAccess, error)
+	Create(ctx context.Context, nb *domain.Notebook) error
+	GetByID(ctx context.Context, id uint64) (*domain.Notebook, error)
+	ListByUserID(ctx context.Context, userID uint64) ([]*domain.Notebook, error)
+	SubtreeIDs(ctx context.Context, id uint64) ([]uint64, error)
+	Rename(ctx context.Context, nb *domain.Notebook) error
+	Move(ctx context.Context, nb *domain.Notebook, parentID *uint64, position int) error
+	DeleteMovingContents(ctx context.Context, nb *domain.Notebook) error
+	DeleteTrashingContents(ctx context.Context, nb *domain.Notebook, subtree []uint64) (int64, error)
+}
+
+type shareRepository interface {
+	Grant(ctx context.Context, share *domain.Share) (bool, error)
+	ListByNote(ctx context.Context, noteID uint64) ([]*domain.Share, error)
+	ListByNotebook(ctx context.Context, notebookID uint64) ([]*domain.Share, error)
+	RevokeNote(ctx context.Context, noteID, userID uint64) error
+	RevokeNotebook(ctx context.Context, notebookID, userID uint64) error
+	ListNotebooksSharedWith(ctx context.Context, userID uint64) ([]*domain.SharedNotebook, error)
+}
+
+type commentRepository interface {
+	Create(ctx context.Context, c *domain.NoteComment) error
+	GetByID(ctx context.Context, noteID, id uint64) (*domain.NoteComment, error)
+	ListByNote(ctx context.Context, noteID uint64, limit, offset int) ([]*domain.NoteComment, int64, error)
+	Delete(ctx context.Context, id uint64) error
+}
+
+type shareLinkRepository interface {
+	Create(ctx context.Context, link *domain.ShareLink) error
+	GetActiveByTokenHash(ctx context.Context, tokenHash string) (*domain.ShareLink, error)
+	ListActiveByNote(ctx context.Context, noteID uint64) ([]*domain.ShareLink, error)
+	RecordView(ctx context.Context, id uint64) (int64, error)
+	Delete(ctx context.Context, noteID, id uint64) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type collabRepository interface {
+	EnsureState(ctx context.Context, noteID uint64) error
+	LockState(ctx context.Context, noteID uint64) (*domain.CollabState, error)
+	SaveState(ctx context.Context, s *domain.CollabState) error
+	AppendEdit(ctx context.Context, e *domain.NoteEdit) error
+	ListEditsSince(ctx context.Context, noteID uint64, revision int64) ([]*domain.NoteEdit, error)
+	Notify(ctx context.Context, channel, payload string) error
+	DeleteEditsBefore(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+var (
+	_ Transactor          = (*TxManager)(nil)
+	_ LoginAttemptStore   = (*LoginAttemptRepo)(nil)
+	_ LoginAttemptStore   = (*MemoryLoginAttemptStore)(nil)
+	_ noteRepository      = (*NoteRepo)(nil)
+	_ tagRepository       = (*TagRepo)(nil)
+	_ notebookRepository  = (*NotebookRepo)(nil)
+	_ shareRepository     = (*ShareRepo)(nil)
+	_ commentRepository   = (*CommentRepo)(nil)
+	_ shareLinkRepository = (*ShareLinkRepo)(nil)
+	_ collabRepository    = (*CollabRepo)(nil)
+)
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+// MemoryLoginAttemptStore keeps login counters in process. Counters are lost
+// on restart and not shared between instances.
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]domain.LoginAttempts
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		attempts: make(map[string]domain.LoginAttempts),
+	}
+}
+
+func (s *MemoryLoginAttemptStore) Get(ctx context.Context, key string) (*domain.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.attempts[key]
+	if !ok {
+		a = domain.LoginAttempts{Key: key}
+	}
+	return &a, nil
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	a, ok := s.attempts[key]
+	if !ok || a.LastFailureAt.Before(now.Add(-window)) {
+		a = domain.LoginAttempts{Key: key, LockedUntil: a.LockedUntil}
+	}
+	a.Failures++
+	a.LastFailureAt = now
+	s.attempts[key] = a
+	return &a, nil
+}
+
+func (s *MemoryLoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.attempts[key]
+	a.Key = key
+	a.Failures = 0
+	a.LockedUntil = &until
+	if a.LastFailureAt.IsZero() {
+		a.LastFailureAt = time.Now()
+	}
+	s.attempts[key] = a
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) DeleteStale(ctx context.Context, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var n int64
+	for key, a := range s.attempts {
+		if a.LastFailureAt.Before(now.Add(-window)) && (a.LockedUntil == nil || a.LockedUntil.Before(now)) {
+			delete(s.attempts, key)
+			n++
+		}
+	}
+	return n, nil
+}
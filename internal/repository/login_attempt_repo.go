@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+// LoginAttemptRepo is the Postgres LoginAttemptStore.
+type LoginAttemptRepo struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepo(db *sql.DB) *LoginAttemptRepo {
+	return &LoginAttemptRepo{
+		db: db,
+	}
+}
+
+func (r *LoginAttemptRepo) Get(ctx context.Context, key string) (*domain.LoginAttempts, error) {
+	query := `SELECT key, failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1`
+	var a domain.LoginAttempts
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, key).Scan(
+		&a.Key, &a.Failures, &a.LastFailureAt, &a.LockedUntil,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &domain.LoginAttempts{Key: key}, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *LoginAttemptRepo) RecordFailure(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempts, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, now())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < now() - make_interval(secs => $2) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = now()
+		RETURNING key, failures, last_failure_at, locked_until
+	`
+	var a domain.LoginAttempts
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, key, window.Seconds()).Scan(
+		&a.Key, &a.Failures, &a.LastFailureAt, &a.LockedUntil,
+	); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *LoginAttemptRepo) Lock(ctx context.Context, key string, until time.Time) error {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at, locked_until)
+		VALUES ($1, 0, now(), $2)
+		ON CONFLICT (key) DO UPDATE SET failures = 0, locked_until = $2
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, key, until)
+	return err
+}
+
+func (r *LoginAttemptRepo) Reset(ctx context.Context, key string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM login_attempts WHERE key = $1`, key)
+	return err
+}
+
+func (r *LoginAttemptRepo) DeleteStale(ctx context.Context, window time.Duration) (int64, error) {
+	query := `
+		DELETE FROM login_attempts
+		WHERE last_failure_at < now() - make_interval(secs => $1)
+		  AND (locked_until IS NULL OR locked_until < now())
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, window.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+// Access to notes is decided here, for single notes and listings alike. A
+// user's access to a note is the highest of
+//
+//   - AccessOwner for their own notes,
+//   - the role of a share of the note with them,
+//   - the role of a share with them of the notebook the note is in, or of any
+//     notebook that one is nested in.
+//
+// The SQL below computes access levels as domain.NoteAccess values.
+
+// shareAccess converts the role of a share aliased s to its access level.
+const shareAccess = `CASE s.role WHEN 'editor' THEN 3 WHEN 'commenter' THEN 2 ELSE 1 END`
+
+// noteAccessCTEs defines for the user $1 the CTE access(note_id, access),
+// holding every note they can see, trashed or not, with their access level.
+// Queries start with "WITH RECURSIVE " + noteAccessCTEs and join notes to it.
+const noteAccessCTEs = `
+		shared_notebooks AS (
+			SELECT s.notebook_id AS id, ` + shareAccess + ` AS access
+			FROM note_shares s
+			WHERE s.user_id = $1 AND s.notebook_id IS NOT NULL
+			UNION
+			SELECT c.id, sn.access
+			FROM notebooks c JOIN shared_notebooks sn ON c.parent_id = sn.id
+		),
+		access AS (
+			SELECT g.note_id, MAX(g.access) AS access
+			FROM (
+				SELECT n.id AS note_id, 4 AS access
+				FROM notes n
+				WHERE n.user_id = $1
+				UNION ALL
+				SELECT s.note_id, ` + shareAccess + `
+				FROM note_shares s
+				WHERE s.user_id = $1 AND s.note_id IS NOT NULL
+				UNION ALL
+				SELECT n.id, sn.access
+				FROM notes n JOIN shared_notebooks sn ON sn.id = n.notebook_id
+			) g
+			GROUP BY g.note_id
+		)`
+
+// scopeFilter keeps the notes filed in one of the notebooks passed as $2, or
+// the user's own notes when $2 is NULL. It expects the notes table to be
+// aliased as n and joined to access as a.
+const scopeFilter = `(CASE WHEN $2::bigint[] IS NULL THEN a.access = 4 ELSE n.notebook_id = ANY($2) END)`
+
+// AccessLevel returns the user's access to the note, whether or not it is in
+// the trash.
+func (r *NoteRepo) AccessLevel(ctx context.Context, userID, noteID uint64) (domain.NoteAccess, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT nb.id, nb.parent_id
+			FROM notes n JOIN notebooks nb ON nb.id = n.notebook_id
+			WHERE n.id = $2
+			UNION
+			SELECT p.id, p.parent_id
+			FROM notebooks p JOIN ancestors an ON p.id = an.parent_id
+		)
+		SELECT CASE WHEN n.user_id = $1 THEN 4 ELSE COALESCE((
+			SELECT MAX(` + shareAccess + `)
+			FROM note_shares s
+			WHERE s.user_id = $1
+			  AND (s.note_id = n.id OR s.notebook_id IN (SELECT id FROM ancestors))
+		), 0) END
+		FROM notes n
+		WHERE n.id = $2
+	`
+	var access domain.NoteAccess
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID, noteID).Scan(&access); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.AccessNone, domain.ErrNoteNotFound
+		}
+		return domain.AccessNone, err
+	}
+	return access, nil
+}
+
+// AccessLevel returns the access the user has to the notes in the notebook:
+// AccessOwner for their own notebooks, otherwise the highest role shared with
+// them on it or on a notebook it is nested in.
+func (r *NotebookRepo) AccessLevel(ctx context.Context, userID, notebookID uint64) (domain.NoteAccess, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM notebooks WHERE id = $2
+			UNION
+			SELECT p.id, p.parent_id
+			FROM notebooks p JOIN ancestors an ON p.id = an.parent_id
+		)
+		SELECT CASE WHEN nb.user_id = $1 THEN 4 ELSE COALESCE((
+			SELECT MAX(` + shareAccess + `)
+			FROM note_shares s
+			WHERE s.user_id = $1 AND s.notebook_id IN (SELECT id FROM ancestors)
+		), 0) END
+		FROM notebooks nb
+		WHERE nb.id = $2
+	`
+	var access domain.NoteAccess
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID, notebookID).Scan(&access); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.AccessNone, domain.ErrNotebookNotFound
+		}
+		return domain.AccessNone, err
+	}
+	return access, nil
+}
@@ -3,10 +3,20 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/maqsatto/Notes-API/internal/domain"
 )
 
+// noteTagsColumn selects a note's tag names from the join table. It expects
+// the notes table to be aliased as n.
+const noteTagsColumn = `ARRAY(
+			SELECT t.name FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+			WHERE nt.note_id = n.id ORDER BY t.name
+		) AS tags`
+
 type NoteRepo struct {
 	db *sql.DB
 }
@@ -19,162 +29,325 @@ func NewNoteRepo(db *sql.DB) *NoteRepo {
 
 func (r *NoteRepo) Create(ctx context.Context, note *domain.Note) error {
 
-	query := `INSERT INTO notes (title, content, user_id, tags)
-			  VALUES ($1, $2, $3, $4)
+	query := `INSERT INTO notes (title, content, user_id, notebook_id, search_language)
+			  VALUES ($1, $2, $3, $4, (SELECT search_language FROM users WHERE id = $3))
 			  RETURNING id, created_at, updated_at`
 
-	if err := r.db.QueryRowContext(ctx, query, note.Title, note.Content, note.UserID, note.Tags).
-		Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt); err != nil {
-		return err
-	}
-	return nil
+	return withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		if err := tx.QueryRowContext(ctx, query, note.Title, note.Content, note.UserID, note.NotebookID).
+			Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt); err != nil {
+			if isForeignKeyViolation(err, "notes_notebook_id_fkey") {
+				return domain.ErrNotebookNotFound
+			}
+			return err
+		}
+		if err := setNoteTags(ctx, tx, note.UserID, note.ID, note.Tags); err != nil {
+			return err
+		}
+		return insertRevision(ctx, tx, note)
+	})
 }
 
+// Update saves the note's title, content and tags if it is still at
+// note.Version, and sets note.Version to the new version. It fails with
+// ErrNoteVersionMismatch when the note was changed since it was read.
 func (r *NoteRepo) Update(ctx context.Context, note *domain.Note) error {
 
-	query := `UPDATE notes SET title = $1, content = $2, tags = $3
-             WHERE id = $4 AND deleted_at IS NULL RETURNING updated_at`
+	query := `UPDATE notes SET title = $1, content = $2
+             WHERE id = $3 AND deleted_at IS NULL AND version = $4 RETURNING user_id, updated_at, version`
 
-	if err := r.db.QueryRowContext(ctx, query, note.Title, note.Content, note.Tags, note.ID).
-		Scan(&note.UpdatedAt); err != nil {
-		return err
-	}
-
-	return nil
+	return withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		if err := tx.QueryRowContext(ctx, query, note.Title, note.Content, note.ID, note.Version).
+			Scan(&note.UserID, &note.UpdatedAt, &note.Version); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return staleNoteError(ctx, tx, note.ID)
+			}
+			return err
+		}
+		if err := setNoteTags(ctx, tx, note.UserID, note.ID, note.Tags); err != nil {
+			return err
+		}
+		return insertRevision(ctx, tx, note)
+	})
 }
 
-func (r *NoteRepo) SoftDelete(ctx context.Context, id uint64) error {
+// SoftDelete moves the note to the trash if it is still at version. It fails
+// with ErrNoteVersionMismatch when the note was changed since it was read.
+func (r *NoteRepo) SoftDelete(ctx context.Context, id uint64, version int64) error {
 
 	query := `UPDATE notes SET deleted_at = now()
-             WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at`
+             WHERE id = $1 AND deleted_at IS NULL AND version = $2 RETURNING deleted_at`
+
+	return withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		var deletedAt time.Time
+		if err := tx.QueryRowContext(ctx, query, id, version).Scan(&deletedAt); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return staleNoteError(ctx, tx, id)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *NoteRepo) SoftDeleteByUserID(ctx context.Context, userID uint64) error {
+	query := `UPDATE notes SET deleted_at = now()
+             WHERE user_id = $1 AND deleted_at IS NULL`
 
-	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID); err != nil {
 		return err
 	}
-
 	return nil
 }
 
-func (r *NoteRepo) HardDelete(ctx context.Context, id uint64) error {
-	query := `DELETE FROM notes WHERE id = $1`
-	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
-		return err
-	}
-	return nil
+// RestoreByUserID undeletes the notes that were deleted together with their
+// owner's account at deletedAt, leaving notes trashed earlier in the trash.
+func (r *NoteRepo) RestoreByUserID(ctx context.Context, userID uint64, deletedAt time.Time) error {
+	query := `UPDATE notes SET deleted_at = NULL WHERE user_id = $1 AND deleted_at = $2`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID, deletedAt)
+	return err
 }
 
-func (r *NoteRepo) GetByID(ctx context.Context, id uint64) (*domain.Note, error) {
+// HardDelete removes the note, in the trash or not, if it is still at
+// version. It fails with ErrNoteVersionMismatch when the note was changed
+// since it was read.
+func (r *NoteRepo) HardDelete(ctx context.Context, id uint64, version int64) error {
+	query := `DELETE FROM notes WHERE id = $1 AND version = $2 RETURNING user_id`
+	return withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		var userID uint64
+		if err := tx.QueryRowContext(ctx, query, id, version).Scan(&userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return staleDeletedNoteError(ctx, tx, id)
+			}
+			return err
+		}
+		return deleteUnusedUserTags(ctx, tx, userID)
+	})
+}
 
-	query := `SELECT id, user_id, title, content, created_at, updated_at, tags FROM notes
-                WHERE id = $1 AND deleted_at IS NULL`
+// GetWithDeleted returns a note whether or not it is in the trash.
+func (r *NoteRepo) GetWithDeleted(ctx context.Context, id uint64) (*domain.Note, error) {
+	query := `SELECT n.id, n.user_id, n.notebook_id, n.title, n.content, n.created_at, n.updated_at, n.deleted_at, n.version, ` + noteTagsColumn + `
+		FROM notes n
+		WHERE n.id = $1`
 
 	var note domain.Note
-	if err := r.db.QueryRowContext(ctx, query, id).Scan(&note.ID, &note.UserID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.Tags); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+		&note.ID, &note.UserID, &note.NotebookID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.DeletedAt, &note.Version, pq.Array(&note.Tags),
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNoteNotFound
+		}
 		return nil, err
 	}
-
 	return &note, nil
 }
-func (r *NoteRepo) ListByUserID(
-	ctx context.Context,
-	userID uint64,
-	limit, offset int,
-) ([]*domain.Note, int64, error) {
-
-	countQuery := `
-		SELECT COUNT(*)
-		FROM notes
-		WHERE user_id = $1
-		  AND deleted_at IS NULL
-	`
 
+// ListDeletedByUserID lists the user's trash, most recently deleted first.
+func (r *NoteRepo) ListDeletedByUserID(ctx context.Context, userID uint64, limit, offset int) ([]*domain.Note, int64, error) {
+	countQuery := `SELECT COUNT(*) FROM notes WHERE user_id = $1 AND deleted_at IS NOT NULL`
 	var total int64
-	if err := r.db.QueryRowContext(ctx, countQuery, userID).Scan(&total); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, userID).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	dataQuery := `
-		SELECT id, user_id, title, content, created_at, updated_at, tags
-		FROM notes
-		WHERE user_id = $1 AND deleted_at IS NULL
-		ORDER BY created_at DESC
+		SELECT n.id, n.user_id, n.notebook_id, n.title, n.content, n.created_at, n.updated_at, n.deleted_at, n.version, ` + noteTagsColumn + `
+		FROM notes n
+		WHERE n.user_id = $1 AND n.deleted_at IS NOT NULL
+		ORDER BY n.deleted_at DESC
 		LIMIT $2 OFFSET $3
 	`
-
-	rows, err := r.db.QueryContext(ctx, dataQuery, userID, limit, offset)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, dataQuery, userID, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 
 	notes := make([]*domain.Note, 0, limit)
-
 	for rows.Next() {
 		var note domain.Note
 		if err := rows.Scan(
 			&note.ID,
 			&note.UserID,
+			&note.NotebookID,
 			&note.Title,
 			&note.Content,
 			&note.CreatedAt,
 			&note.UpdatedAt,
-			&note.Tags,
+			&note.DeletedAt,
+			&note.Version,
+			pq.Array(&note.Tags),
 		); err != nil {
 			return nil, 0, err
 		}
-
 		notes = append(notes, &note)
 	}
-
 	if err := rows.Err(); err != nil {
 		return nil, 0, err
 	}
-
 	return notes, total, nil
 }
 
-func (r *NoteRepo) ListByTags(ctx context.Context, userID uint64, tags []string, limit, offset int) ([]*domain.Note, int64, error) {
-	dataQuery := `
-		SELECT id, user_id, title, content, created_at, updated_at, tags
-		FROM notes
-		WHERE user_id = $1
-		AND deleted_at IS NULL
-		AND tags @> $2
-		ORDER BY created_at DESC
-		LIMIT $3 OFFSET $4
+// Restore takes a note out of the trash.
+func (r *NoteRepo) Restore(ctx context.Context, id uint64) error {
+	query := `UPDATE notes SET deleted_at = NULL
+             WHERE id = $1 AND deleted_at IS NOT NULL RETURNING updated_at`
+
+	var updatedAt time.Time
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&updatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrNoteNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// EmptyTrash permanently deletes all of the user's trashed notes.
+func (r *NoteRepo) EmptyTrash(ctx context.Context, userID uint64) (int64, error) {
+	var n int64
+	err := withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM notes WHERE user_id = $1 AND deleted_at IS NOT NULL`, userID)
+		if err != nil {
+			return err
+		}
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		return deleteUnusedUserTags(ctx, tx, userID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// PurgeDeleted permanently deletes notes that have been in the trash for
+// longer than retention, along with tags left unused.
+func (r *NoteRepo) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	var n int64
+	err := withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		res, err := tx.ExecContext(ctx,
+			`DELETE FROM notes WHERE deleted_at < now() - make_interval(secs => $1)`,
+			retention.Seconds(),
+		)
+		if err != nil {
+			return err
+		}
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		return deleteUnusedTags(ctx, tx)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (r *NoteRepo) GetByID(ctx context.Context, id uint64) (*domain.Note, error) {
+
+	query := `SELECT n.id, n.user_id, n.notebook_id, n.title, n.content, n.created_at, n.updated_at, n.version, ` + noteTagsColumn + `
+		FROM notes n
+		WHERE n.id = $1 AND n.deleted_at IS NULL`
+
+	var note domain.Note
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&note.ID, &note.UserID, &note.NotebookID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.Version, pq.Array(&note.Tags)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNoteNotFound
+		}
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+// ListByUserID lists the user's own notes, newest first. A non-nil
+// notebookIDs lists the notes filed in one of those notebooks instead, which
+// may be shared with the user.
+func (r *NoteRepo) ListByUserID(
+	ctx context.Context,
+	userID uint64,
+	notebookIDs []uint64,
+	limit, offset int,
+) ([]*domain.Note, int64, error) {
+
+	countQuery := `WITH RECURSIVE ` + noteAccessCTEs + `
+		SELECT COUNT(*)
+		FROM notes n
+		JOIN access a ON a.note_id = n.id
+		WHERE n.deleted_at IS NULL
+		  AND ` + scopeFilter + `
 	`
-	countQuery := `	SELECT COUNT(*)
-					FROM notes
-					WHERE user_id = $1
-					AND deleted_at IS NULL
-					AND tags @> $2`
+
 	var total int64
-	if err := r.db.QueryRowContext(ctx, countQuery, userID, tags).Scan(&total); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, userID, pq.Array(notebookIDs)).Scan(&total); err != nil {
 		return nil, 0, err
 	}
-	rows, err := r.db.QueryContext(ctx, dataQuery, userID, tags, limit, offset)
+
+	dataQuery := `WITH RECURSIVE ` + noteAccessCTEs + `
+		SELECT n.id, n.user_id, n.notebook_id, n.title, n.content, n.created_at, n.updated_at, n.version, ` + noteTagsColumn + `
+		FROM notes n
+		JOIN access a ON a.note_id = n.id
+		WHERE n.deleted_at IS NULL
+		  AND ` + scopeFilter + `
+		ORDER BY n.created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, dataQuery, userID, pq.Array(notebookIDs), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	notes, err := scanNotes(rows, limit)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer rows.Close()
 
-	notes := make([]*domain.Note, 0, limit)
+	return notes, total, nil
+}
 
-	for rows.Next() {
-		var note domain.Note
-		if err := rows.Scan(
-			&note.ID,
-			&note.UserID,
-			&note.Title,
-			&note.Content,
-			&note.CreatedAt,
-			&note.UpdatedAt,
-			&note.Tags); err != nil {
-			return nil, 0, err
-		}
-		notes = append(notes, &note)
+// ListByTags returns the notes ListByUserID would, keeping those carrying
+// every one of the given tags.
+func (r *NoteRepo) ListByTags(ctx context.Context, userID uint64, tags []string, notebookIDs []uint64, limit, offset int) ([]*domain.Note, int64, error) {
+	// tags belong to the note's owner, who need not be the user
+	tagFilter := `
+		n.id IN (
+			SELECT nt.note_id
+			FROM note_tags nt
+			JOIN tags t ON t.id = nt.tag_id
+			WHERE t.name = ANY($3)
+			GROUP BY nt.note_id
+			HAVING COUNT(*) = cardinality($3)
+		)`
+	dataQuery := `WITH RECURSIVE ` + noteAccessCTEs + `
+		SELECT n.id, n.user_id, n.notebook_id, n.title, n.content, n.created_at, n.updated_at, n.version, ` + noteTagsColumn + `
+		FROM notes n
+		JOIN access a ON a.note_id = n.id
+		WHERE n.deleted_at IS NULL
+		AND ` + scopeFilter + `
+		AND ` + tagFilter + `
+		ORDER BY n.created_at DESC
+		LIMIT $4 OFFSET $5
+	`
+	countQuery := `WITH RECURSIVE ` + noteAccessCTEs + `
+					SELECT COUNT(*)
+					FROM notes n
+					JOIN access a ON a.note_id = n.id
+					WHERE n.deleted_at IS NULL
+					AND ` + scopeFilter + `
+					AND ` + tagFilter
+	var total int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, userID, pq.Array(notebookIDs), pq.Array(tags)).Scan(&total); err != nil {
+		return nil, 0, err
 	}
-	if err := rows.Err(); err != nil {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, dataQuery, userID, pq.Array(notebookIDs), pq.Array(tags), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	notes, err := scanNotes(rows, limit)
+	if err != nil {
 		return nil, 0, err
 	}
 
@@ -185,122 +358,199 @@ func (r *NoteRepo) SearchByTitle(
 	ctx context.Context,
 	userID uint64,
 	titleQuery string,
+	notebookIDs []uint64,
 	limit, offset int,
 ) ([]*domain.Note, int64, error) {
+	return r.searchColumn(ctx, userID, "title", titleQuery, notebookIDs, limit, offset)
+}
 
-	search := "%" + titleQuery + "%"
+func (r *NoteRepo) SearchByContent(ctx context.Context, userID uint64, contentQuery string, notebookIDs []uint64, limit, offset int) ([]*domain.Note, int64, error) {
+	return r.searchColumn(ctx, userID, "content", contentQuery, notebookIDs, limit, offset)
+}
 
-	countQuery := `
+// searchColumn matches a substring of the title or content column.
+func (r *NoteRepo) searchColumn(ctx context.Context, userID uint64, column, query string, notebookIDs []uint64, limit, offset int) ([]*domain.Note, int64, error) {
+	search := "%" + escapeLike(query) + "%"
+
+	countQuery := `WITH RECURSIVE ` + noteAccessCTEs + `
 		SELECT COUNT(*)
-		FROM notes
-		WHERE user_id = $1
-		  AND deleted_at IS NULL
-		  AND title ILIKE $2
+		FROM notes n
+		JOIN access a ON a.note_id = n.id
+		WHERE n.deleted_at IS NULL
+		  AND ` + scopeFilter + `
+		  AND n.` + column + ` ILIKE $3
 	`
 
 	var total int64
-	if err := r.db.QueryRowContext(ctx, countQuery, userID, search).Scan(&total); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, userID, pq.Array(notebookIDs), search).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	dataQuery := `
-		SELECT id, user_id, title, content, created_at, updated_at, tags
-		FROM notes
-		WHERE user_id = $1
-		  AND deleted_at IS NULL
-		  AND title ILIKE $2
-		ORDER BY created_at DESC
-		LIMIT $3 OFFSET $4
+	dataQuery := `WITH RECURSIVE ` + noteAccessCTEs + `
+		SELECT n.id, n.user_id, n.notebook_id, n.title, n.content, n.created_at, n.updated_at, n.version, ` + noteTagsColumn + `
+		FROM notes n
+		JOIN access a ON a.note_id = n.id
+		WHERE n.deleted_at IS NULL
+		  AND ` + scopeFilter + `
+		  AND n.` + column + ` ILIKE $3
+		ORDER BY n.created_at DESC
+		LIMIT $4 OFFSET $5
 	`
 
-	rows, err := r.db.QueryContext(ctx, dataQuery, userID, search, limit, offset)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, dataQuery, userID, pq.Array(notebookIDs), search, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer rows.Close()
-
-	notes := make([]*domain.Note, 0, limit)
-
-	for rows.Next() {
-		note := new(domain.Note)
-		if err := rows.Scan(
-			&note.ID,
-			&note.UserID,
-			&note.Title,
-			&note.Content,
-			&note.CreatedAt,
-			&note.UpdatedAt,
-			&note.Tags,
-		); err != nil {
-			return nil, 0, err
-		}
-		notes = append(notes, note)
-	}
-
-	if err := rows.Err(); err != nil {
+	notes, err := scanNotes(rows, limit)
+	if err != nil {
 		return nil, 0, err
 	}
 
 	return notes, total, nil
 }
 
-func (r *NoteRepo) SearchByContent(ctx context.Context, userID uint64, contentQuery string, limit, offset int) ([]*domain.Note, int64, error) {
-	countQuery := `
+// ListSharedWith lists the live notes other users shared with the user,
+// directly or through a notebook, most recently updated first.
+func (r *NoteRepo) ListSharedWith(ctx context.Context, userID uint64, limit, offset int) ([]*domain.SharedNote, int64, error) {
+	countQuery := `WITH RECURSIVE ` + noteAccessCTEs + `
 		SELECT COUNT(*)
-		FROM notes
-		WHERE user_id = $1
-		AND deleted_at IS NULL
-		AND content ILIKE $2
+		FROM notes n
+		JOIN access a ON a.note_id = n.id
+		WHERE n.deleted_at IS NULL AND a.access < 4
 	`
-	search := "%" + contentQuery + "%"
 	var total int64
-	if err := r.db.QueryRowContext(ctx, countQuery, userID, search).Scan(&total); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, userID).Scan(&total); err != nil {
 		return nil, 0, err
 	}
-	dataQuery := `
-		SELECT id, user_id, title, content, created_at, updated_at, tags
-		FROM notes
-		WHERE user_id = $1
-		  AND deleted_at IS NULL
-		  AND content ILIKE $2
-		ORDER BY created_at DESC
-		LIMIT $3 OFFSET $4
+
+	dataQuery := `WITH RECURSIVE ` + noteAccessCTEs + `
+		SELECT n.id, n.user_id, n.notebook_id, n.title, n.content, n.created_at, n.updated_at, n.version, ` + noteTagsColumn + `,
+			a.access, u.username
+		FROM notes n
+		JOIN access a ON a.note_id = n.id
+		JOIN users u ON u.id = n.user_id
+		WHERE n.deleted_at IS NULL AND a.access < 4
+		ORDER BY n.updated_at DESC, n.id
+		LIMIT $2 OFFSET $3
 	`
-	rows, err := r.db.QueryContext(ctx, dataQuery, userID, search, limit, offset)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, dataQuery, userID, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 
-	notes := make([]*domain.Note, 0, limit)
+	notes := make([]*domain.SharedNote, 0, limit)
 	for rows.Next() {
-		var note domain.Note
+		var (
+			note   domain.SharedNote
+			access domain.NoteAccess
+		)
 		if err := rows.Scan(
 			&note.ID,
 			&note.UserID,
+			&note.NotebookID,
 			&note.Title,
 			&note.Content,
 			&note.CreatedAt,
 			&note.UpdatedAt,
-			&note.Tags,
+			&note.Version,
+			pq.Array(&note.Tags),
+			&access,
+			&note.OwnerUsername,
 		); err != nil {
 			return nil, 0, err
 		}
-
+		note.Role = access.Role()
 		notes = append(notes, &note)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, 0, err
 	}
-
 	return notes, total, nil
 }
 
+// Move files a note in a notebook, or in none when notebookID is nil.
+func (r *NoteRepo) Move(ctx context.Context, id uint64, notebookID *uint64) error {
+	query := `UPDATE notes SET notebook_id = $1
+             WHERE id = $2 AND deleted_at IS NULL RETURNING updated_at`
+
+	var updatedAt time.Time
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, notebookID, id).Scan(&updatedAt); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return domain.ErrNoteNotFound
+		case isForeignKeyViolation(err, "notes_notebook_id_fkey"):
+			return domain.ErrNotebookNotFound
+		}
+		return err
+	}
+	return nil
+}
+
 func (r *NoteRepo) CountByUserID(ctx context.Context, userID uint64) (int64, error) {
 	query := `SELECT COUNT(*) FROM notes WHERE user_id = $1 AND deleted_at IS NULL`
 	var count int64
-	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
 		return 0, err
 	}
 	return count, nil
 }
+
+// staleNoteError explains why a conditional write to a live note matched no
+// row: the note is gone, or it is at another version.
+func staleNoteError(ctx context.Context, q dbtx, id uint64) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM notes WHERE id = $1 AND deleted_at IS NULL)`, id,
+	).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrNoteNotFound
+	}
+	return domain.ErrNoteVersionMismatch
+}
+
+// staleDeletedNoteError is staleNoteError for writes that also apply to
+// notes in the trash.
+func staleDeletedNoteError(ctx context.Context, q dbtx, id uint64) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM notes WHERE id = $1)`, id,
+	).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrNoteNotFound
+	}
+	return domain.ErrNoteVersionMismatch
+}
+
+// scanNotes reads rows selected with the id, user_id, notebook_id, title,
+// content, created_at, updated_at, version, tags column list and closes them.
+func scanNotes(rows *sql.Rows, capHint int) ([]*domain.Note, error) {
+	defer rows.Close()
+
+	notes := make([]*domain.Note, 0, capHint)
+	for rows.Next() {
+		var note domain.Note
+		if err := rows.Scan(
+			&note.ID,
+			&note.UserID,
+			&note.NotebookID,
+			&note.Title,
+			&note.Content,
+			&note.CreatedAt,
+			&note.UpdatedAt,
+			&note.Version,
+			pq.Array(&note.Tags),
+		); err != nil {
+			return nil, err
+		}
+		notes = append(notes, &note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
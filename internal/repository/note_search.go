@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+const (
+	titleHeadlineOptions   = `HighlightAll=true, StartSel=<mark>, StopSel=</mark>`
+	contentHeadlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=3, FragmentDelimiter=" ... "`
+)
+
+// Search runs a full-text query over the user's own notes using the user's
+// search language and orders the matches by ts_rank. A non-nil notebookIDs
+// searches the notes filed in one of those notebooks instead, which may be
+// shared with the user.
+//
+// The query uses web search syntax ("quoted phrases", -negation, or), and
+// words ending in * are matched as prefixes.
+func (r *NoteRepo) Search(ctx context.Context, userID uint64, query string, notebookIDs []uint64, limit, offset int) ([]*domain.NoteSearchResult, int64, error) {
+	args := []any{userID, pq.Array(notebookIDs)}
+	tsquery, ok := buildTSQuery(query, &args)
+	if !ok {
+		return nil, 0, domain.ErrInvalidSearchQuery
+	}
+
+	withQuery := `
+		WITH RECURSIVE ` + noteAccessCTEs + `,
+		q AS (
+			SELECT ` + tsquery + ` AS query
+			FROM users u
+			WHERE u.id = $1
+		)`
+
+	countQuery := withQuery + `
+		SELECT COUNT(*)
+		FROM notes n
+		JOIN access a ON a.note_id = n.id
+		CROSS JOIN q
+		WHERE n.deleted_at IS NULL
+		  AND ` + scopeFilter + `
+		  AND n.search_vector @@ q.query
+	`
+	var total int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	dataQuery := withQuery + fmt.Sprintf(`
+		SELECT n.id, n.user_id, n.notebook_id, n.title, n.content, n.created_at, n.updated_at, n.version, `+noteTagsColumn+`,
+			ts_rank(n.search_vector, q.query) AS rank,
+			ts_headline(n.search_language, n.title, q.query, '%s'),
+			ts_headline(n.search_language, n.content, q.query, '%s')
+		FROM notes n
+		JOIN access a ON a.note_id = n.id
+		CROSS JOIN q
+		WHERE n.deleted_at IS NULL
+		  AND `+scopeFilter+`
+		  AND n.search_vector @@ q.query
+		ORDER BY rank DESC, n.updated_at DESC
+		LIMIT $%d OFFSET $%d
+	`, titleHeadlineOptions, contentHeadlineOptions, len(args)+1, len(args)+2)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, dataQuery, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := make([]*domain.NoteSearchResult, 0, limit)
+	for rows.Next() {
+		var res domain.NoteSearchResult
+		if err := rows.Scan(
+			&res.ID,
+			&res.UserID,
+			&res.NotebookID,
+			&res.Title,
+			&res.Content,
+			&res.CreatedAt,
+			&res.UpdatedAt,
+			&res.Version,
+			pq.Array(&res.Tags),
+			&res.Rank,
+			&res.TitleHighlight,
+			&res.ContentHighlight,
+		); err != nil {
+			return nil, 0, err
+		}
+		results = append(results, &res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// SetSearchLanguage switches the text search configuration of all of the
+// user's notes, which regenerates their search vectors.
+func (r *NoteRepo) SetSearchLanguage(ctx context.Context, userID uint64, language string) error {
+	query := `UPDATE notes SET search_language = $1::regconfig
+             WHERE user_id = $2 AND search_language <> $1::regconfig`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, language, userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SearchLanguageExists reports whether Postgres has a text search
+// configuration with the given name.
+func (r *NoteRepo) SearchLanguageExists(ctx context.Context, language string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM pg_ts_config WHERE cfgname = $1)`
+	var exists bool
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, language).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// buildTSQuery returns an SQL tsquery expression for the raw user query,
+// appending its parameters to args. The expression refers to the user's
+// search language as u.search_language. It reports false when the query has
+// no searchable terms.
+//
+// Words ending in * are matched as prefixes with to_tsquery and the other
+// words are left to websearch_to_tsquery. The query is split on "or" first,
+// so that prefix words are combined with the words around them the way
+// websearch_to_tsquery would: AND within the groups "or" separates, OR
+// between them.
+func buildTSQuery(raw string, args *[]any) (string, bool) {
+	var groups []string
+	for _, words := range splitOr(splitSearchWords(raw)) {
+		if group, ok := buildTSGroup(words, args); ok {
+			groups = append(groups, group)
+		}
+	}
+	switch len(groups) {
+	case 0:
+		return "", false
+	case 1:
+		return groups[0], true
+	}
+	return "(" + strings.Join(groups, ") || (") + ")", true
+}
+
+// buildTSGroup returns an SQL tsquery expression matching all of words.
+func buildTSGroup(words []string, args *[]any) (string, bool) {
+	var rest, prefixes, negated []string
+	for _, word := range words {
+		p, negate, ok := prefixTerm(word)
+		switch {
+		case !ok:
+			rest = append(rest, word)
+		case negate:
+			negated = append(negated, p)
+		default:
+			prefixes = append(prefixes, p)
+		}
+	}
+
+	parts := make([]string, 0, 2+len(negated))
+	if len(rest) > 0 {
+		*args = append(*args, strings.Join(rest, " "))
+		parts = append(parts, fmt.Sprintf("websearch_to_tsquery(u.search_language, $%d)", len(*args)))
+	}
+	if len(prefixes) > 0 {
+		terms := make([]string, len(prefixes))
+		for i, p := range prefixes {
+			terms[i] = p + ":*"
+		}
+		*args = append(*args, strings.Join(terms, " & "))
+		parts = append(parts, fmt.Sprintf("to_tsquery(u.search_language, $%d)", len(*args)))
+	}
+	for _, p := range negated {
+		*args = append(*args, p+":*")
+		parts = append(parts, fmt.Sprintf("!!to_tsquery(u.search_language, $%d)", len(*args)))
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, " && "), true
+}
+
+// prefixTerm reports whether word, which may be negated with -, ends in *
+// and returns it reduced to letters and digits so that it is safe to use in
+// to_tsquery syntax. Quoted phrases are never prefix terms.
+func prefixTerm(word string) (prefix string, negate, ok bool) {
+	if strings.HasPrefix(word, `"`) {
+		return "", false, false
+	}
+	word, negate = strings.CutPrefix(word, "-")
+	if !strings.HasSuffix(word, "*") {
+		return "", false, false
+	}
+	if prefix = strings.Map(keepWordRune, word); prefix == "" {
+		return "", false, false
+	}
+	return prefix, negate, true
+}
+
+// splitSearchWords splits a web search query on white space outside of
+// quotes.
+func splitSearchWords(raw string) []string {
+	var (
+		words   []string
+		inQuote bool
+		start   = -1
+	)
+	for i, r := range raw {
+		switch {
+		case r == '"':
+			if start < 0 {
+				start = i
+			}
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			if start >= 0 {
+				words = append(words, raw[start:i])
+				start = -1
+			}
+		case start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, raw[start:])
+	}
+	return words
+}
+
+// splitOr splits words into the groups separated by the word "or", dropping
+// empty groups.
+func splitOr(words []string) [][]string {
+	var groups [][]string
+	var group []string
+	for _, word := range words {
+		if strings.EqualFold(word, "or") {
+			if len(group) > 0 {
+				groups = append(groups, group)
+			}
+			group = nil
+			continue
+		}
+		group = append(group, word)
+	}
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func keepWordRune(r rune) rune {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return unicode.ToLower(r)
+	}
+	return -1
+}
+
+// escapeLike escapes LIKE/ILIKE wildcards so user input matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+		args []any
+	}{
+		{"", "", nil},
+		{"   ", "", nil},
+		{"or", "", nil},
+		{"*", "websearch_to_tsquery(u.search_language, $3)", []any{"*"}},
+		{"meeting notes", "websearch_to_tsquery(u.search_language, $3)", []any{"meeting notes"}},
+		{`"meeting notes" -draft or agenda`,
+			"(websearch_to_tsquery(u.search_language, $3)) || (websearch_to_tsquery(u.search_language, $4))",
+			[]any{`"meeting notes" -draft`, "agenda"}},
+		{"rep*", "to_tsquery(u.search_language, $3)", []any{"rep:*"}},
+		{"Rep-ort* draft",
+			"websearch_to_tsquery(u.search_language, $3) && to_tsquery(u.search_language, $4)",
+			[]any{"draft", "report:*"}},
+		{"foo* bar*", "to_tsquery(u.search_language, $3)", []any{"foo:* & bar:*"}},
+		{"foo* or bar*",
+			"(to_tsquery(u.search_language, $3)) || (to_tsquery(u.search_language, $4))",
+			[]any{"foo:*", "bar:*"}},
+		{"report OR draft*",
+			"(websearch_to_tsquery(u.search_language, $3)) || (to_tsquery(u.search_language, $4))",
+			[]any{"report", "draft:*"}},
+		{"a b* or c",
+			"(websearch_to_tsquery(u.search_language, $3) && to_tsquery(u.search_language, $4)) || (websearch_to_tsquery(u.search_language, $5))",
+			[]any{"a", "b:*", "c"}},
+		{"report -draft*",
+			"websearch_to_tsquery(u.search_language, $3) && !!to_tsquery(u.search_language, $4)",
+			[]any{"report", "draft:*"}},
+		{`"draft*" or`, "websearch_to_tsquery(u.search_language, $3)", []any{`"draft*"`}},
+		{"x* or or y", "(to_tsquery(u.search_language, $3)) || (websearch_to_tsquery(u.search_language, $4))", []any{"x:*", "y"}},
+	}
+	for _, tt := range tests {
+		args := []any{uint64(1), nil}
+		got, ok := buildTSQuery(tt.raw, &args)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("buildTSQuery(%q) = %q, %v; want %q", tt.raw, got, ok, tt.want)
+			continue
+		}
+		if tt.args != nil && !slices.Equal(args[2:], tt.args) {
+			t.Errorf("buildTSQuery(%q) args = %q, want %q", tt.raw, args[2:], tt.args)
+		}
+	}
+}
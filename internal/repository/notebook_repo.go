@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+// notebookNoteCountColumn counts the live notes filed directly in a notebook.
+// It expects the notebooks table to be aliased as nb.
+const notebookNoteCountColumn = `(
+			SELECT COUNT(*) FROM notes n
+			WHERE n.notebook_id = nb.id AND n.deleted_at IS NULL
+		) AS note_count`
+
+type NotebookRepo struct {
+	db *sql.DB
+}
+
+func NewNotebookRepo(db *sql.DB) *NotebookRepo {
+	return &NotebookRepo{
+		db: db,
+	}
+}
+
+// LockTree serialises changes to the user's notebooks until the transaction
+// in ctx ends, so concurrent moves cannot build a cycle between them checking
+// it and committing. It locks the user's row, which note writes do not need.
+func (r *NotebookRepo) LockTree(ctx context.Context, userID uint64) error {
+	var id uint64
+	if err := conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE`, userID,
+	).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Create adds a notebook after its last sibling.
+func (r *NotebookRepo) Create(ctx context.Context, nb *domain.Notebook) error {
+	query := `
+		INSERT INTO notebooks (user_id, parent_id, name, position)
+		SELECT $1::bigint, $2::bigint, $3, COALESCE(MAX(position) + 1, 0)
+		FROM notebooks
+		WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2
+		RETURNING id, position, created_at, updated_at
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, nb.UserID, nb.ParentID, nb.Name).
+		Scan(&nb.ID, &nb.Position, &nb.CreatedAt, &nb.UpdatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err, "notebooks_parent_id_fkey") {
+			return domain.ErrNotebookNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *NotebookRepo) GetByID(ctx context.Context, id uint64) (*domain.Notebook, error) {
+	query := `
+		SELECT nb.id, nb.user_id, nb.parent_id, nb.name, nb.position, nb.created_at, nb.updated_at, ` + notebookNoteCountColumn + `
+		FROM notebooks nb
+		WHERE nb.id = $1
+	`
+	nb, err := scanNotebook(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	return nb, nil
+}
+
+// ListByUserID returns all of the user's notebooks, siblings in order.
+func (r *NotebookRepo) ListByUserID(ctx context.Context, userID uint64) ([]*domain.Notebook, error) {
+	query := `
+		SELECT nb.id, nb.user_id, nb.parent_id, nb.name, nb.position, nb.created_at, nb.updated_at, ` + notebookNoteCountColumn + `
+		FROM notebooks nb
+		WHERE nb.user_id = $1
+		ORDER BY nb.parent_id NULLS FIRST, nb.position, nb.id
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notebooks := make([]*domain.Notebook, 0)
+	for rows.Next() {
+		nb, err := scanNotebook(rows)
+		if err != nil {
+			return nil, err
+		}
+		notebooks = append(notebooks, nb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return notebooks, nil
+}
+
+// SubtreeIDs returns the ids of the notebook and of every notebook nested in
+// it.
+func (r *NotebookRepo) SubtreeIDs(ctx context.Context, id uint64) ([]uint64, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM notebooks WHERE id = $1
+			UNION
+			SELECT c.id FROM notebooks c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT id FROM subtree
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uint64, 0)
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *NotebookRepo) Rename(ctx context.Context, nb *domain.Notebook) error {
+	query := `UPDATE notebooks SET name = $1 WHERE id = $2 RETURNING updated_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, nb.Name, nb.ID).Scan(&nb.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrNotebookNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Move places the notebook, with everything in it, under parentID (nil for
+// the top level) at position among its new siblings. Positions past the end
+// append it. Callers must have checked that parentID is not inside it.
+func (r *NotebookRepo) Move(ctx context.Context, nb *domain.Notebook, parentID *uint64, position int) error {
+	return withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		// close the gap it leaves, then make room where it goes
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE notebooks SET position = position - 1
+			WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND position > $3`,
+			nb.UserID, nb.ParentID, nb.Position,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE notebooks SET position = position + 1
+			WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND position >= $3 AND id <> $4`,
+			nb.UserID, parentID, position, nb.ID,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE notebooks SET parent_id = $1, position = $2 WHERE id = $3`,
+			parentID, position, nb.ID,
+		); err != nil {
+			if isForeignKeyViolation(err, "notebooks_parent_id_fkey") {
+				return domain.ErrNotebookNotFound
+			}
+			return err
+		}
+		if err := renumberNotebooks(ctx, tx, nb.UserID, parentID); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx,
+			`SELECT parent_id, position, updated_at FROM notebooks WHERE id = $1`, nb.ID,
+		).Scan(&nb.ParentID, &nb.Position, &nb.UpdatedAt)
+	})
+}
+
+// DeleteMovingContents deletes the notebook, moving its notes and
+// sub-notebooks up into its parent. The sub-notebooks take its place among
+// the parent's children, in their order.
+func (r *NotebookRepo) DeleteMovingContents(ctx context.Context, nb *domain.Notebook) error {
+	return withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		if err := renumberNotebooks(ctx, tx, nb.UserID, &nb.ID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE notebooks SET position = position + (
+				SELECT COUNT(*) FROM notebooks WHERE parent_id = $3
+			)
+			WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND position > $4`,
+			nb.UserID, nb.ParentID, nb.ID, nb.Position,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE notebooks SET parent_id = $1, position = position + $2 WHERE parent_id = $3`,
+			nb.ParentID, nb.Position, nb.ID,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE notes SET notebook_id = $1 WHERE notebook_id = $2`,
+			nb.ParentID, nb.ID,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM notebooks WHERE id = $1`, nb.ID); err != nil {
+			return err
+		}
+		return renumberNotebooks(ctx, tx, nb.UserID, nb.ParentID)
+	})
+}
+
+// DeleteTrashingContents deletes the notebook and its sub-notebooks and moves
+// the notes in them to the trash, where they are no longer in any notebook.
+// It returns how many notes were trashed.
+func (r *NotebookRepo) DeleteTrashingContents(ctx context.Context, nb *domain.Notebook, subtree []uint64) (int64, error) {
+	var n int64
+	err := withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE notes SET deleted_at = now() WHERE notebook_id = ANY($1) AND deleted_at IS NULL`,
+			pq.Array(subtree),
+		)
+		if err != nil {
+			return err
+		}
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		// sub-notebooks go with it through ON DELETE CASCADE
+		if _, err := tx.ExecContext(ctx, `DELETE FROM notebooks WHERE id = $1`, nb.ID); err != nil {
+			return err
+		}
+		return renumberNotebooks(ctx, tx, nb.UserID, nb.ParentID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// renumberNotebooks numbers the children of parentID 0, 1, 2... in their
+// current order.
+func renumberNotebooks(ctx context.Context, q dbtx, userID uint64, parentID *uint64) error {
+	query := `
+		UPDATE notebooks nb SET position = o.position
+		FROM (
+			SELECT id, row_number() OVER (ORDER BY position, id) - 1 AS position
+			FROM notebooks
+			WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2
+		) o
+		WHERE nb.id = o.id AND nb.position <> o.position
+	`
+	_, err := q.ExecContext(ctx, query, userID, parentID)
+	return err
+}
+
+func scanNotebook(row rowScanner) (*domain.Notebook, error) {
+	var nb domain.Notebook
+	if err := row.Scan(
+		&nb.ID,
+		&nb.UserID,
+		&nb.ParentID,
+		&nb.Name,
+		&nb.Position,
+		&nb.CreatedAt,
+		&nb.UpdatedAt,
+		&nb.NoteCount,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotebookNotFound
+		}
+		return nil, err
+	}
+	return &nb, nil
+}
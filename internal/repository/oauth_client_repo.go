@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type OAuthClientRepo struct {
+	db *sql.DB
+}
+
+func NewOAuthClientRepo(db *sql.DB) *OAuthClientRepo {
+	return &OAuthClientRepo{
+		db: db,
+	}
+}
+
+const oauthClientColumns = `id, owner_id, name, COALESCE(secret_hash, ''), redirect_uris, scopes, created_at`
+
+func scanOAuthClient(row rowScanner) (*domain.OAuthClient, error) {
+	var c domain.OAuthClient
+	if err := row.Scan(
+		&c.ID, &c.OwnerID, &c.Name, &c.SecretHash, pq.Array(&c.RedirectURIs), pq.Array(&c.Scopes), &c.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *OAuthClientRepo) Create(ctx context.Context, client *domain.OAuthClient) error {
+	var secretHash any
+	if client.SecretHash != "" {
+		secretHash = client.SecretHash
+	}
+	query := `
+		INSERT INTO oauth_clients (id, owner_id, name, secret_hash, redirect_uris, scopes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+	return conn(ctx, r.db).QueryRowContext(ctx, query,
+		client.ID, client.OwnerID, client.Name, secretHash, pq.Array(client.RedirectURIs), pq.Array(client.Scopes),
+	).Scan(&client.CreatedAt)
+}
+
+func (r *OAuthClientRepo) GetByID(ctx context.Context, id string) (*domain.OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE id = $1`
+	return scanOAuthClient(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+}
+
+// ListByOwner returns the clients a user registered, newest first.
+func (r *OAuthClientRepo) ListByOwner(ctx context.Context, ownerID uint64) ([]*domain.OAuthClient, error) {
+	query := `
+		SELECT ` + oauthClientColumns + `
+		FROM oauth_clients
+		WHERE owner_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []*domain.OAuthClient
+	for rows.Next() {
+		c, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// Delete removes a client of the owner together with its grants, codes and
+// sessions.
+func (r *OAuthClientRepo) Delete(ctx context.Context, ownerID uint64, id string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM oauth_clients WHERE id = $1 AND owner_id = $2`, id, ownerID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrOAuthClientNotFound
+	}
+	return nil
+}
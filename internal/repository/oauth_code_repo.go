@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type OAuthCodeRepo struct {
+	db *sql.DB
+}
+
+func NewOAuthCodeRepo(db *sql.DB) *OAuthCodeRepo {
+	return &OAuthCodeRepo{
+		db: db,
+	}
+}
+
+func (r *OAuthCodeRepo) Create(ctx context.Context, code *domain.OAuthAuthorizationCode) error {
+	query := `
+		INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, redirect_uri_given, scopes, code_challenge, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at
+	`
+	return conn(ctx, r.db).QueryRowContext(ctx, query,
+		code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.RedirectURIGiven, pq.Array(code.Scopes), code.CodeChallenge, code.ExpiresAt,
+	).Scan(&code.CreatedAt)
+}
+
+// Consume removes and returns a code so it can be redeemed only once.
+// Unknown codes give ErrOAuthInvalidGrant.
+func (r *OAuthCodeRepo) Consume(ctx context.Context, hash string) (*domain.OAuthAuthorizationCode, error) {
+	query := `
+		DELETE FROM oauth_authorization_codes
+		WHERE code_hash = $1
+		RETURNING code_hash, client_id, user_id, redirect_uri, redirect_uri_given, scopes, code_challenge, expires_at, created_at
+	`
+	var c domain.OAuthAuthorizationCode
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, hash).Scan(
+		&c.CodeHash, &c.ClientID, &c.UserID, &c.RedirectURI, &c.RedirectURIGiven, pq.Array(&c.Scopes), &c.CodeChallenge, &c.ExpiresAt, &c.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DeleteExpired removes codes that were never redeemed.
+func (r *OAuthCodeRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM oauth_authorization_codes WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
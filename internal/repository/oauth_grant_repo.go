@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type OAuthGrantRepo struct {
+	db *sql.DB
+}
+
+func NewOAuthGrantRepo(db *sql.DB) *OAuthGrantRepo {
+	return &OAuthGrantRepo{
+		db: db,
+	}
+}
+
+const oauthGrantColumns = `g.id, g.user_id, g.client_id, c.name, g.scopes, g.created_at, g.updated_at`
+
+func scanOAuthGrant(row rowScanner) (*domain.OAuthGrant, error) {
+	var g domain.OAuthGrant
+	if err := row.Scan(
+		&g.ID, &g.UserID, &g.ClientID, &g.ClientName, pq.Array(&g.Scopes), &g.CreatedAt, &g.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrOAuthGrantNotFound
+		}
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *OAuthGrantRepo) Get(ctx context.Context, userID uint64, clientID string) (*domain.OAuthGrant, error) {
+	query := `
+		SELECT ` + oauthGrantColumns + `
+		FROM oauth_grants g
+		JOIN oauth_clients c ON c.id = g.client_id
+		WHERE g.user_id = $1 AND g.client_id = $2
+	`
+	return scanOAuthGrant(conn(ctx, r.db).QueryRowContext(ctx, query, userID, clientID))
+}
+
+// Grant adds scopes to what the user approved for the client.
+func (r *OAuthGrantRepo) Grant(ctx context.Context, userID uint64, clientID string, scopes []string) error {
+	query := `
+		INSERT INTO oauth_grants (user_id, client_id, scopes)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, client_id) DO UPDATE
+		SET scopes = ARRAY(SELECT DISTINCT unnest(oauth_grants.scopes || EXCLUDED.scopes) ORDER BY 1),
+			updated_at = now()
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID, clientID, pq.Array(scopes))
+	return err
+}
+
+// ListByUserID returns the user's authorized apps, most recently authorized
+// first.
+func (r *OAuthGrantRepo) ListByUserID(ctx context.Context, userID uint64) ([]*domain.OAuthGrant, error) {
+	query := `
+		SELECT ` + oauthGrantColumns + `
+		FROM oauth_grants g
+		JOIN oauth_clients c ON c.id = g.client_id
+		WHERE g.user_id = $1
+		ORDER BY g.updated_at DESC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []*domain.OAuthGrant
+	for rows.Next() {
+		g, err := scanOAuthGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (r *OAuthGrantRepo) Delete(ctx context.Context, userID uint64, clientID string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM oauth_grants WHERE user_id = $1 AND client_id = $2`, userID, clientID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrOAuthGrantNotFound
+	}
+	return nil
+}
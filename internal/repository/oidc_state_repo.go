@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type OIDCStateRepo struct {
+	db *sql.DB
+}
+
+func NewOIDCStateRepo(db *sql.DB) *OIDCStateRepo {
+	return &OIDCStateRepo{
+		db: db,
+	}
+}
+
+func (r *OIDCStateRepo) Create(ctx context.Context, state *domain.OIDCLoginState) error {
+	query := `
+		INSERT INTO oidc_login_states (state_hash, provider, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	return conn(ctx, r.db).QueryRowContext(ctx, query,
+		state.StateHash, state.Provider, state.CodeVerifier, state.Nonce, state.ExpiresAt,
+	).Scan(&state.CreatedAt)
+}
+
+// Consume removes and returns a login state so each can complete only one
+// login. Unknown states give ErrInvalidToken.
+func (r *OIDCStateRepo) Consume(ctx context.Context, hash string) (*domain.OIDCLoginState, error) {
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1
+		RETURNING state_hash, provider, code_verifier, nonce, expires_at, created_at
+	`
+	var s domain.OIDCLoginState
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, hash).Scan(
+		&s.StateHash, &s.Provider, &s.CodeVerifier, &s.Nonce, &s.ExpiresAt, &s.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteExpired removes logins that were abandoned at the provider.
+func (r *OIDCStateRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM oidc_login_states WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type PasswordResetRepo struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepo(db *sql.DB) *PasswordResetRepo {
+	return &PasswordResetRepo{
+		db: db,
+	}
+}
+
+// Create stores a reset token, discarding the user's earlier unused ones so
+// only the latest mailed link works.
+func (r *PasswordResetRepo) Create(ctx context.Context, token *domain.PasswordResetToken) error {
+	return withTx(ctx, r.db, func(ctx context.Context, q dbtx) error {
+		if _, err := q.ExecContext(ctx,
+			`DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL`, token.UserID,
+		); err != nil {
+			return err
+		}
+		query := `
+			INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at
+		`
+		return q.QueryRowContext(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt).
+			Scan(&token.ID, &token.CreatedAt)
+	})
+}
+
+// GetByHashForUpdate loads a token and locks it so it can be used only once.
+func (r *PasswordResetRepo) GetByHashForUpdate(ctx context.Context, hash string) (*domain.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+	var t domain.PasswordResetToken
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, hash).Scan(
+		&t.ID, &t.UserID, &t.TokenHash, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *PasswordResetRepo) MarkUsed(ctx context.Context, id uint64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE password_reset_tokens SET used_at = now() WHERE id = $1`, id)
+	return err
+}
+
+// DeleteExpired removes used and expired tokens.
+func (r *PasswordResetRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM password_reset_tokens WHERE expires_at < now() OR used_at IS NOT NULL`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type RefreshTokenRepo struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepo(db *sql.DB) *RefreshTokenRepo {
+	return &RefreshTokenRepo{
+		db: db,
+	}
+}
+
+// Create stores a refresh token. FamilyID is the session the token belongs
+// to.
+func (r *RefreshTokenRepo) Create(ctx context.Context, token *domain.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, parent_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		token.UserID, token.FamilyID, token.ParentID, token.TokenHash, token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetByHashForUpdate loads a token and locks its row until the surrounding
+// transaction ends, so concurrent refreshes with the same token serialise.
+func (r *RefreshTokenRepo) GetByHashForUpdate(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, parent_id, token_hash, expires_at, created_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+	var t domain.RefreshToken
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, hash).Scan(
+		&t.ID, &t.UserID, &t.FamilyID, &t.ParentID, &t.TokenHash, &t.ExpiresAt, &t.CreatedAt, &t.UsedAt, &t.RevokedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *RefreshTokenRepo) MarkUsed(ctx context.Context, id uint64) error {
+	query := `UPDATE refresh_tokens SET used_at = now() WHERE id = $1 AND used_at IS NULL`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrTokenReused
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, familyID)
+	return err
+}
+
+func (r *RefreshTokenRepo) RevokeAllForUser(ctx context.Context, userID uint64) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type RevisionRepo struct {
+	db *sql.DB
+}
+
+func NewRevisionRepo(db *sql.DB) *RevisionRepo {
+	return &RevisionRepo{
+		db: db,
+	}
+}
+
+// ListByNoteID returns a note's revisions, newest first.
+func (r *RevisionRepo) ListByNoteID(ctx context.Context, noteID uint64, limit, offset int) ([]*domain.NoteRevision, int64, error) {
+	countQuery := `SELECT COUNT(*) FROM note_revisions WHERE note_id = $1`
+	var total int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, noteID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	dataQuery := `
+		SELECT id, note_id, revision, title, content, tags, created_at
+		FROM note_revisions
+		WHERE note_id = $1
+		ORDER BY revision DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, dataQuery, noteID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	revisions := make([]*domain.NoteRevision, 0, limit)
+	for rows.Next() {
+		var rev domain.NoteRevision
+		if err := rows.Scan(
+			&rev.ID,
+			&rev.NoteID,
+			&rev.Revision,
+			&rev.Title,
+			&rev.Content,
+			pq.Array(&rev.Tags),
+			&rev.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		revisions = append(revisions, &rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return revisions, total, nil
+}
+
+func (r *RevisionRepo) Get(ctx context.Context, noteID uint64, revision int) (*domain.NoteRevision, error) {
+	query := `
+		SELECT id, note_id, revision, title, content, tags, created_at
+		FROM note_revisions
+		WHERE note_id = $1 AND revision = $2
+	`
+	var rev domain.NoteRevision
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, noteID, revision).Scan(
+		&rev.ID, &rev.NoteID, &rev.Revision, &rev.Title, &rev.Content, pq.Array(&rev.Tags), &rev.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// Prune deletes revisions beyond the newest keepLast of each note and those
+// older than keepDays days. Zero disables the respective rule. The newest
+// revision of a note is always kept. It returns the number of deleted rows.
+func (r *RevisionRepo) Prune(ctx context.Context, keepLast, keepDays int) (int64, error) {
+	if keepLast <= 0 && keepDays <= 0 {
+		return 0, nil
+	}
+	query := `
+		DELETE FROM note_revisions nr
+		USING (
+			SELECT id,
+				row_number() OVER (PARTITION BY note_id ORDER BY revision DESC) AS pos
+			FROM note_revisions
+		) ranked
+		WHERE nr.id = ranked.id
+		  AND ranked.pos > 1
+		  AND (
+			($1 > 0 AND ranked.pos > $1)
+			OR ($2 > 0 AND nr.created_at < now() - make_interval(days => $2))
+		  )
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, keepLast, keepDays)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// insertRevision records the note's current state as its next revision. It
+// must run in the transaction that wrote the note so the note row lock
+// serialises revision numbers.
+func insertRevision(ctx context.Context, q dbtx, note *domain.Note) error {
+	query := `
+		INSERT INTO note_revisions (note_id, revision, title, content, tags)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4
+		FROM note_revisions
+		WHERE note_id = $1
+	`
+	tags := note.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	_, err := q.ExecContext(ctx, query, note.ID, note.Title, note.Content, pq.Array(tags))
+	return err
+}
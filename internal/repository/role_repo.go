@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type RoleRepo struct {
+	db *sql.DB
+}
+
+func NewRoleRepo(db *sql.DB) *RoleRepo {
+	return &RoleRepo{
+		db: db,
+	}
+}
+
+const roleColumns = `name, description, permissions, built_in, created_at, updated_at`
+
+func scanRole(row rowScanner) (*domain.Role, error) {
+	var role domain.Role
+	if err := row.Scan(
+		&role.Name, &role.Description, pq.Array(&role.Permissions), &role.BuiltIn, &role.CreatedAt, &role.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepo) Get(ctx context.Context, name string) (*domain.Role, error) {
+	query := `SELECT ` + roleColumns + ` FROM roles WHERE name = $1`
+	return scanRole(conn(ctx, r.db).QueryRowContext(ctx, query, name))
+}
+
+func (r *RoleRepo) List(ctx context.Context) ([]*domain.Role, error) {
+	query := `SELECT ` + roleColumns + ` FROM roles ORDER BY built_in DESC, name`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*domain.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Save creates or updates a custom role. Built-in roles are left untouched
+// and yield ErrBuiltInRole.
+func (r *RoleRepo) Save(ctx context.Context, role *domain.Role) error {
+	query := `
+		INSERT INTO roles (name, description, permissions)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE
+			SET description = EXCLUDED.description, permissions = EXCLUDED.permissions
+			WHERE roles.built_in = false
+		RETURNING ` + roleColumns
+	saved, err := scanRole(conn(ctx, r.db).QueryRowContext(ctx, query, role.Name, role.Description, pq.Array(role.Permissions)))
+	if err != nil {
+		if errors.Is(err, domain.ErrRoleNotFound) {
+			return domain.ErrBuiltInRole
+		}
+		return err
+	}
+	*role = *saved
+	return nil
+}
+
+// Delete removes a custom role that no user has.
+func (r *RoleRepo) Delete(ctx context.Context, name string) error {
+	var builtIn bool
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		`DELETE FROM roles WHERE name = $1 AND built_in = false RETURNING built_in`, name,
+	).Scan(&builtIn)
+	switch {
+	case err == nil:
+		return nil
+	case isForeignKeyViolation(err, ""):
+		return domain.ErrRoleInUse
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+	if _, err := r.Get(ctx, name); err != nil {
+		return err
+	}
+	return domain.ErrBuiltInRole
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type SessionRepo struct {
+	db *sql.DB
+}
+
+func NewSessionRepo(db *sql.DB) *SessionRepo {
+	return &SessionRepo{
+		db: db,
+	}
+}
+
+const sessionColumns = `id, user_id, client_id, scopes, device, user_agent, ip,
+	created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner) (*domain.Session, error) {
+	var s domain.Session
+	if err := row.Scan(
+		&s.ID, &s.UserID, &s.ClientID, pq.Array(&s.Scopes), &s.Device, &s.UserAgent, &s.IP,
+		&s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *SessionRepo) Create(ctx context.Context, session *domain.Session) error {
+	var scopes any
+	if session.ClientID != nil {
+		scopes = pq.Array(session.Scopes)
+	}
+	query := `
+		INSERT INTO sessions (user_id, client_id, scopes, device, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, last_seen_at
+	`
+	return conn(ctx, r.db).QueryRowContext(ctx, query,
+		session.UserID, session.ClientID, scopes, session.Device, session.UserAgent, session.IP, session.ExpiresAt,
+	).Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+}
+
+// GetActive returns a live session. Revoked, expired and unknown sessions
+// yield ErrSessionNotFound.
+func (r *SessionRepo) GetActive(ctx context.Context, id string) (*domain.Session, error) {
+	id, ok := sessionID(id)
+	if !ok {
+		return nil, domain.ErrSessionNotFound
+	}
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > now()
+	`
+	return scanSession(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+}
+
+// Touch records activity on a live session and returns its owner. Revoked,
+// expired and unknown sessions yield ErrSessionNotFound.
+func (r *SessionRepo) Touch(ctx context.Context, id string) (uint64, error) {
+	id, ok := sessionID(id)
+	if !ok {
+		return 0, domain.ErrSessionNotFound
+	}
+	query := `
+		UPDATE sessions SET last_seen_at = now()
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > now()
+		RETURNING user_id
+	`
+	var userID uint64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, domain.ErrSessionNotFound
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// Extend pushes the expiry of a live session, typically on refresh, and
+// returns the session.
+func (r *SessionRepo) Extend(ctx context.Context, id string, expiresAt time.Time) (*domain.Session, error) {
+	id, ok := sessionID(id)
+	if !ok {
+		return nil, domain.ErrSessionNotFound
+	}
+	query := `
+		UPDATE sessions SET last_seen_at = now(), expires_at = $2
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > now()
+		RETURNING ` + sessionColumns
+	return scanSession(conn(ctx, r.db).QueryRowContext(ctx, query, id, expiresAt))
+}
+
+// ListActiveByUserID returns the user's live login sessions, most recently
+// used first. Sessions of OAuth clients are listed as authorized apps
+// instead.
+func (r *SessionRepo) ListActiveByUserID(ctx context.Context, userID uint64) ([]*domain.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND client_id IS NULL AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*domain.Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Revoke ends one of the user's sessions. Sessions of other users and
+// malformed IDs are reported as not found.
+func (r *SessionRepo) Revoke(ctx context.Context, userID uint64, id string) error {
+	id, ok := sessionID(id)
+	if !ok {
+		return domain.ErrSessionNotFound
+	}
+	query := `
+		UPDATE sessions SET revoked_at = now()
+		WHERE user_id = $2 AND id = $1 AND revoked_at IS NULL
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllForUser ends every live session of the user and returns their IDs.
+func (r *SessionRepo) RevokeAllForUser(ctx context.Context, userID uint64) ([]string, error) {
+	query := `
+		UPDATE sessions SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING id
+	`
+	return r.revokeMany(ctx, query, userID)
+}
+
+// RevokeForClient ends the user's sessions with an OAuth client and returns
+// their IDs.
+func (r *SessionRepo) RevokeForClient(ctx context.Context, userID uint64, clientID string) ([]string, error) {
+	query := `
+		UPDATE sessions SET revoked_at = now()
+		WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL
+		RETURNING id
+	`
+	return r.revokeMany(ctx, query, userID, clientID)
+}
+
+func (r *SessionRepo) revokeMany(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteExpired removes expired and revoked sessions together with their
+// refresh tokens. Access tokens of a deleted session are rejected just like
+// those of a revoked one.
+func (r *SessionRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM sessions WHERE expires_at < now() OR revoked_at IS NOT NULL`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// sessionID returns id, a UUID in its canonical 8-4-4-4-12 form, in lower
+// case, and reports false for anything else. Sessions are looked up with
+// id = $1 so that the primary key is used, and ids that are not UUIDs must
+// not reach the query, where the cast to uuid would fail.
+func sessionID(id string) (string, bool) {
+	if len(id) != 36 {
+		return "", false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return "", false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return "", false
+			}
+		}
+	}
+	return strings.ToLower(id), true
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type ShareLinkRepo struct {
+	db *sql.DB
+}
+
+func NewShareLinkRepo(db *sql.DB) *ShareLinkRepo {
+	return &ShareLinkRepo{
+		db: db,
+	}
+}
+
+const shareLinkColumns = `l.id, l.note_id, l.created_by, l.token_hash, COALESCE(l.password_hash, ''),
+	l.expires_at, l.view_count, l.last_viewed_at, l.created_at`
+
+func scanShareLink(row rowScanner) (*domain.ShareLink, error) {
+	var l domain.ShareLink
+	if err := row.Scan(
+		&l.ID, &l.NoteID, &l.CreatedBy, &l.TokenHash, &l.PasswordHash,
+		&l.ExpiresAt, &l.ViewCount, &l.LastViewedAt, &l.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrShareLinkNotFound
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *ShareLinkRepo) Create(ctx context.Context, link *domain.ShareLink) error {
+	query := `
+		INSERT INTO share_links (note_id, created_by, token_hash, password_hash, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING id, created_at
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		link.NoteID, link.CreatedBy, link.TokenHash, link.PasswordHash, link.ExpiresAt,
+	).Scan(&link.ID, &link.CreatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err, "share_links_note_id_fkey") {
+			return domain.ErrNoteNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetActiveByTokenHash finds a link that has not expired, to a note that is
+// not in the trash and whose owner is neither deleted nor suspended.
+func (r *ShareLinkRepo) GetActiveByTokenHash(ctx context.Context, tokenHash string) (*domain.ShareLink, error) {
+	query := `
+		SELECT ` + shareLinkColumns + `
+		FROM share_links l
+		JOIN notes n ON n.id = l.note_id
+		JOIN users u ON u.id = n.user_id
+		WHERE l.token_hash = $1
+		  AND (l.expires_at IS NULL OR l.expires_at > now())
+		  AND n.deleted_at IS NULL
+		  AND u.deleted_at IS NULL AND u.suspended_at IS NULL
+	`
+	return scanShareLink(conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash))
+}
+
+// ListActiveByNote returns the note's links that have not expired, newest
+// first.
+func (r *ShareLinkRepo) ListActiveByNote(ctx context.Context, noteID uint64) ([]*domain.ShareLink, error) {
+	query := `
+		SELECT ` + shareLinkColumns + `
+		FROM share_links l
+		WHERE l.note_id = $1 AND (l.expires_at IS NULL OR l.expires_at > now())
+		ORDER BY l.created_at DESC, l.id DESC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]*domain.ShareLink, 0)
+	for rows.Next() {
+		l, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// RecordView counts a view of the link and returns the new count.
+func (r *ShareLinkRepo) RecordView(ctx context.Context, id uint64) (int64, error) {
+	query := `
+		UPDATE share_links SET view_count = view_count + 1, last_viewed_at = now()
+		WHERE id = $1
+		RETURNING view_count
+	`
+	var views int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&views); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, domain.ErrShareLinkNotFound
+		}
+		return 0, err
+	}
+	return views, nil
+}
+
+// Delete revokes one of the note's links.
+func (r *ShareLinkRepo) Delete(ctx context.Context, noteID, id uint64) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM share_links WHERE id = $1 AND note_id = $2`, id, noteID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrShareLinkNotFound
+	}
+	return nil
+}
+
+func (r *ShareLinkRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM share_links WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
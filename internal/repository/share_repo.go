@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type ShareRepo struct {
+	db *sql.DB
+}
+
+func NewShareRepo(db *sql.DB) *ShareRepo {
+	return &ShareRepo{
+		db: db,
+	}
+}
+
+// Grant shares the note or notebook of the share with share.UserID, or
+// changes the role of the share the user already has on it. It reports
+// whether the share is new.
+func (r *ShareRepo) Grant(ctx context.Context, share *domain.Share) (bool, error) {
+	column, constraint, targetID := "note_id", "uq_note_shares_note_user", share.NoteID
+	if share.NotebookID != nil {
+		column, constraint, targetID = "notebook_id", "uq_note_shares_notebook_user", share.NotebookID
+	}
+	query := `
+		INSERT INTO note_shares (` + column + `, user_id, role, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT ON CONSTRAINT ` + constraint + `
+		DO UPDATE SET role = EXCLUDED.role
+		RETURNING id, created_by, created_at, updated_at, xmax = 0
+	`
+	var created bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, targetID, share.UserID, share.Role, share.CreatedBy).
+		Scan(&share.ID, &share.CreatedBy, &share.CreatedAt, &share.UpdatedAt, &created)
+	if err != nil {
+		switch {
+		case isForeignKeyViolation(err, "note_shares_note_id_fkey"):
+			return false, domain.ErrNoteNotFound
+		case isForeignKeyViolation(err, "note_shares_notebook_id_fkey"):
+			return false, domain.ErrNotebookNotFound
+		case isForeignKeyViolation(err, "note_shares_user_id_fkey"):
+			return false, domain.ErrUserNotFound
+		}
+		return false, err
+	}
+	return created, nil
+}
+
+// ListByNote returns the shares of a note, oldest first.
+func (r *ShareRepo) ListByNote(ctx context.Context, noteID uint64) ([]*domain.Share, error) {
+	return r.list(ctx, "note_id", noteID)
+}
+
+// ListByNotebook returns the shares of a notebook, oldest first.
+func (r *ShareRepo) ListByNotebook(ctx context.Context, notebookID uint64) ([]*domain.Share, error) {
+	return r.list(ctx, "notebook_id", notebookID)
+}
+
+func (r *ShareRepo) list(ctx context.Context, column string, id uint64) ([]*domain.Share, error) {
+	query := `
+		SELECT s.id, s.note_id, s.notebook_id, s.user_id, u.username, u.email, s.role, s.created_by, s.created_at, s.updated_at
+		FROM note_shares s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.` + column + ` = $1
+		ORDER BY s.created_at, s.id
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := make([]*domain.Share, 0)
+	for rows.Next() {
+		var s domain.Share
+		if err := rows.Scan(
+			&s.ID,
+			&s.NoteID,
+			&s.NotebookID,
+			&s.UserID,
+			&s.Username,
+			&s.Email,
+			&s.Role,
+			&s.CreatedBy,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		shares = append(shares, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// RevokeNote removes the user's share of a note.
+func (r *ShareRepo) RevokeNote(ctx context.Context, noteID, userID uint64) error {
+	return r.revoke(ctx, "note_id", noteID, userID)
+}
+
+// RevokeNotebook removes the user's share of a notebook.
+func (r *ShareRepo) RevokeNotebook(ctx context.Context, notebookID, userID uint64) error {
+	return r.revoke(ctx, "notebook_id", notebookID, userID)
+}
+
+func (r *ShareRepo) revoke(ctx context.Context, column string, id, userID uint64) error {
+	query := `DELETE FROM note_shares WHERE ` + column + ` = $1 AND user_id = $2`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrShareNotFound
+	}
+	return nil
+}
+
+// ListNotebooksSharedWith returns the notebooks other users shared with the
+// user, by name. Notebooks nested in them are shared too but not listed.
+func (r *ShareRepo) ListNotebooksSharedWith(ctx context.Context, userID uint64) ([]*domain.SharedNotebook, error) {
+	query := `
+		SELECT nb.id, nb.user_id, nb.parent_id, nb.name, nb.position, nb.created_at, nb.updated_at, ` + notebookNoteCountColumn + `,
+			s.role, u.username
+		FROM note_shares s
+		JOIN notebooks nb ON nb.id = s.notebook_id
+		JOIN users u ON u.id = nb.user_id
+		WHERE s.user_id = $1 AND u.deleted_at IS NULL
+		ORDER BY nb.name, nb.id
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notebooks := make([]*domain.SharedNotebook, 0)
+	for rows.Next() {
+		var nb domain.SharedNotebook
+		if err := rows.Scan(
+			&nb.ID,
+			&nb.UserID,
+			&nb.ParentID,
+			&nb.Name,
+			&nb.Position,
+			&nb.CreatedAt,
+			&nb.UpdatedAt,
+			&nb.NoteCount,
+			&nb.Role,
+			&nb.OwnerUsername,
+		); err != nil {
+			return nil, err
+		}
+		notebooks = append(notebooks, &nb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return notebooks, nil
+}
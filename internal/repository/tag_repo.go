@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type TagRepo struct {
+	db *sql.DB
+}
+
+func NewTagRepo(db *sql.DB) *TagRepo {
+	return &TagRepo{
+		db: db,
+	}
+}
+
+func (r *TagRepo) GetOrCreateTags(ctx context.Context, userID uint64, tagNames []string) ([]uint64, error) {
+	return getOrCreateTags(ctx, conn(ctx, r.db), userID, tagNames)
+}
+
+func (r *TagRepo) GetByName(ctx context.Context, userID uint64, name string) (*domain.Tag, error) {
+	query := `
+		SELECT t.id, t.user_id, t.name, t.created_at, COUNT(n.id)
+		FROM tags t
+		LEFT JOIN note_tags nt ON nt.tag_id = t.id
+		LEFT JOIN notes n ON n.id = nt.note_id AND n.deleted_at IS NULL
+		WHERE t.user_id = $1 AND t.name = $2
+		GROUP BY t.id
+	`
+	var tag domain.Tag
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID, name).
+		Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt, &tag.NoteCount); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrTagNotFound
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *TagRepo) ListUserTags(ctx context.Context, userID uint64) ([]*domain.Tag, error) {
+	query := `
+		SELECT t.id, t.user_id, t.name, t.created_at, COUNT(n.id)
+		FROM tags t
+		LEFT JOIN note_tags nt ON nt.tag_id = t.id
+		LEFT JOIN notes n ON n.id = nt.note_id AND n.deleted_at IS NULL
+		WHERE t.user_id = $1
+		GROUP BY t.id
+		ORDER BY t.name
+	`
+	return r.list(ctx, query, userID)
+}
+
+func (r *TagRepo) ListPopularTags(ctx context.Context, userID uint64, limit int) ([]*domain.Tag, error) {
+	query := `
+		SELECT t.id, t.user_id, t.name, t.created_at, COUNT(n.id) AS note_count
+		FROM tags t
+		LEFT JOIN note_tags nt ON nt.tag_id = t.id
+		LEFT JOIN notes n ON n.id = nt.note_id AND n.deleted_at IS NULL
+		WHERE t.user_id = $1
+		GROUP BY t.id
+		ORDER BY note_count DESC, t.name
+		LIMIT $2
+	`
+	return r.list(ctx, query, userID, limit)
+}
+
+func (r *TagRepo) list(ctx context.Context, query string, args ...any) ([]*domain.Tag, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]*domain.Tag, 0)
+	for rows.Next() {
+		var tag domain.Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt, &tag.NoteCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Rename renames a user's tag. If the user already has a tag called newName
+// the two are merged: every note tagged oldName is tagged newName instead and
+// oldName is removed. It reports whether a merge happened.
+func (r *TagRepo) Rename(ctx context.Context, userID uint64, oldName, newName string) (bool, error) {
+	merged := false
+	err := withTx(ctx, r.db, func(ctx context.Context, tx dbtx) error {
+		var srcID uint64
+		if err := tx.QueryRowContext(ctx,
+			`SELECT id FROM tags WHERE user_id = $1 AND name = $2 FOR UPDATE`,
+			userID, oldName,
+		).Scan(&srcID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return domain.ErrTagNotFound
+			}
+			return err
+		}
+
+		// the tags are part of the notes, so their versions change too
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE notes SET version = version + 1 WHERE id IN (SELECT note_id FROM note_tags WHERE tag_id = $1)`,
+			srcID,
+		); err != nil {
+			return err
+		}
+
+		var dstID uint64
+		err := tx.QueryRowContext(ctx,
+			`SELECT id FROM tags WHERE user_id = $1 AND name = $2 FOR UPDATE`,
+			userID, newName,
+		).Scan(&dstID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if _, err := tx.ExecContext(ctx, `UPDATE tags SET name = $1 WHERE id = $2`, newName, srcID); err != nil {
+				if isUniqueViolation(err, "uq_tags_user_name") {
+					return domain.ErrConflict
+				}
+				return err
+			}
+			return nil
+		case err != nil:
+			return err
+		}
+
+		merged = true
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO note_tags (note_id, tag_id)
+			SELECT note_id, $1 FROM note_tags WHERE tag_id = $2
+			ON CONFLICT DO NOTHING
+		`, dstID, srcID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, srcID)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return merged, nil
+}
+
+// DeleteUnusedTags removes tags of every user that are no longer attached to
+// any note.
+func (r *TagRepo) DeleteUnusedTags(ctx context.Context) error {
+	return deleteUnusedTags(ctx, conn(ctx, r.db))
+}
+
+func getOrCreateTags(ctx context.Context, q dbtx, userID uint64, tagNames []string) ([]uint64, error) {
+	if len(tagNames) == 0 {
+		return []uint64{}, nil
+	}
+
+	insert := `
+		INSERT INTO tags (user_id, name)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (user_id, name) DO NOTHING
+	`
+	if _, err := q.ExecContext(ctx, insert, userID, pq.Array(tagNames)); err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT id FROM tags WHERE user_id = $1 AND name = ANY($2)`,
+		userID, pq.Array(tagNames),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uint64, 0, len(tagNames))
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// setNoteTags replaces the tags attached to a note, creating missing tags for
+// the note's owner and dropping the owner's tags that end up unused.
+func setNoteTags(ctx context.Context, q dbtx, userID, noteID uint64, tagNames []string) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM note_tags WHERE note_id = $1`, noteID); err != nil {
+		return err
+	}
+	if _, err := getOrCreateTags(ctx, q, userID, tagNames); err != nil {
+		return err
+	}
+	if len(tagNames) > 0 {
+		link := `
+			INSERT INTO note_tags (note_id, tag_id)
+			SELECT $1, id FROM tags WHERE user_id = $2 AND name = ANY($3)
+		`
+		if _, err := q.ExecContext(ctx, link, noteID, userID, pq.Array(tagNames)); err != nil {
+			return err
+		}
+	}
+	return deleteUnusedUserTags(ctx, q, userID)
+}
+
+func deleteUnusedTags(ctx context.Context, q dbtx) error {
+	query := `
+		DELETE FROM tags t
+		WHERE NOT EXISTS (SELECT 1 FROM note_tags nt WHERE nt.tag_id = t.id)
+	`
+	_, err := q.ExecContext(ctx, query)
+	return err
+}
+
+func deleteUnusedUserTags(ctx context.Context, q dbtx, userID uint64) error {
+	query := `
+		DELETE FROM tags t
+		WHERE t.user_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM note_tags nt WHERE nt.tag_id = t.id)
+	`
+	_, err := q.ExecContext(ctx, query, userID)
+	return err
+}
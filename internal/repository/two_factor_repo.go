@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+type TwoFactorRepo struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepo(db *sql.DB) *TwoFactorRepo {
+	return &TwoFactorRepo{
+		db: db,
+	}
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores the
+// given hashes instead.
+func (r *TwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID uint64, hashes []string) error {
+	return withTx(ctx, r.db, func(ctx context.Context, q dbtx) error {
+		if _, err := q.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		query := `
+			INSERT INTO recovery_codes (user_id, code_hash)
+			SELECT $1, unnest($2::text[])
+		`
+		_, err := q.ExecContext(ctx, query, userID, pq.Array(hashes))
+		return err
+	})
+}
+
+func (r *TwoFactorRepo) DeleteRecoveryCodes(ctx context.Context, userID uint64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	return err
+}
+
+// UseRecoveryCode marks an unused code as used. Unknown and used codes yield
+// ErrInvalidTOTPCode.
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userID uint64, hash string) error {
+	query := `
+		UPDATE recovery_codes SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, userID, hash)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+func (r *TwoFactorRepo) CountUnusedRecoveryCodes(ctx context.Context, userID uint64) (int, error) {
+	query := `SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	var n int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (r *TwoFactorRepo) CreateChallenge(ctx context.Context, c *domain.LoginChallenge) error {
+	query := `
+		INSERT INTO login_challenges (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	return conn(ctx, r.db).QueryRowContext(ctx, query, c.UserID, c.TokenHash, c.ExpiresAt).
+		Scan(&c.ID, &c.CreatedAt)
+}
+
+// GetChallengeForUpdate loads a challenge and locks it so concurrent
+// attempts are counted one by one.
+func (r *TwoFactorRepo) GetChallengeForUpdate(ctx context.Context, hash string) (*domain.LoginChallenge, error) {
+	query := `
+		SELECT id, user_id, token_hash, attempts, expires_at, created_at
+		FROM login_challenges
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+	var c domain.LoginChallenge
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, hash).Scan(
+		&c.ID, &c.UserID, &c.TokenHash, &c.Attempts, &c.ExpiresAt, &c.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *TwoFactorRepo) RecordChallengeAttempt(ctx context.Context, id uint64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE login_challenges SET attempts = attempts + 1 WHERE id = $1`, id)
+	return err
+}
+
+func (r *TwoFactorRepo) DeleteChallenge(ctx context.Context, id uint64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM login_challenges WHERE id = $1`, id)
+	return err
+}
+
+func (r *TwoFactorRepo) DeleteExpiredChallenges(ctx context.Context) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM login_challenges WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+
+	txMaxAttempts = 3
+	txRetryDelay  = 20 * time.Millisecond
+)
+
+type txCtxKey struct{}
+
+// txState is stored in the context while a transaction is open. depth counts
+// nested WithinTransaction calls and is used to name savepoints. afterCommit
+// holds the functions registered with AfterCommit at this level.
+type txState struct {
+	tx          *sql.Tx
+	depth       int
+	afterCommit []func()
+}
+
+// TxManager implements Transactor on top of *sql.DB. The open *sql.Tx is
+// carried in the context, and every repository resolves its connection
+// through conn, so repository calls made with that context join the
+// transaction transparently.
+type TxManager struct {
+	db *sql.DB
+}
+
+func NewTxManager(db *sql.DB) *TxManager {
+	return &TxManager{
+		db: db,
+	}
+}
+
+// WithinTransaction runs fn inside a transaction. When ctx already carries a
+// transaction, fn runs inside a savepoint of it instead, and only that
+// savepoint is rolled back if fn fails.
+//
+// The outermost call retries fn on serialization failures and deadlocks, so
+// fn must be safe to run more than once. The context passed to fn must not be
+// used from several goroutines at the same time.
+func (m *TxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if st, ok := ctx.Value(txCtxKey{}).(*txState); ok {
+		return m.withinSavepoint(ctx, st, fn)
+	}
+
+	var err error
+	for attempt := 1; attempt <= txMaxAttempts; attempt++ {
+		err = m.run(ctx, fn)
+		if err == nil || !isRetryable(err) || attempt == txMaxAttempts {
+			break
+		}
+
+		delay := txRetryDelay<<(attempt-1) + rand.N(txRetryDelay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return err
+}
+
+func (m *TxManager) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: begin: %w", domain.ErrTransaction, err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	st := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txCtxKey{}, st)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		if isRetryable(err) {
+			return err
+		}
+		return fmt.Errorf("%w: commit: %w", domain.ErrTransaction, err)
+	}
+	for _, f := range st.afterCommit {
+		f()
+	}
+	return nil
+}
+
+func (m *TxManager) withinSavepoint(ctx context.Context, parent *txState, fn func(ctx context.Context) error) error {
+	st := &txState{tx: parent.tx, depth: parent.depth + 1}
+	name := fmt.Sprintf("sp_%d", st.depth)
+
+	if _, err := st.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("%w: savepoint: %w", domain.ErrTransaction, err)
+	}
+	if err := fn(context.WithValue(ctx, txCtxKey{}, st)); err != nil {
+		if _, rbErr := st.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("%w: rollback to savepoint: %w", domain.ErrTransaction, rbErr))
+		}
+		return err
+	}
+	if _, err := st.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("%w: release savepoint: %w", domain.ErrTransaction, err)
+	}
+	parent.afterCommit = append(parent.afterCommit, st.afterCommit...)
+	return nil
+}
+
+// AfterCommit runs f once the transaction carried by ctx has committed, or
+// right away when ctx carries none. f is dropped if the transaction, or the
+// savepoint it was registered in, is rolled back. Use it for side effects
+// that must not be seen before the data they depend on, such as evicting a
+// cache entry.
+func AfterCommit(ctx context.Context, f func()) {
+	if st, ok := ctx.Value(txCtxKey{}).(*txState); ok {
+		st.afterCommit = append(st.afterCommit, f)
+		return
+	}
+	f()
+}
+
+// conn returns the transaction carried by ctx, or db when there is none.
+func conn(ctx context.Context, db *sql.DB) dbtx {
+	if st, ok := ctx.Value(txCtxKey{}).(*txState); ok {
+		return st.tx
+	}
+	return db
+}
+
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == pgSerializationFailure || pqErr.Code == pgDeadlockDetected
+}
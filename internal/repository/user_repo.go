@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/maqsatto/Notes-API/internal/domain"
@@ -23,11 +25,11 @@ func (r *UserRepo) Create(ctx context.Context, user *domain.User) error {
 	query := `
 		INSERT INTO users (email, username, password)
 		VALUES ($1, $2, $3)
-		RETURNING id, created_at, updated_at
+		RETURNING id, search_language, created_at, updated_at
 	`
-	if err := r.db.QueryRowContext(ctx, query, user.Email, user.Username, user.Password).
-		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt); err != nil {
-		return err
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, user.Email, user.Username, user.Password).
+		Scan(&user.ID, &user.SearchLanguage, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return mapUserConstraintErr(err)
 	}
 	return nil
 }
@@ -39,11 +41,11 @@ func (r *UserRepo) Update(ctx context.Context, id uint64, user *domain.User) err
 		WHERE id =$3 AND deleted_at IS NULL
 		RETURNING updated_at
 	`
-	if err := r.db.QueryRowContext(ctx, query, user.Email, user.Username, id).Scan(&user.UpdatedAt); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, user.Email, user.Username, id).Scan(&user.UpdatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.ErrUserNotFound
 		}
-		return err
+		return mapUserConstraintErr(err)
 	}
 	return nil
 }
@@ -51,12 +53,37 @@ func (r *UserRepo) Update(ctx context.Context, id uint64, user *domain.User) err
 func (r *UserRepo) UpdatePassword(ctx context.Context, id uint64, hashedPassword string) error {
 	query := `
 		UPDATE users
-		SET password = $1
+		SET password = $1, password_reset_required = false
+		WHERE id = $2 AND deleted_at IS NULL
+		RETURNING updated_at
+	`
+	var updated_at time.Time
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, hashedPassword, id).Scan(&updated_at); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ReplacePasswordHash swaps the stored hash of an unchanged password for an
+// upgraded one. It does nothing if the password changed in the meantime.
+func (r *UserRepo) ReplacePasswordHash(ctx context.Context, id uint64, oldHash, newHash string) error {
+	query := `UPDATE users SET password = $1 WHERE id = $2 AND password = $3 AND deleted_at IS NULL`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, newHash, id, oldHash)
+	return err
+}
+
+func (r *UserRepo) UpdateSearchLanguage(ctx context.Context, id uint64, language string) error {
+	query := `
+		UPDATE users
+		SET search_language = $1::regconfig
 		WHERE id = $2 AND deleted_at IS NULL
 		RETURNING updated_at
 	`
 	var updated_at time.Time
-	if err := r.db.QueryRowContext(ctx, query, hashedPassword, id).Scan(&updated_at); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, language, id).Scan(&updated_at); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.ErrUserNotFound
 		}
@@ -65,6 +92,90 @@ func (r *UserRepo) UpdatePassword(ctx context.Context, id uint64, hashedPassword
 	return nil
 }
 
+// MarkEmailVerified records that the user proved ownership of email. It
+// fails with ErrStateViolation if the account's address changed meanwhile.
+func (r *UserRepo) MarkEmailVerified(ctx context.Context, id uint64, email string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, now())
+		WHERE id = $1 AND email = $2 AND deleted_at IS NULL
+	`
+	return r.execUserUpdate(ctx, domain.ErrStateViolation, query, id, email)
+}
+
+// UpdateEmail switches to a confirmed address, which is thereby verified.
+func (r *UserRepo) UpdateEmail(ctx context.Context, id uint64, email string) error {
+	query := `
+		UPDATE users
+		SET email = $1, email_verified_at = now()
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+	if err := r.execUserUpdate(ctx, domain.ErrUserNotFound, query, email, id); err != nil {
+		return mapUserConstraintErr(err)
+	}
+	return nil
+}
+
+// SetPendingTOTPSecret stores a secret awaiting confirmation. It fails with
+// ErrStateViolation while two-factor authentication is enabled.
+func (r *UserRepo) SetPendingTOTPSecret(ctx context.Context, id uint64, secret string) error {
+	query := `
+		UPDATE users
+		SET totp_secret = $1, totp_last_step = 0
+		WHERE id = $2 AND deleted_at IS NULL AND totp_enabled_at IS NULL
+	`
+	return r.execUserUpdate(ctx, domain.ErrStateViolation, query, secret, id)
+}
+
+// EnableTOTP turns on two-factor authentication with the pending secret,
+// recording step as the last code used.
+func (r *UserRepo) EnableTOTP(ctx context.Context, id uint64, step int64) error {
+	query := `
+		UPDATE users
+		SET totp_enabled_at = now(), totp_last_step = $1
+		WHERE id = $2 AND deleted_at IS NULL AND totp_secret <> '' AND totp_enabled_at IS NULL
+	`
+	return r.execUserUpdate(ctx, domain.ErrStateViolation, query, step, id)
+}
+
+func (r *UserRepo) DisableTOTP(ctx context.Context, id uint64) error {
+	query := `
+		UPDATE users
+		SET totp_secret = '', totp_enabled_at = NULL, totp_last_step = 0
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	return r.execUserUpdate(ctx, domain.ErrUserNotFound, query, id)
+}
+
+// UseTOTPStep records a TOTP time step as used. Steps at or before the last
+// used one are rejected with ErrInvalidTOTPCode so a code cannot be replayed.
+func (r *UserRepo) UseTOTPStep(ctx context.Context, id uint64, step int64) error {
+	query := `
+		UPDATE users
+		SET totp_last_step = $1
+		WHERE id = $2 AND deleted_at IS NULL AND totp_last_step < $1
+	`
+	return r.execUserUpdate(ctx, domain.ErrInvalidTOTPCode, query, step, id)
+}
+
+// execUserUpdate runs an update of a single user and returns notFound when
+// no row matched.
+func (r *UserRepo) execUserUpdate(ctx context.Context, notFound error, query string, args ...any) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// SoftDelete moves the user to the trash. Deleted users are listed by Search
+// with UserStatusDeleted, brought back with Restore and removed for good by
+// PurgeDeleted once the retention window has passed.
 func (r *UserRepo) SoftDelete(ctx context.Context, id uint64) error {
 	query := `
 		UPDATE users
@@ -73,7 +184,7 @@ func (r *UserRepo) SoftDelete(ctx context.Context, id uint64) error {
 		RETURNING deleted_at
 	`
 	var deleted_at time.Time
-	if err := r.db.QueryRowContext(ctx, query, id).Scan(&deleted_at); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&deleted_at); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.ErrUserNotFound
 		}
@@ -87,7 +198,7 @@ func (r *UserRepo) HardDelete(ctx context.Context, id uint64) error {
 		DELETE FROM users
 		WHERE id = $1
 	`
-	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.ErrUserNotFound
 		}
@@ -96,55 +207,74 @@ func (r *UserRepo) HardDelete(ctx context.Context, id uint64) error {
 	return nil
 }
 
-func (r *UserRepo) GetByID(ctx context.Context, id uint64) (*domain.User, error) {
+// PurgeDeleted permanently deletes users that were soft-deleted longer than
+// retention ago. Their notes go with them through ON DELETE CASCADE.
+func (r *UserRepo) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
 	query := `
-		SELECT id, email, username, password, created_at, updated_at FROM users
-		WHERE id = $1 AND deleted_at IS NULL
+		DELETE FROM users
+		WHERE deleted_at < now() - make_interval(secs => $1)
 	`
-	var getUser domain.User
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// userColumns is the column list scanUser expects.
+const userColumns = `id, email, username, password, search_language, email_verified_at,
+	totp_secret, totp_enabled_at, totp_last_step, role, suspended_at, password_reset_required,
+	created_at, updated_at, deleted_at`
 
-	if err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&getUser.ID, &getUser.Email, &getUser.Username, &getUser.Password, &getUser.CreatedAt, &getUser.UpdatedAt,
-	); err != nil {
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner, extra ...any) (*domain.User, error) {
+	var u domain.User
+	dest := []any{
+		&u.ID, &u.Email, &u.Username, &u.Password, &u.SearchLanguage, &u.EmailVerifiedAt,
+		&u.TOTPSecret, &u.TOTPEnabledAt, &u.TOTPLastStep, &u.Role, &u.SuspendedAt, &u.PasswordResetRequired,
+		&u.CreatedAt, &u.UpdatedAt, &u.DeletedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
 		return nil, err
 	}
-	return &getUser, nil
+	return &u, nil
+}
+
+func (r *UserRepo) GetByID(ctx context.Context, id uint64) (*domain.User, error) {
+	query := `
+		SELECT ` + userColumns + ` FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	return scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, id))
 }
 
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	query := `
-		SELECT id, email, username, password, created_at, updated_at FROM users
+		SELECT ` + userColumns + ` FROM users
 		WHERE email = $1 AND deleted_at IS NULL
 	`
-	var getUser domain.User
-
-	if err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&getUser.ID, &getUser.Email, &getUser.Username, &getUser.Password, &getUser.CreatedAt, &getUser.UpdatedAt,
-	); err != nil {
-		return nil, err
-	}
-	return &getUser, nil
+	return scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, email))
 }
 
 func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
 	query := `
-		SELECT id, email, username, password, created_at, updated_at FROM users
+		SELECT ` + userColumns + ` FROM users
 		WHERE username = $1 AND deleted_at IS NULL
 	`
-	var getUser domain.User
-
-	if err := r.db.QueryRowContext(ctx, query, username).Scan(
-		&getUser.ID, &getUser.Email, &getUser.Username, &getUser.Password, &getUser.CreatedAt, &getUser.UpdatedAt,
-	); err != nil {
-		return nil, err
-	}
-	return &getUser, nil
+	return scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, username))
 }
 
 func (r *UserRepo) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 and deleted_at IS NULL)`
 	var exists bool
-	if err := r.db.QueryRowContext(ctx, query, email).Scan(&exists); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(&exists); err != nil {
 		return false, err
 	}
 	return exists, nil
@@ -153,21 +283,21 @@ func (r *UserRepo) ExistsByEmail(ctx context.Context, email string) (bool, error
 func (r *UserRepo) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 and deleted_at IS NULL)`
 	var exists bool
-	if err := r.db.QueryRowContext(ctx, query, username).Scan(&exists); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, username).Scan(&exists); err != nil {
 		return false, err
 	}
 	return exists, nil
 }
 
 func (r *UserRepo) List(ctx context.Context, limit, offset int) ([]*domain.User, int64, error) {
-	query := `SELECT id, email, username, created_at, updated_at FROM users
-	WHERE deleted_at IS NULL LIMIT $1 OFFSET $2`
+	query := `SELECT id, email, username, search_language, created_at, updated_at FROM users
+	WHERE deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2`
 	countQuery := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
 	var total int64
-	if err := r.db.QueryRowContext(ctx, countQuery).Scan(&total); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery).Scan(&total); err != nil {
 		return nil, 0, err
 	}
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -175,7 +305,7 @@ func (r *UserRepo) List(ctx context.Context, limit, offset int) ([]*domain.User,
 	users := make([]*domain.User, 0, limit)
 	for rows.Next() {
 		var user domain.User
-		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.SearchLanguage, &user.CreatedAt, &user.UpdatedAt); err != nil {
 			return nil, 0, err
 		}
 		users = append(users, &user)
@@ -188,11 +318,165 @@ func (r *UserRepo) List(ctx context.Context, limit, offset int) ([]*domain.User,
 
 func (r *UserRepo) Count(ctx context.Context) (uint64, error) {
 	query := `
-		SELECT COUNT(*) FROM users;
+		SELECT COUNT(*) FROM users WHERE deleted_at IS NULL;
 	`
 	var count uint64
-	if err := r.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query).Scan(&count); err != nil {
 		return 0, err
 	}
 	return count, nil
 }
+
+// Administration
+
+// SetRole assigns a role to the user.
+func (r *UserRepo) SetRole(ctx context.Context, id uint64, role string) error {
+	err := r.execUserUpdate(ctx, domain.ErrUserNotFound,
+		`UPDATE users SET role = $1 WHERE id = $2 AND deleted_at IS NULL`, role, id)
+	if isForeignKeyViolation(err, "") {
+		return domain.ErrRoleNotFound
+	}
+	return err
+}
+
+// ExistsWithRole reports whether an active user has the role.
+func (r *UserRepo) ExistsWithRole(ctx context.Context, role string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE role = $1 AND deleted_at IS NULL)`
+	var exists bool
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, role).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// Suspend blocks the user's logins. Suspending a suspended user keeps the
+// original time.
+func (r *UserRepo) Suspend(ctx context.Context, id uint64) error {
+	return r.execUserUpdate(ctx, domain.ErrUserNotFound,
+		`UPDATE users SET suspended_at = COALESCE(suspended_at, now()) WHERE id = $1 AND deleted_at IS NULL`, id)
+}
+
+func (r *UserRepo) Unsuspend(ctx context.Context, id uint64) error {
+	return r.execUserUpdate(ctx, domain.ErrUserNotFound,
+		`UPDATE users SET suspended_at = NULL WHERE id = $1 AND deleted_at IS NULL`, id)
+}
+
+// RequirePasswordReset blocks logins until the password is changed through
+// UpdatePassword.
+func (r *UserRepo) RequirePasswordReset(ctx context.Context, id uint64) error {
+	return r.execUserUpdate(ctx, domain.ErrUserNotFound,
+		`UPDATE users SET password_reset_required = true WHERE id = $1 AND deleted_at IS NULL`, id)
+}
+
+// Restore undoes a soft delete and returns when the user had been deleted.
+func (r *UserRepo) Restore(ctx context.Context, id uint64) (time.Time, error) {
+	query := `
+		UPDATE users u
+		SET deleted_at = NULL
+		FROM (SELECT id, deleted_at FROM users WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE) old
+		WHERE u.id = old.id
+		RETURNING old.deleted_at
+	`
+	var deletedAt time.Time
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, domain.ErrUserNotFound
+		}
+		return time.Time{}, mapUserConstraintErr(err)
+	}
+	return deletedAt, nil
+}
+
+// GetSummary returns the user with its live note count, including
+// soft-deleted users.
+func (r *UserRepo) GetSummary(ctx context.Context, id uint64) (*domain.UserSummary, error) {
+	query := `
+		SELECT ` + userColumns + `,
+			(SELECT COUNT(*) FROM notes n WHERE n.user_id = users.id AND n.deleted_at IS NULL)
+		FROM users
+		WHERE id = $1
+	`
+	var count int64
+	u, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, id), &count)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UserSummary{User: *u, NoteCount: count}, nil
+}
+
+// Search lists users matching the filter, oldest first, with their live
+// note counts.
+func (r *UserRepo) Search(ctx context.Context, filter domain.UserFilter, limit, offset int) ([]*domain.UserSummary, int64, error) {
+	var (
+		where []string
+		args  []any
+	)
+	switch filter.Status {
+	case domain.UserStatusActive:
+		where = append(where, "deleted_at IS NULL AND suspended_at IS NULL")
+	case domain.UserStatusSuspended:
+		where = append(where, "deleted_at IS NULL AND suspended_at IS NOT NULL")
+	case domain.UserStatusDeleted:
+		where = append(where, "deleted_at IS NOT NULL")
+	case domain.UserStatusAll:
+	default:
+		where = append(where, "deleted_at IS NULL")
+	}
+	if filter.Query != "" {
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+		where = append(where, fmt.Sprintf("(email ILIKE $%d OR username ILIKE $%d)", len(args), len(args)))
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		where = append(where, fmt.Sprintf("role = $%d", len(args)))
+	}
+	cond := "TRUE"
+	if len(where) > 0 {
+		cond = strings.Join(where, " AND ")
+	}
+
+	var total int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT `+userColumns+`,
+			(SELECT COUNT(*) FROM notes n WHERE n.user_id = users.id AND n.deleted_at IS NULL)
+		FROM users
+		WHERE %s
+		ORDER BY id
+		LIMIT $%d OFFSET $%d
+	`, cond, len(args)+1, len(args)+2)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]*domain.UserSummary, 0, limit)
+	for rows.Next() {
+		var count int64
+		u, err := scanUser(rows, &count)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &domain.UserSummary{User: *u, NoteCount: count})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func mapUserConstraintErr(err error) error {
+	switch {
+	case isUniqueViolation(err, "uq_users_email_active"):
+		return domain.ErrEmailAlreadyExists
+	case isUniqueViolation(err, "uq_users_username_active"):
+		return domain.ErrUsernameAlreadyExists
+	case isUniqueViolation(err, ""):
+		return domain.ErrUserAlreadyExists
+	}
+	return err
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ adminService = (*AdminService)(nil)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+// AdminService backs the admin API. Permissions are checked by the HTTP
+// layer; the service only refuses actions an administrator may not take on
+// their own account. Every change is recorded in the audit log.
+type AdminService struct {
+	tx     repository.Transactor
+	users  *repository.UserRepo
+	notes  *repository.NoteRepo
+	roles  *repository.RoleRepo
+	audit  *repository.AuditRepo
+	tokens *TokenService
+	resets *PasswordResetService
+}
+
+func NewAdminService(tx repository.Transactor, users *repository.UserRepo, notes *repository.NoteRepo, roles *repository.RoleRepo, audit *repository.AuditRepo, tokens *TokenService, resets *PasswordResetService) *AdminService {
+	return &AdminService{
+		tx:     tx,
+		users:  users,
+		notes:  notes,
+		roles:  roles,
+		audit:  audit,
+		tokens: tokens,
+		resets: resets,
+	}
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, filter domain.UserFilter, limit, offset int) ([]*domain.UserSummary, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	switch filter.Status {
+	case "", domain.UserStatusActive, domain.UserStatusSuspended, domain.UserStatusDeleted, domain.UserStatusAll:
+	default:
+		return nil, 0, domain.ErrInvalidInput
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	return s.users.Search(ctx, filter, limit, offset)
+}
+
+// GetUser returns the user with its note count, including deleted users.
+func (s *AdminService) GetUser(ctx context.Context, userID uint64) (*domain.UserSummary, error) {
+	if userID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	return s.users.GetSummary(ctx, userID)
+}
+
+// Suspend blocks the user's logins and ends their sessions.
+func (s *AdminService) Suspend(ctx context.Context, actorID, userID uint64) error {
+	if actorID == userID {
+		return domain.ErrOperationNotAllowed
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.users.Suspend(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.tokens.RevokeAllSessions(ctx, userID); err != nil {
+			return err
+		}
+		return s.record(ctx, domain.AuditUserSuspend, actorID, userID, "")
+	})
+}
+
+func (s *AdminService) Unsuspend(ctx context.Context, actorID, userID uint64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.users.Unsuspend(ctx, userID); err != nil {
+			return err
+		}
+		return s.record(ctx, domain.AuditUserUnsuspend, actorID, userID, "")
+	})
+}
+
+// ForcePasswordReset blocks the user's logins until they choose a new
+// password through the reset link mailed to them, and ends their sessions.
+func (s *AdminService) ForcePasswordReset(ctx context.Context, actorID, userID uint64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.users.RequirePasswordReset(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.tokens.RevokeAllSessions(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.resets.SendRequiredReset(ctx, user); err != nil {
+			return err
+		}
+		return s.record(ctx, domain.AuditUserForceReset, actorID, userID, "")
+	})
+}
+
+// Restore undoes the deletion of an account along with the notes deleted
+// with it, as long as it has not been purged yet.
+func (s *AdminService) Restore(ctx context.Context, actorID, userID uint64) (*domain.UserSummary, error) {
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		deletedAt, err := s.users.Restore(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.notes.RestoreByUserID(ctx, userID, deletedAt); err != nil {
+			return err
+		}
+		return s.record(ctx, domain.AuditUserRestore, actorID, userID, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.users.GetSummary(ctx, userID)
+}
+
+// SetRole assigns a role and ends the user's sessions so that new tokens
+// carry the new permissions. The actor must hold every permission of both the
+// user's current role and the new one.
+func (s *AdminService) SetRole(ctx context.Context, actorID, userID uint64, role string) error {
+	if actorID == userID {
+		return domain.ErrOperationNotAllowed
+	}
+	role = strings.TrimSpace(role)
+	if role == "" {
+		return domain.ErrInvalidRole
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		held, err := s.actorPermissions(ctx, actorID)
+		if err != nil {
+			return err
+		}
+		user, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, name := range []string{user.Role, role} {
+			r, err := s.roles.Get(ctx, name)
+			if err != nil {
+				return err
+			}
+			if err := checkGrantable(held, r.Permissions); err != nil {
+				return err
+			}
+		}
+		if err := s.users.SetRole(ctx, userID, role); err != nil {
+			return err
+		}
+		if err := s.tokens.RevokeAllSessions(ctx, userID); err != nil {
+			return err
+		}
+		return s.record(ctx, domain.AuditUserSetRole, actorID, userID, "role="+role)
+	})
+}
+
+func (s *AdminService) ListRoles(ctx context.Context) ([]*domain.Role, error) {
+	return s.roles.List(ctx)
+}
+
+// SaveRole creates or replaces a custom role. Users who have it get the new
+// permissions from their next token refresh. The actor must hold every
+// permission the role has or is given.
+func (s *AdminService) SaveRole(ctx context.Context, actorID uint64, role *domain.Role) error {
+	role.Name = strings.TrimSpace(role.Name)
+	if !roleNamePattern.MatchString(role.Name) {
+		return domain.ErrInvalidRole
+	}
+	perms := make([]string, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		if !slices.Contains(domain.Permissions, p) {
+			return fmt.Errorf("%w: %q", domain.ErrInvalidPermission, p)
+		}
+		if !slices.Contains(perms, p) {
+			perms = append(perms, p)
+		}
+	}
+	role.Permissions = perms
+	role.Description = strings.TrimSpace(role.Description)
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		held, err := s.actorPermissions(ctx, actorID)
+		if err != nil {
+			return err
+		}
+		if err := checkGrantable(held, role.Permissions); err != nil {
+			return err
+		}
+		switch existing, err := s.roles.Get(ctx, role.Name); {
+		case err == nil:
+			if existing.BuiltIn {
+				return domain.ErrBuiltInRole
+			}
+			if err := checkGrantable(held, existing.Permissions); err != nil {
+				return err
+			}
+		case !errors.Is(err, domain.ErrRoleNotFound):
+			return err
+		}
+		if err := s.roles.Save(ctx, role); err != nil {
+			return err
+		}
+		return s.record(ctx, domain.AuditRoleSave, actorID, 0,
+			fmt.Sprintf("role=%s permissions=%s", role.Name, strings.Join(role.Permissions, ",")))
+	})
+}
+
+// DeleteRole removes a custom role no user has. The actor must hold every
+// permission of the role.
+func (s *AdminService) DeleteRole(ctx context.Context, actorID uint64, name string) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		held, err := s.actorPermissions(ctx, actorID)
+		if err != nil {
+			return err
+		}
+		role, err := s.roles.Get(ctx, name)
+		if err != nil {
+			return err
+		}
+		if role.BuiltIn {
+			return domain.ErrBuiltInRole
+		}
+		if err := checkGrantable(held, role.Permissions); err != nil {
+			return err
+		}
+		if err := s.roles.Delete(ctx, name); err != nil {
+			return err
+		}
+		return s.record(ctx, domain.AuditRoleDelete, actorID, 0, "role="+name)
+	})
+}
+
+// actorPermissions returns the permissions the actor's role grants now,
+// which may be more recent than those in their access token.
+func (s *AdminService) actorPermissions(ctx context.Context, actorID uint64) ([]string, error) {
+	actor, err := s.users.GetByID(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.roles.Get(ctx, actor.Role)
+	if err != nil {
+		return nil, err
+	}
+	return role.Permissions, nil
+}
+
+// checkGrantable refuses to let an actor grant, or take away, permissions
+// they do not hold themselves.
+func checkGrantable(held, perms []string) error {
+	for _, p := range perms {
+		if !domain.HasPermission(held, p) {
+			return fmt.Errorf("%w: %q", domain.ErrPermissionNotHeld, p)
+		}
+	}
+	return nil
+}
+
+// record writes an audit entry; a zero userID means the action concerns no
+// particular user.
+func (s *AdminService) record(ctx context.Context, action string, actorID, userID uint64, details string) error {
+	entry := &domain.AuditEntry{
+		ActorID: &actorID,
+		Action:  action,
+		Details: details,
+	}
+	if userID != 0 {
+		entry.UserID = &userID
+	}
+	return s.audit.Record(ctx, entry)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+)
+
+var _ apiKeyService = (*APIKeyService)(nil)
+
+const maxAPIKeyNameLength = 100
+
+// APIKeyService manages personal API keys, which let scripts act for a user
+// within the key's scopes without holding the user's password.
+type APIKeyService struct {
+	keys *repository.APIKeyRepo
+}
+
+func NewAPIKeyService(keys *repository.APIKeyRepo) *APIKeyService {
+	return &APIKeyService{
+		keys: keys,
+	}
+}
+
+// Create issues a key and returns it along with its record. The key itself
+// is not stored and cannot be shown again.
+func (s *APIKeyService) Create(ctx context.Context, userID uint64, name string, scopes []string, expiresAt *time.Time) (*domain.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxAPIKeyNameLength {
+		return nil, "", domain.ErrInvalidInput
+	}
+	granted, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", domain.ErrInvalidInput
+	}
+
+	key, lookup, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	record := &domain.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    lookup,
+		KeyHash:   hash,
+		Scopes:    granted,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.keys.Create(ctx, record); err != nil {
+		return nil, "", err
+	}
+	return record, key, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID uint64) ([]*domain.APIKey, error) {
+	return s.keys.ListByUserID(ctx, userID)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, userID, keyID uint64) error {
+	if keyID == 0 {
+		return domain.ErrInvalidID
+	}
+	return s.keys.Revoke(ctx, userID, keyID)
+}
+
+// Authenticate resolves a presented key to its owner and scopes. Unknown,
+// revoked and expired keys, and keys of suspended or deleted users, all
+// yield ErrInvalidAPIKey.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (uint64, []string, error) {
+	lookup, ok := auth.APIKeyLookup(key)
+	if !ok {
+		return 0, nil, domain.ErrInvalidAPIKey
+	}
+	record, err := s.keys.GetByPrefix(ctx, lookup)
+	if err != nil {
+		if errors.Is(err, domain.ErrAPIKeyNotFound) {
+			return 0, nil, domain.ErrInvalidAPIKey
+		}
+		return 0, nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashOpaqueToken(key)), []byte(record.KeyHash)) != 1 {
+		return 0, nil, domain.ErrInvalidAPIKey
+	}
+	if record.RevokedAt != nil || record.Expired(time.Now()) {
+		return 0, nil, domain.ErrInvalidAPIKey
+	}
+	if err := s.keys.TouchLastUsed(ctx, record.ID); err != nil {
+		return 0, nil, err
+	}
+	return record.UserID, record.Scopes, nil
+}
+
+// normalizeScopes checks that scopes is a non-empty list of known scopes and
+// drops duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, domain.ErrInvalidScope
+	}
+	out := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(domain.Scopes, scope) {
+			return nil, domain.ErrInvalidScope
+		}
+		if !slices.Contains(out, scope) {
+			out = append(out, scope)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,810 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/logger"
+	"github.com/maqsatto/Notes-API/internal/ot"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+// CollabChannel is the Postgres notification channel on which API instances
+// tell each other about edits and presence in collaborative sessions.
+const CollabChannel = "note_collab"
+
+const (
+	// collabEventBuffer is how many events a client may fall behind before
+	// its session is closed.
+	collabEventBuffer = 256
+	// collabTimeout bounds the database work done outside of requests.
+	collabTimeout = 10 * time.Second
+)
+
+var errCollabClosed = errors.New("collab: shutting down")
+
+const (
+	noticeEdit     = "edit"
+	noticePresence = "presence"
+	noticeLeave    = "leave"
+)
+
+// collabNotice is the payload published on CollabChannel. Edits only carry
+// their revision; instances read them from the edit log.
+type collabNotice struct {
+	NoteID       uint64 `json:"note_id"`
+	Type         string `json:"type"`
+	Revision     int64  `json:"revision,omitempty"`
+	ClientID     string `json:"client_id,omitempty"`
+	UserID       uint64 `json:"user_id,omitempty"`
+	Username     string `json:"username,omitempty"`
+	Cursor       *int   `json:"cursor,omitempty"`
+	SelectionEnd *int   `json:"selection_end,omitempty"`
+}
+
+var _ collabService = (*CollabService)(nil)
+
+// CollabService runs collaborative editing sessions on note content. Clients
+// send operations based on the revision they have seen; the service
+// transforms them against the edits made since, under a lock on the note's
+// document so that every API instance agrees on one order. Instances learn
+// about each other's edits and presence through Postgres notifications, and
+// the document is written back to the note periodically.
+type CollabService struct {
+	tx           repository.Transactor
+	collab       *repository.CollabRepo
+	notes        *repository.NoteRepo
+	users        *repository.UserRepo
+	log          *logger.Logger
+	persistEvery time.Duration
+	presenceTTL  time.Duration
+
+	mu   sync.Mutex
+	hubs map[uint64]*collabHub
+	// closed is set by Shutdown; no sessions start afterwards.
+	closed bool
+	// wg counts running hubs.
+	wg sync.WaitGroup
+}
+
+func NewCollabService(tx repository.Transactor, collab *repository.CollabRepo, notes *repository.NoteRepo, users *repository.UserRepo, log *logger.Logger, persistEvery, presenceTTL time.Duration) *CollabService {
+	return &CollabService{
+		tx:           tx,
+		collab:       collab,
+		notes:        notes,
+		users:        users,
+		log:          log,
+		persistEvery: persistEvery,
+		presenceTTL:  presenceTTL,
+		hubs:         make(map[uint64]*collabHub),
+	}
+}
+
+// Join starts a session for the user on a note they can at least view. The
+// session may edit when the user can edit the note and canWrite is set; it
+// is cleared for credentials limited to reading. The first event of the
+// session is an init event carrying the document.
+func (s *CollabService) Join(ctx context.Context, userID, noteID uint64, canWrite bool) (*CollabSession, error) {
+	if userID == 0 || noteID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	access, err := s.notes.AccessLevel(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if access < domain.AccessView {
+		return nil, domain.ErrNoteAccessDenied
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.readState(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	clientID, err := newClientID()
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &CollabSession{
+		svc:      s,
+		noteID:   noteID,
+		userID:   userID,
+		canEdit:  canWrite && access >= domain.AccessEdit,
+		events:   make(chan domain.CollabEvent, collabEventBuffer),
+		revision: state.Revision,
+		presence: domain.CollabPresence{
+			ClientID: clientID,
+			UserID:   userID,
+			Username: user.Username,
+		},
+	}
+	if err := s.attach(sess, state); err != nil {
+		return nil, err
+	}
+	// Edits made while the document was read may have been announced before
+	// the hub existed.
+	go sess.hub.catchUp()
+	if err := s.publish(ctx, sess.presenceNotice(noticePresence)); err != nil {
+		sess.Close()
+		return nil, err
+	}
+	return sess, nil
+}
+
+// HandleNotification applies a payload received on CollabChannel. Payloads
+// about notes without sessions on this instance are ignored.
+func (s *CollabService) HandleNotification(payload string) {
+	var n collabNotice
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		s.log.Error("collab: malformed notification", err)
+		return
+	}
+	h := s.hub(n.NoteID)
+	if h == nil {
+		return
+	}
+	switch n.Type {
+	case noticeEdit:
+		go h.catchUp()
+	case noticePresence:
+		h.seePeer(domain.CollabPresence{
+			ClientID:     n.ClientID,
+			UserID:       n.UserID,
+			Username:     n.Username,
+			Cursor:       n.Cursor,
+			SelectionEnd: n.SelectionEnd,
+		})
+	case noticeLeave:
+		h.dropPeer(n.ClientID)
+	}
+}
+
+// Resync catches every session up with the edit log. It is called when
+// notifications may have been missed, after the listener reconnects.
+func (s *CollabService) Resync() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range s.hubs {
+		go h.catchUp()
+	}
+}
+
+// Shutdown ends every session and waits, until ctx is done, for documents
+// with pending edits to be written back to their notes.
+func (s *CollabService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	var sessions []*CollabSession
+	for _, h := range s.hubs {
+		h.mu.Lock()
+		for _, sess := range h.sessions {
+			sessions = append(sessions, sess)
+		}
+		h.mu.Unlock()
+	}
+	s.mu.Unlock()
+	for _, sess := range sessions {
+		sess.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *CollabService) edit(ctx context.Context, sess *CollabSession, revision int64, operation json.RawMessage) error {
+	if !sess.canEdit {
+		return domain.ErrNoteAccessDenied
+	}
+	op := &ot.Operation{}
+	if err := json.Unmarshal(operation, op); err != nil {
+		return domain.ErrInvalidEditOperation
+	}
+
+	var outOfSync bool
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		outOfSync = false
+		if err := authorizeNote(ctx, s.notes, sess.userID, sess.noteID, domain.AccessEdit); err != nil {
+			return err
+		}
+		state, reset, err := s.lockState(ctx, sess.noteID)
+		if err != nil {
+			return err
+		}
+		if revision < 0 || revision > state.Revision {
+			return domain.ErrInvalidEditOperation
+		}
+		if reset {
+			outOfSync = true
+			return nil
+		}
+
+		rebased := op
+		if revision < state.Revision {
+			edits, err := s.collab.ListEditsSince(ctx, sess.noteID, revision)
+			if err != nil {
+				return err
+			}
+			// The edits the client missed were pruned from the log.
+			if int64(len(edits)) != state.Revision-revision {
+				outOfSync = true
+				return nil
+			}
+			for _, e := range edits {
+				if e.IsReset() {
+					outOfSync = true
+					return nil
+				}
+				concurrent := &ot.Operation{}
+				if err := json.Unmarshal(e.Operation, concurrent); err != nil {
+					return err
+				}
+				if rebased, _, err = ot.Transform(rebased, concurrent); err != nil {
+					return domain.ErrInvalidEditOperation
+				}
+			}
+		}
+
+		content, err := rebased.Apply(state.Content)
+		if err != nil {
+			return domain.ErrInvalidEditOperation
+		}
+		if _, err := validator.IsValidContent(content); err != nil {
+			return err
+		}
+		data, err := json.Marshal(rebased)
+		if err != nil {
+			return err
+		}
+
+		state.Revision++
+		state.Content = content
+		if err := s.collab.AppendEdit(ctx, &domain.NoteEdit{
+			NoteID:    sess.noteID,
+			Revision:  state.Revision,
+			UserID:    sess.userID,
+			ClientID:  sess.presence.ClientID,
+			Operation: data,
+		}); err != nil {
+			return err
+		}
+		if err := s.collab.SaveState(ctx, state); err != nil {
+			return err
+		}
+		return s.publish(ctx, collabNotice{NoteID: sess.noteID, Type: noticeEdit, Revision: state.Revision})
+	})
+	if err != nil {
+		return err
+	}
+
+	if outOfSync {
+		sess.hub.resync(sess)
+		return domain.ErrEditOutOfSync
+	}
+	sess.hub.markDirty()
+	sess.hub.catchUp()
+	return nil
+}
+
+// persist writes the note's document back to the note if it has edits the
+// note does not have. It fails with ErrNoteVersionMismatch when the note was
+// changed by someone else after lockState compared it with the document.
+func (s *CollabService) persist(ctx context.Context, noteID uint64) error {
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		state, _, err := s.lockState(ctx, noteID)
+		if err != nil {
+			return err
+		}
+		if !state.Dirty() {
+			return nil
+		}
+		note, err := s.notes.GetByID(ctx, noteID)
+		if err != nil {
+			return err
+		}
+		if contentHash(note.Content) != state.SyncedHash {
+			return domain.ErrNoteVersionMismatch
+		}
+		note.Content = state.Content
+		if err := s.notes.Update(ctx, note); err != nil {
+			return err
+		}
+		state.PersistedRevision = state.Revision
+		state.SyncedHash = contentHash(state.Content)
+		return s.collab.SaveState(ctx, state)
+	})
+	// Edits to a note moved to the trash are dropped with it.
+	if errors.Is(err, domain.ErrNoteNotFound) {
+		return nil
+	}
+	return err
+}
+
+// readState returns the note's document, starting one if needed.
+func (s *CollabService) readState(ctx context.Context, noteID uint64) (*domain.CollabState, error) {
+	var state *domain.CollabState
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		state, _, err = s.lockState(ctx, noteID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// lockState starts the note's document if needed and locks it. When the
+// note's content was changed outside the session, by a plain update or a
+// revision restore, that content replaces the document and reset is true.
+// It must run in a transaction.
+func (s *CollabService) lockState(ctx context.Context, noteID uint64) (state *domain.CollabState, reset bool, err error) {
+	if err := s.collab.EnsureState(ctx, noteID); err != nil {
+		return nil, false, err
+	}
+	if state, err = s.collab.LockState(ctx, noteID); err != nil {
+		return nil, false, err
+	}
+	hash := contentHash(state.NoteContent)
+	if hash == state.SyncedHash {
+		return state, false, nil
+	}
+
+	state.Revision++
+	state.Content = state.NoteContent
+	state.PersistedRevision = state.Revision
+	state.SyncedHash = hash
+	if err := s.collab.AppendEdit(ctx, &domain.NoteEdit{
+		NoteID:   noteID,
+		Revision: state.Revision,
+		Content:  state.Content,
+	}); err != nil {
+		return nil, false, err
+	}
+	if err := s.collab.SaveState(ctx, state); err != nil {
+		return nil, false, err
+	}
+	if err := s.publish(ctx, collabNotice{NoteID: noteID, Type: noticeEdit, Revision: state.Revision}); err != nil {
+		return nil, false, err
+	}
+	return state, true, nil
+}
+
+func (s *CollabService) publish(ctx context.Context, n collabNotice) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return s.collab.Notify(ctx, CollabChannel, string(payload))
+}
+
+func (s *CollabService) hub(noteID uint64) *collabHub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hubs[noteID]
+}
+
+// attach adds sess to the hub of its note, starting the hub if needed, and
+// queues the session's init event.
+func (s *CollabService) attach(sess *CollabSession, state *domain.CollabState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errCollabClosed
+	}
+
+	h, ok := s.hubs[sess.noteID]
+	if !ok {
+		h = &collabHub{
+			svc:      s,
+			noteID:   sess.noteID,
+			revision: state.Revision,
+			sessions: make(map[string]*CollabSession),
+			peers:    make(map[string]*collabPeer),
+			stop:     make(chan struct{}),
+		}
+		s.hubs[sess.noteID] = h
+		s.wg.Add(1)
+		go h.run()
+	}
+	sess.hub = h
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions[sess.presence.ClientID] = sess
+	h.peers[sess.presence.ClientID] = &collabPeer{presence: sess.presence, seenAt: time.Now()}
+	sess.send(domain.CollabEvent{
+		Type:     domain.CollabEventInit,
+		Revision: state.Revision,
+		Content:  state.Content,
+		ClientID: sess.presence.ClientID,
+		CanEdit:  sess.canEdit,
+		Peers:    h.peerList(sess.presence.ClientID),
+	})
+	return nil
+}
+
+// detach removes sess from its hub and stops the hub when it was the last
+// session on this instance.
+func (s *CollabService) detach(sess *CollabSession) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := sess.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.sessions[sess.presence.ClientID]; !ok {
+		return false
+	}
+	delete(h.sessions, sess.presence.ClientID)
+	h.forget(sess.presence.ClientID)
+	sess.closeLocked()
+	if len(h.sessions) == 0 {
+		delete(s.hubs, h.noteID)
+		close(h.stop)
+	}
+	return true
+}
+
+// CollabSession is one client's connection to a collaborative document.
+type CollabSession struct {
+	svc     *CollabService
+	hub     *collabHub
+	noteID  uint64
+	userID  uint64
+	canEdit bool
+	events  chan domain.CollabEvent
+
+	// The fields below are guarded by hub.mu. revision is the last revision
+	// the client was sent.
+	revision int64
+	presence domain.CollabPresence
+	closed   bool
+}
+
+// Events delivers the session's events. It is closed when the session ends,
+// including when the client fell too far behind.
+func (c *CollabSession) Events() <-chan domain.CollabEvent {
+	return c.events
+}
+
+// Edit applies an operation the client made on the given revision of the
+// document. It returns ErrEditOutOfSync, after sending a reset event, when
+// the operation can no longer be rebased.
+func (c *CollabSession) Edit(ctx context.Context, revision int64, operation json.RawMessage) error {
+	return c.svc.edit(ctx, c, revision, operation)
+}
+
+// MoveCursor shares the client's cursor and selection end with the other
+// clients.
+func (c *CollabSession) MoveCursor(ctx context.Context, cursor, selectionEnd *int) error {
+	if cursor != nil && *cursor < 0 || selectionEnd != nil && *selectionEnd < 0 {
+		return domain.ErrInvalidInput
+	}
+	c.hub.mu.Lock()
+	c.presence.Cursor = cursor
+	c.presence.SelectionEnd = selectionEnd
+	n := c.presenceNotice(noticePresence)
+	c.hub.mu.Unlock()
+	return c.svc.publish(ctx, n)
+}
+
+// Close ends the session. It is safe to call more than once.
+func (c *CollabSession) Close() {
+	if !c.svc.detach(c) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), collabTimeout)
+	defer cancel()
+	c.hub.mu.Lock()
+	n := c.presenceNotice(noticeLeave)
+	c.hub.mu.Unlock()
+	if err := c.svc.publish(ctx, n); err != nil {
+		c.svc.log.Error("collab: publish leave", err)
+	}
+}
+
+// send queues ev for the client, ending the session if the client is too
+// far behind. hub.mu must be held.
+func (c *CollabSession) send(ev domain.CollabEvent) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.events <- ev:
+	default:
+		c.closeLocked()
+	}
+}
+
+func (c *CollabSession) closeLocked() {
+	if !c.closed {
+		c.closed = true
+		close(c.events)
+	}
+}
+
+// presenceNotice must be called with hub.mu held.
+func (c *CollabSession) presenceNotice(kind string) collabNotice {
+	return collabNotice{
+		NoteID:       c.noteID,
+		Type:         kind,
+		ClientID:     c.presence.ClientID,
+		UserID:       c.presence.UserID,
+		Username:     c.presence.Username,
+		Cursor:       c.presence.Cursor,
+		SelectionEnd: c.presence.SelectionEnd,
+	}
+}
+
+// collabHub holds the sessions this instance has on one note.
+type collabHub struct {
+	svc    *CollabService
+	noteID uint64
+	stop   chan struct{}
+
+	// sync serializes catching up with the edit log.
+	sync sync.Mutex
+
+	mu       sync.Mutex
+	revision int64
+	sessions map[string]*CollabSession
+	// peers are the clients on every instance that recently reported
+	// presence, by client ID.
+	peers map[string]*collabPeer
+	dirty bool
+}
+
+type collabPeer struct {
+	presence domain.CollabPresence
+	seenAt   time.Time
+}
+
+// run persists the document while it has local edits, refreshes the
+// presence of local clients and forgets peers that went silent. When the
+// hub stops it persists one last time.
+func (h *collabHub) run() {
+	defer h.svc.wg.Done()
+
+	persist := time.NewTicker(h.svc.persistEvery)
+	defer persist.Stop()
+	presence := time.NewTicker(h.svc.presenceTTL / 3)
+	defer presence.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			h.persist()
+			return
+		case <-persist.C:
+			h.persist()
+		case <-presence.C:
+			h.refreshPresence()
+		}
+	}
+}
+
+func (h *collabHub) markDirty() {
+	h.mu.Lock()
+	h.dirty = true
+	h.mu.Unlock()
+}
+
+func (h *collabHub) persist() {
+	h.mu.Lock()
+	dirty := h.dirty
+	h.dirty = false
+	h.mu.Unlock()
+	if !dirty {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), collabTimeout)
+	defer cancel()
+	err := h.svc.persist(ctx, h.noteID)
+	switch {
+	case errors.Is(err, domain.ErrNoteVersionMismatch):
+		// The next round resets the document to the changed note.
+		h.markDirty()
+	case err != nil:
+		h.svc.log.Error("collab: persist note", err)
+		h.markDirty()
+	}
+}
+
+// catchUp sends the sessions the edits logged after the hub's revision.
+func (h *collabHub) catchUp() {
+	h.sync.Lock()
+	defer h.sync.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), collabTimeout)
+	defer cancel()
+
+	h.mu.Lock()
+	from := h.revision
+	h.mu.Unlock()
+
+	edits, err := h.svc.collab.ListEditsSince(ctx, h.noteID, from)
+	if err != nil {
+		h.svc.log.Error("collab: read edits", err)
+		return
+	}
+	if len(edits) > 0 && edits[0].Revision != from+1 {
+		// The edits were pruned before this instance saw them.
+		h.resyncAll(ctx)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, e := range edits {
+		h.deliver(e)
+		h.revision = e.Revision
+	}
+}
+
+// deliver must be called with mu held.
+func (h *collabHub) deliver(e *domain.NoteEdit) {
+	author := domain.CollabPresence{ClientID: e.ClientID, UserID: e.UserID}
+	if p, ok := h.peers[e.ClientID]; ok {
+		author = p.presence
+	}
+	for _, sess := range h.sessions {
+		if e.Revision <= sess.revision {
+			continue
+		}
+		sess.revision = e.Revision
+		switch {
+		case e.IsReset():
+			sess.send(domain.CollabEvent{Type: domain.CollabEventReset, Revision: e.Revision, Content: e.Content})
+		case e.ClientID == sess.presence.ClientID:
+			sess.send(domain.CollabEvent{Type: domain.CollabEventAck, Revision: e.Revision})
+		default:
+			sess.send(domain.CollabEvent{
+				Type:      domain.CollabEventEdit,
+				Revision:  e.Revision,
+				Operation: e.Operation,
+				Presence:  &author,
+			})
+		}
+	}
+}
+
+// resync sends one session the current document.
+func (h *collabHub) resync(sess *CollabSession) {
+	ctx, cancel := context.WithTimeout(context.Background(), collabTimeout)
+	defer cancel()
+	state, err := h.svc.readState(ctx, h.noteID)
+	if err != nil {
+		h.svc.log.Error("collab: read document", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sess.revision = max(sess.revision, state.Revision)
+	sess.send(domain.CollabEvent{Type: domain.CollabEventReset, Revision: state.Revision, Content: state.Content})
+}
+
+// resyncAll sends every session the current document. h.sync must be held.
+func (h *collabHub) resyncAll(ctx context.Context) {
+	state, err := h.svc.readState(ctx, h.noteID)
+	if err != nil {
+		h.svc.log.Error("collab: read document", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sess := range h.sessions {
+		if sess.revision < state.Revision {
+			sess.revision = state.Revision
+			sess.send(domain.CollabEvent{Type: domain.CollabEventReset, Revision: state.Revision, Content: state.Content})
+		}
+	}
+	h.revision = max(h.revision, state.Revision)
+}
+
+func (h *collabHub) seePeer(p domain.CollabPresence) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.peers[p.ClientID] = &collabPeer{presence: p, seenAt: time.Now()}
+	h.broadcast(p.ClientID, domain.CollabEvent{Type: domain.CollabEventPresence, Presence: &p})
+}
+
+func (h *collabHub) dropPeer(clientID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.forget(clientID)
+}
+
+// forget must be called with mu held.
+func (h *collabHub) forget(clientID string) {
+	p, ok := h.peers[clientID]
+	if !ok {
+		return
+	}
+	delete(h.peers, clientID)
+	h.broadcast(clientID, domain.CollabEvent{Type: domain.CollabEventLeave, Presence: &p.presence})
+}
+
+// broadcast sends ev to every session but the one of clientID. mu must be
+// held.
+func (h *collabHub) broadcast(clientID string, ev domain.CollabEvent) {
+	for id, sess := range h.sessions {
+		if id != clientID {
+			sess.send(ev)
+		}
+	}
+}
+
+// peerList must be called with mu held.
+func (h *collabHub) peerList(except string) []domain.CollabPresence {
+	peers := make([]domain.CollabPresence, 0, len(h.peers))
+	for id, p := range h.peers {
+		if id != except {
+			peers = append(peers, p.presence)
+		}
+	}
+	return peers
+}
+
+// refreshPresence republishes the presence of local clients, so other
+// instances keep them, and forgets remote clients not heard from within the
+// presence TTL.
+func (h *collabHub) refreshPresence() {
+	h.mu.Lock()
+	notices := make([]collabNotice, 0, len(h.sessions))
+	for _, sess := range h.sessions {
+		notices = append(notices, sess.presenceNotice(noticePresence))
+	}
+	for id, p := range h.peers {
+		if _, local := h.sessions[id]; !local && time.Since(p.seenAt) > h.svc.presenceTTL {
+			h.forget(id)
+		}
+	}
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), collabTimeout)
+	defer cancel()
+	for _, n := range notices {
+		if err := h.svc.publish(ctx, n); err != nil {
+			h.svc.log.Error("collab: publish presence", err)
+			return
+		}
+	}
+}
+
+// contentHash is the hex SHA-256 of content, as stored in SyncedHash.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func newClientID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
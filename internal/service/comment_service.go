@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ commentService = (*CommentService)(nil)
+
+// CommentService manages comments on notes. Anyone a note is shared with can
+// read them; commenters, editors and the owner can add them.
+type CommentService struct {
+	comments *repository.CommentRepo
+	notes    *repository.NoteRepo
+}
+
+func NewCommentService(comments *repository.CommentRepo, notes *repository.NoteRepo) *CommentService {
+	return &CommentService{
+		comments: comments,
+		notes:    notes,
+	}
+}
+
+func (s *CommentService) List(ctx context.Context, userID, noteID uint64, limit, offset int) ([]*domain.NoteComment, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessView); err != nil {
+		return nil, 0, err
+	}
+	return s.comments.ListByNote(ctx, noteID, limit, offset)
+}
+
+func (s *CommentService) Add(ctx context.Context, userID, noteID uint64, body string) (*domain.NoteComment, error) {
+	body = strings.TrimSpace(body)
+	if _, err := validator.IsValidComment(body); err != nil {
+		return nil, err
+	}
+	if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessComment); err != nil {
+		return nil, err
+	}
+	if _, err := s.notes.GetByID(ctx, noteID); err != nil {
+		return nil, err
+	}
+
+	c := &domain.NoteComment{
+		NoteID: noteID,
+		UserID: userID,
+		Body:   body,
+	}
+	if err := s.comments.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Delete removes a comment. Authors can delete their own comments and the
+// note's owner can delete any.
+func (s *CommentService) Delete(ctx context.Context, userID, noteID, commentID uint64) error {
+	if commentID == 0 {
+		return domain.ErrInvalidID
+	}
+	if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessView); err != nil {
+		return err
+	}
+	c, err := s.comments.GetByID(ctx, noteID, commentID)
+	if err != nil {
+		return err
+	}
+	if c.UserID != userID {
+		if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessOwner); err != nil {
+			return err
+		}
+	}
+	return s.comments.Delete(ctx, commentID)
+}
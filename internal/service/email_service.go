@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/mail"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ emailService = (*EmailService)(nil)
+
+// EmailService proves ownership of email addresses: it verifies the address
+// given at registration and confirms a new address before it replaces the
+// current one.
+type EmailService struct {
+	tx        repository.Transactor
+	users     *repository.UserRepo
+	tokens    *repository.EmailTokenRepo
+	mailer    mail.Mailer
+	verifyURL string
+	changeURL string
+	ttl       time.Duration
+}
+
+// NewEmailService creates the service. Mailed links are verifyURL or
+// changeURL with the token appended as the "token" query parameter.
+func NewEmailService(tx repository.Transactor, users *repository.UserRepo, tokens *repository.EmailTokenRepo, mailer mail.Mailer, verifyURL, changeURL string, ttl time.Duration) *EmailService {
+	return &EmailService{
+		tx:        tx,
+		users:     users,
+		tokens:    tokens,
+		mailer:    mailer,
+		verifyURL: verifyURL,
+		changeURL: changeURL,
+		ttl:       ttl,
+	}
+}
+
+// SendVerification mails a verification link for the user's current
+// address.
+func (s *EmailService) SendVerification(ctx context.Context, user *domain.User) error {
+	if user.EmailVerified() {
+		return domain.ErrEmailAlreadyVerified
+	}
+	token, err := s.issue(ctx, user.ID, domain.EmailTokenVerify, user.Email)
+	if err != nil {
+		return err
+	}
+	return sendAfterCommit(ctx, s.mailer, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm this is your email address by opening the link below within %s:\n\n%s\n",
+			user.Username, s.ttl, withToken(s.verifyURL, token),
+		),
+	})
+}
+
+// ResendVerification mails a fresh verification link, invalidating the
+// previous one.
+func (s *EmailService) ResendVerification(ctx context.Context, userID uint64) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.SendVerification(ctx, user)
+}
+
+func (s *EmailService) Verify(ctx context.Context, token string) error {
+	if token == "" {
+		return domain.ErrMissingToken
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		t, err := s.use(ctx, domain.EmailTokenVerify, token)
+		if err != nil {
+			return err
+		}
+		if err := s.users.MarkEmailVerified(ctx, t.UserID, t.Email); err != nil {
+			if errors.Is(err, domain.ErrStateViolation) {
+				// the address changed since the link was sent
+				return domain.ErrInvalidToken
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// RequestChange mails a confirmation link to newEmail and a notice to the
+// user's current address. The address changes only once the link is used.
+func (s *EmailService) RequestChange(ctx context.Context, user *domain.User, newEmail string) error {
+	newEmail = normalizeEmail(newEmail)
+	if _, err := validator.IsValidEmail(newEmail); err != nil {
+		return err
+	}
+	if newEmail == user.Email {
+		return domain.ErrNothingToUpdate
+	}
+	if taken, err := s.users.ExistsByEmail(ctx, newEmail); err != nil {
+		return err
+	} else if taken {
+		return domain.ErrEmailAlreadyExists
+	}
+
+	token, err := s.issue(ctx, user.ID, domain.EmailTokenChange, newEmail)
+	if err != nil {
+		return err
+	}
+	if err := sendAfterCommit(ctx, s.mailer, mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nOpen the link below within %s to use this address for your account:\n\n%s\n",
+			user.Username, s.ttl, withToken(s.changeURL, token),
+		),
+	}); err != nil {
+		return err
+	}
+	return sendAfterCommit(ctx, s.mailer, mail.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to change the email address of your account to %s. "+
+				"It changes once the new address is confirmed.\n\n"+
+				"If it wasn't you, change your password right away.\n",
+			user.Username, newEmail,
+		),
+	})
+}
+
+// ConfirmChange switches the account to the address the token was sent to.
+func (s *EmailService) ConfirmChange(ctx context.Context, token string) error {
+	if token == "" {
+		return domain.ErrMissingToken
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		t, err := s.use(ctx, domain.EmailTokenChange, token)
+		if err != nil {
+			return err
+		}
+		return s.users.UpdateEmail(ctx, t.UserID, t.Email)
+	})
+}
+
+func (s *EmailService) issue(ctx context.Context, userID uint64, purpose domain.EmailTokenPurpose, email string) (string, error) {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.tokens.Create(ctx, &domain.EmailToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// use consumes a token; it must run in a transaction.
+func (s *EmailService) use(ctx context.Context, purpose domain.EmailTokenPurpose, token string) (*domain.EmailToken, error) {
+	t, err := s.tokens.GetByHashForUpdate(ctx, purpose, auth.HashOpaqueToken(token))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.UsedAt != nil:
+		return nil, domain.ErrInvalidToken
+	case time.Now().After(t.ExpiresAt):
+		return nil, domain.ErrExpiredToken
+	}
+	if err := s.tokens.MarkUsed(ctx, t.ID); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// withToken appends token to base as the "token" query parameter.
+func withToken(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// sendAfterCommit hands msg to mailer once the transaction carried by ctx
+// has committed, so that a rolled back change never mails a link with a
+// token that was not stored. The message is checked right away.
+func sendAfterCommit(ctx context.Context, mailer mail.Mailer, msg mail.Message) error {
+	if err := mail.Validate(msg); err != nil {
+		return err
+	}
+	repository.AfterCommit(ctx, func() {
+		// delivery errors are the mailer's to report
+		_ = mailer.Send(ctx, msg)
+	})
+	return nil
+}
@@ -2,30 +2,169 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/maqsatto/Notes-API/internal/domain"
 )
 
 type noteService interface {
-	Create(ctx context.Context, userID uint64, title, content string, tags []string) (*domain.Note, error)
-	Update(ctx context.Context, userID, noteID uint64, title, content string, tags []string) (*domain.Note, error)
-	Delete(ctx context.Context, userID, noteID uint64) error
-	PermanentDelete(ctx context.Context, userID, noteID uint64) error
+	Create(ctx context.Context, userID uint64, title, content string, tags []string, notebookID *uint64) (*domain.Note, error)
+	Update(ctx context.Context, userID, noteID uint64, version int64, title, content string, tags []string) (*domain.Note, error)
+	Move(ctx context.Context, userID, noteID uint64, notebookID *uint64) (*domain.Note, error)
+	Delete(ctx context.Context, userID, noteID uint64, version int64) error
+	PermanentDelete(ctx context.Context, userID, noteID uint64, version int64) error
 
 	GetByID(ctx context.Context, userID, noteID uint64) (*domain.Note, error)
-	ListByUser(ctx context.Context, userID uint64, limit, offset int) ([]*domain.Note, int64, error)
-	ListByTags(ctx context.Context, userID uint64, tags []string, limit, offset int) ([]*domain.Note, int64, error)
-	SearchByTitle(ctx context.Context, userID uint64, query string, limit, offset int) ([]*domain.Note, int64, error)
-	SearchByContent(ctx context.Context, userID uint64, query string, limit, offset int) ([]*domain.Note, int64, error)
+	ListByUser(ctx context.Context, userID uint64, scope domain.NoteScope, limit, offset int) ([]*domain.Note, int64, error)
+	ListByTags(ctx context.Context, userID uint64, tags []string, scope domain.NoteScope, limit, offset int) ([]*domain.Note, int64, error)
+	SearchByTitle(ctx context.Context, userID uint64, query string, scope domain.NoteScope, limit, offset int) ([]*domain.Note, int64, error)
+	SearchByContent(ctx context.Context, userID uint64, query string, scope domain.NoteScope, limit, offset int) ([]*domain.Note, int64, error)
+	Search(ctx context.Context, userID uint64, query string, scope domain.NoteScope, limit, offset int) ([]*domain.NoteSearchResult, int64, error)
+
+	ListTrash(ctx context.Context, userID uint64, limit, offset int) ([]*domain.Note, int64, error)
+	Restore(ctx context.Context, userID, noteID uint64) (*domain.Note, error)
+	EmptyTrash(ctx context.Context, userID uint64) (int64, error)
 
 	GetUserNoteCount(ctx context.Context, userID uint64) (int64, error)
 }
 
+type notebookService interface {
+	Create(ctx context.Context, userID uint64, name string, parentID *uint64) (*domain.Notebook, error)
+	List(ctx context.Context, userID uint64) ([]*domain.Notebook, error)
+	GetByID(ctx context.Context, userID, notebookID uint64) (*domain.Notebook, error)
+	Rename(ctx context.Context, userID, notebookID uint64, name string) (*domain.Notebook, error)
+	Move(ctx context.Context, userID, notebookID uint64, parentID *uint64, position *int) (*domain.Notebook, error)
+	Delete(ctx context.Context, userID, notebookID uint64, mode domain.NotebookDeleteMode) error
+}
+
+type shareService interface {
+	ShareNote(ctx context.Context, ownerID, noteID uint64, who string, role domain.ShareRole) (*domain.Share, bool, error)
+	ShareNotebook(ctx context.Context, ownerID, notebookID uint64, who string, role domain.ShareRole) (*domain.Share, bool, error)
+	ListNoteShares(ctx context.Context, ownerID, noteID uint64) ([]*domain.Share, error)
+	ListNotebookShares(ctx context.Context, ownerID, notebookID uint64) ([]*domain.Share, error)
+	RevokeNoteShare(ctx context.Context, userID, noteID, granteeID uint64) error
+	RevokeNotebookShare(ctx context.Context, userID, notebookID, granteeID uint64) error
+	ListSharedNotes(ctx context.Context, userID uint64, limit, offset int) ([]*domain.SharedNote, int64, error)
+	ListSharedNotebooks(ctx context.Context, userID uint64) ([]*domain.SharedNotebook, error)
+}
+
+type commentService interface {
+	List(ctx context.Context, userID, noteID uint64, limit, offset int) ([]*domain.NoteComment, int64, error)
+	Add(ctx context.Context, userID, noteID uint64, body string) (*domain.NoteComment, error)
+	Delete(ctx context.Context, userID, noteID, commentID uint64) error
+}
+
+type shareLinkService interface {
+	Create(ctx context.Context, ownerID, noteID uint64, password string, expiresAt *time.Time) (*domain.ShareLink, string, error)
+	List(ctx context.Context, ownerID, noteID uint64) ([]*domain.ShareLink, error)
+	Revoke(ctx context.Context, ownerID, noteID, linkID uint64) error
+	Open(ctx context.Context, token, password, ip string) (*domain.Note, *domain.ShareLink, error)
+}
+
+type collabService interface {
+	Join(ctx context.Context, userID, noteID uint64, canWrite bool) (*CollabSession, error)
+	HandleNotification(payload string)
+	Resync()
+	Shutdown(ctx context.Context) error
+}
+
+type revisionService interface {
+	List(ctx context.Context, userID, noteID uint64, limit, offset int) ([]*domain.NoteRevision, int64, error)
+	Get(ctx context.Context, userID, noteID uint64, revision int) (*domain.NoteRevision, error)
+	Diff(ctx context.Context, userID, noteID uint64, from, to int) (*domain.NoteRevision, *domain.NoteRevision, string, error)
+	Restore(ctx context.Context, userID, noteID uint64, revision int) (*domain.Note, error)
+}
+
+type tagService interface {
+	ListTags(ctx context.Context, userID uint64) ([]*domain.Tag, error)
+	ListPopularTags(ctx context.Context, userID uint64, limit int) ([]*domain.Tag, error)
+	RenameTag(ctx context.Context, userID uint64, oldName, newName string) (*domain.Tag, bool, error)
+}
+
+type tokenService interface {
+	Issue(ctx context.Context, userID uint64, client domain.ClientInfo) (*domain.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error)
+	Revoke(ctx context.Context, refreshToken string) error
+	CheckSession(ctx context.Context, userID uint64, sessionID string) error
+	ListSessions(ctx context.Context, userID uint64) ([]*domain.Session, error)
+	RevokeSession(ctx context.Context, userID uint64, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userID uint64) error
+}
+
+type twoFactorService interface {
+	Status(ctx context.Context, userID uint64) (bool, int, error)
+	Enroll(ctx context.Context, userID uint64) (*domain.TwoFactorEnrollment, error)
+	Confirm(ctx context.Context, userID uint64, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uint64, password, code string) ([]string, error)
+	Disable(ctx context.Context, userID uint64, password, code string) error
+	StartChallenge(ctx context.Context, userID uint64) (string, time.Time, error)
+	CompleteLogin(ctx context.Context, challengeToken, code string, client domain.ClientInfo) (*domain.LoginResult, error)
+}
+
+type passwordResetService interface {
+	RequestReset(ctx context.Context, email string) error
+	SendRequiredReset(ctx context.Context, user *domain.User) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type emailService interface {
+	SendVerification(ctx context.Context, user *domain.User) error
+	ResendVerification(ctx context.Context, userID uint64) error
+	Verify(ctx context.Context, token string) error
+	RequestChange(ctx context.Context, user *domain.User, newEmail string) error
+	ConfirmChange(ctx context.Context, token string) error
+}
+
+type apiKeyService interface {
+	Create(ctx context.Context, userID uint64, name string, scopes []string, expiresAt *time.Time) (*domain.APIKey, string, error)
+	List(ctx context.Context, userID uint64) ([]*domain.APIKey, error)
+	Revoke(ctx context.Context, userID, keyID uint64) error
+	Authenticate(ctx context.Context, key string) (uint64, []string, error)
+}
+
+type oidcService interface {
+	Providers() []string
+	Start(ctx context.Context, provider string) (string, error)
+	Callback(ctx context.Context, state, code string, client domain.ClientInfo) (*domain.LoginResult, error)
+}
+
+type oauthService interface {
+	RegisterClient(ctx context.Context, ownerID uint64, name string, redirectURIs, scopes []string, confidential bool) (*domain.OAuthClient, string, error)
+	ListClients(ctx context.Context, ownerID uint64) ([]*domain.OAuthClient, error)
+	DeleteClient(ctx context.Context, ownerID uint64, clientID string) error
+
+	Authorize(ctx context.Context, userID uint64, req domain.AuthorizationRequest) (*domain.OAuthClient, []string, bool, error)
+	Decide(ctx context.Context, userID uint64, req domain.AuthorizationRequest, approved bool) (string, error)
+
+	Exchange(ctx context.Context, clientID, clientSecret, code, redirectURI, verifier string, info domain.ClientInfo) (*domain.TokenPair, error)
+	Refresh(ctx context.Context, clientID, clientSecret, refreshToken string) (*domain.TokenPair, error)
+	Introspect(ctx context.Context, clientID, clientSecret, token, hint string) (*domain.TokenIntrospection, error)
+	Revoke(ctx context.Context, clientID, clientSecret, token, hint string) error
+
+	ListAuthorizedApps(ctx context.Context, userID uint64) ([]*domain.OAuthGrant, error)
+	RevokeAuthorizedApp(ctx context.Context, userID uint64, clientID string) error
+}
+
+type adminService interface {
+	ListUsers(ctx context.Context, filter domain.UserFilter, limit, offset int) ([]*domain.UserSummary, int64, error)
+	GetUser(ctx context.Context, userID uint64) (*domain.UserSummary, error)
+	Suspend(ctx context.Context, actorID, userID uint64) error
+	Unsuspend(ctx context.Context, actorID, userID uint64) error
+	ForcePasswordReset(ctx context.Context, actorID, userID uint64) error
+	Restore(ctx context.Context, actorID, userID uint64) (*domain.UserSummary, error)
+	SetRole(ctx context.Context, actorID, userID uint64, role string) error
+
+	ListRoles(ctx context.Context) ([]*domain.Role, error)
+	SaveRole(ctx context.Context, actorID uint64, role *domain.Role) error
+	DeleteRole(ctx context.Context, actorID uint64, name string) error
+}
+
 type userService interface {
 	Register(ctx context.Context, username, email, password string) (*domain.User, error)
-	Login(ctx context.Context, emailOrUsername, password string) (*domain.User, string, error)
+	Login(ctx context.Context, emailOrUsername, password string, client domain.ClientInfo) (*domain.LoginResult, error)
 	UpdateProfile(ctx context.Context, userID uint64, username, email string) (*domain.User, error)
 	ChangePassword(ctx context.Context, userID uint64, oldPassword, newPassword string) error
+	UpdateSearchLanguage(ctx context.Context, userID uint64, language string) error
 
 	DeleteAccount(ctx context.Context, userID uint64) error
 	PermanentDeleteAccount(ctx context.Context, userID uint64) error
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+)
+
+// LoginPolicy tunes LoginGuard. Thresholds of zero disable the lockout of
+// that kind of key.
+type LoginPolicy struct {
+	// FreeAttempts failures in a row are allowed without delay. Each further
+	// failure doubles the wait before the next attempt, starting at
+	// BaseDelay and capped at MaxDelay.
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+
+	// AccountThreshold and IPThreshold failures within Window lock the
+	// account or client address for LockDuration.
+	AccountThreshold int
+	IPThreshold      int
+	LockDuration     time.Duration
+	Window           time.Duration
+}
+
+// LoginGuard throttles password guessing. Failures are counted per account
+// and per client address, so both a single account under attack and a single
+// client trying many accounts are slowed down and eventually locked out.
+// Callers name known accounts with userIdentifier.
+type LoginGuard struct {
+	store  repository.LoginAttemptStore
+	audit  *repository.AuditRepo
+	policy LoginPolicy
+}
+
+func NewLoginGuard(store repository.LoginAttemptStore, audit *repository.AuditRepo, policy LoginPolicy) *LoginGuard {
+	return &LoginGuard{
+		store:  store,
+		audit:  audit,
+		policy: policy,
+	}
+}
+
+// Check returns a *domain.RetryAfterError when a login for identifier from
+// ip must not be attempted yet.
+func (g *LoginGuard) Check(ctx context.Context, identifier, ip string) error {
+	now := time.Now()
+	for _, key := range g.keys(identifier, ip) {
+		a, err := g.store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if a.LockedUntil != nil && now.Before(*a.LockedUntil) {
+			lockErr := domain.ErrTooManyAttempts
+			if isAccountKey(key) {
+				lockErr = domain.ErrAccountLocked
+			}
+			return &domain.RetryAfterError{Err: lockErr, After: a.LockedUntil.Sub(now)}
+		}
+		if a.Failures == 0 || now.Sub(a.LastFailureAt) > g.policy.Window {
+			continue
+		}
+		if wait := a.LastFailureAt.Add(g.delay(a.Failures)).Sub(now); wait > 0 {
+			return &domain.RetryAfterError{Err: domain.ErrTooManyAttempts, After: wait}
+		}
+	}
+	return nil
+}
+
+// Fail counts a failed login and locks the keys that reached their
+// threshold. userID is the account the identifier belongs to, if any.
+func (g *LoginGuard) Fail(ctx context.Context, identifier, ip string, userID *uint64) error {
+	for _, key := range g.keys(identifier, ip) {
+		a, err := g.store.RecordFailure(ctx, key, g.policy.Window)
+		if err != nil {
+			return err
+		}
+		threshold := g.policy.IPThreshold
+		if isAccountKey(key) {
+			threshold = g.policy.AccountThreshold
+		}
+		if threshold <= 0 || a.Failures < threshold {
+			continue
+		}
+
+		until := time.Now().Add(g.policy.LockDuration)
+		if err := g.store.Lock(ctx, key, until); err != nil {
+			return err
+		}
+		entry := &domain.AuditEntry{
+			Action:  domain.AuditLoginLockout,
+			IP:      ip,
+			Details: fmt.Sprintf("%s locked until %s after %d failed attempts", key, until.UTC().Format(time.RFC3339), a.Failures),
+		}
+		if isAccountKey(key) {
+			entry.UserID = userID
+		}
+		if err := g.audit.Record(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Succeed clears the failures of the account. The client address keeps its
+// count so that one valid login does not reset a spraying attack.
+func (g *LoginGuard) Succeed(ctx context.Context, identifier string) error {
+	return g.store.Reset(ctx, accountKey(identifier))
+}
+
+// delay is the wait after the given number of consecutive failures.
+func (g *LoginGuard) delay(failures int) time.Duration {
+	n := failures - g.policy.FreeAttempts
+	if n <= 0 || g.policy.BaseDelay <= 0 {
+		return 0
+	}
+	d := g.policy.BaseDelay
+	for i := 1; i < n && d < g.policy.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, g.policy.MaxDelay)
+}
+
+func (g *LoginGuard) keys(identifier, ip string) []string {
+	keys := []string{accountKey(identifier)}
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	return keys
+}
+
+// userIdentifier is the identifier logins to a known account are counted
+// against, so that its email address and username share one counter. Names
+// that match no account are counted under "name:" so that they cannot
+// collide with it.
+func userIdentifier(userID uint64) string {
+	return "user:" + strconv.FormatUint(userID, 10)
+}
+
+func accountKey(identifier string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+func isAccountKey(key string) bool {
+	return strings.HasPrefix(key, "account:")
+}
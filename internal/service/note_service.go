@@ -1 +1,373 @@
 package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ noteService = (*NoteService)(nil)
+
+type NoteService struct {
+	tx        repository.Transactor
+	notes     *repository.NoteRepo
+	notebooks *repository.NotebookRepo
+	users     *repository.UserRepo
+
+	requireVerifiedEmail bool
+}
+
+// NewNoteService creates the service. With requireVerifiedEmail set, users
+// must verify their email address before they can create notes.
+func NewNoteService(tx repository.Transactor, notes *repository.NoteRepo, notebooks *repository.NotebookRepo, users *repository.UserRepo, requireVerifiedEmail bool) *NoteService {
+	return &NoteService{
+		tx:                   tx,
+		notes:                notes,
+		notebooks:            notebooks,
+		users:                users,
+		requireVerifiedEmail: requireVerifiedEmail,
+	}
+}
+
+// Create adds a note, filed in notebookID unless that is nil.
+func (s *NoteService) Create(ctx context.Context, userID uint64, title, content string, tags []string, notebookID *uint64) (*domain.Note, error) {
+	if userID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	title = strings.TrimSpace(title)
+	if err := validator.IsValidNote(title, content); err != nil {
+		return nil, err
+	}
+	tags = normalizeTags(tags)
+	if _, err := validator.IsValidTags(tags); err != nil {
+		return nil, err
+	}
+	if s.requireVerifiedEmail {
+		user, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !user.EmailVerified() {
+			return nil, domain.ErrEmailNotVerified
+		}
+	}
+	if notebookID != nil {
+		if _, err := authorizeNotebook(ctx, s.notebooks, userID, *notebookID, domain.AccessOwner); err != nil {
+			return nil, err
+		}
+	}
+
+	note := &domain.Note{
+		UserID:     userID,
+		NotebookID: notebookID,
+		Title:      title,
+		Content:    content,
+		Tags:       tags,
+	}
+	if err := s.notes.Create(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Update replaces a note's title, content and tags. A non-zero version is the
+// version the client last saw; the update fails with ErrNoteVersionMismatch
+// if the note has changed since.
+func (s *NoteService) Update(ctx context.Context, userID, noteID uint64, version int64, title, content string, tags []string) (*domain.Note, error) {
+	title = strings.TrimSpace(title)
+	if err := validator.IsValidNote(title, content); err != nil {
+		return nil, err
+	}
+	tags = normalizeTags(tags)
+	if _, err := validator.IsValidTags(tags); err != nil {
+		return nil, err
+	}
+
+	var note *domain.Note
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessEdit); err != nil {
+			return err
+		}
+		var err error
+		if note, err = s.notes.GetByID(ctx, noteID); err != nil {
+			return err
+		}
+		if err := checkVersion(note, version); err != nil {
+			return err
+		}
+
+		note.Title = title
+		note.Content = content
+		note.Tags = tags
+		return staleWrite(s.notes.Update(ctx, note), version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Move files a note in a notebook, or in none when notebookID is nil.
+func (s *NoteService) Move(ctx context.Context, userID, noteID uint64, notebookID *uint64) (*domain.Note, error) {
+	var note *domain.Note
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessOwner); err != nil {
+			return err
+		}
+		if notebookID != nil {
+			if _, err := authorizeNotebook(ctx, s.notebooks, userID, *notebookID, domain.AccessOwner); err != nil {
+				return err
+			}
+		}
+		if err := s.notes.Move(ctx, noteID, notebookID); err != nil {
+			return err
+		}
+		var err error
+		note, err = s.notes.GetByID(ctx, noteID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Delete moves a note to the trash. A non-zero version must be the note's
+// current version, as for Update.
+func (s *NoteService) Delete(ctx context.Context, userID, noteID uint64, version int64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessOwner); err != nil {
+			return err
+		}
+		note, err := s.notes.GetByID(ctx, noteID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(note, version); err != nil {
+			return err
+		}
+		return staleWrite(s.notes.SoftDelete(ctx, noteID, note.Version), version)
+	})
+}
+
+// PermanentDelete removes a note for good, whether or not it is in the trash.
+// A non-zero version must be the note's current version, as for Update.
+func (s *NoteService) PermanentDelete(ctx context.Context, userID, noteID uint64, version int64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		note, err := s.getWithDeleted(ctx, userID, noteID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(note, version); err != nil {
+			return err
+		}
+		return staleWrite(s.notes.HardDelete(ctx, noteID, note.Version), version)
+	})
+}
+
+func (s *NoteService) ListTrash(ctx context.Context, userID uint64, limit, offset int) ([]*domain.Note, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return s.notes.ListDeletedByUserID(ctx, userID, limit, offset)
+}
+
+func (s *NoteService) Restore(ctx context.Context, userID, noteID uint64) (*domain.Note, error) {
+	var note *domain.Note
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		n, err := s.getWithDeleted(ctx, userID, noteID)
+		if err != nil {
+			return err
+		}
+		if n.DeletedAt == nil {
+			return domain.ErrStateViolation
+		}
+		if err := s.notes.Restore(ctx, noteID); err != nil {
+			return err
+		}
+		note, err = s.notes.GetByID(ctx, noteID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// EmptyTrash permanently deletes every trashed note of the user and returns
+// how many were removed.
+func (s *NoteService) EmptyTrash(ctx context.Context, userID uint64) (int64, error) {
+	if userID == 0 {
+		return 0, domain.ErrInvalidID
+	}
+	return s.notes.EmptyTrash(ctx, userID)
+}
+
+// checkVersion fails with ErrNoteVersionMismatch unless version is zero,
+// meaning the client made no precondition, or the note's current version.
+func checkVersion(note *domain.Note, version int64) error {
+	if version != 0 && note.Version != version {
+		return domain.ErrNoteVersionMismatch
+	}
+	return nil
+}
+
+// staleWrite reports a write that lost a race with a concurrent change as a
+// plain conflict when the client did not ask for a particular version.
+func staleWrite(err error, version int64) error {
+	if version == 0 && errors.Is(err, domain.ErrNoteVersionMismatch) {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+// getWithDeleted returns one of the user's own notes, trashed or not.
+func (s *NoteService) getWithDeleted(ctx context.Context, userID, noteID uint64) (*domain.Note, error) {
+	if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessOwner); err != nil {
+		return nil, err
+	}
+	return s.notes.GetWithDeleted(ctx, noteID)
+}
+
+// GetByID returns one of the user's notes or a note shared with them.
+func (s *NoteService) GetByID(ctx context.Context, userID, noteID uint64) (*domain.Note, error) {
+	if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessView); err != nil {
+		return nil, err
+	}
+	return s.notes.GetByID(ctx, noteID)
+}
+
+// ListByUser lists the user's own notes, or with a notebook scope the notes
+// in it, which may belong to whoever shared the notebook with the user.
+func (s *NoteService) ListByUser(ctx context.Context, userID uint64, scope domain.NoteScope, limit, offset int) ([]*domain.Note, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	notebookIDs, err := s.scopeNotebookIDs(ctx, userID, scope)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.notes.ListByUserID(ctx, userID, notebookIDs, limit, offset)
+}
+
+func (s *NoteService) ListByTags(ctx context.Context, userID uint64, tags []string, scope domain.NoteScope, limit, offset int) ([]*domain.Note, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	tags = normalizeTags(tags)
+	if len(tags) == 0 {
+		return s.ListByUser(ctx, userID, scope, limit, offset)
+	}
+	if _, err := validator.IsValidTags(tags); err != nil {
+		return nil, 0, err
+	}
+	notebookIDs, err := s.scopeNotebookIDs(ctx, userID, scope)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.notes.ListByTags(ctx, userID, tags, notebookIDs, limit, offset)
+}
+
+func (s *NoteService) SearchByTitle(ctx context.Context, userID uint64, query string, scope domain.NoteScope, limit, offset int) ([]*domain.Note, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, 0, domain.ErrInvalidSearchQuery
+	}
+	notebookIDs, err := s.scopeNotebookIDs(ctx, userID, scope)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.notes.SearchByTitle(ctx, userID, query, notebookIDs, limit, offset)
+}
+
+func (s *NoteService) SearchByContent(ctx context.Context, userID uint64, query string, scope domain.NoteScope, limit, offset int) ([]*domain.Note, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, 0, domain.ErrInvalidSearchQuery
+	}
+	notebookIDs, err := s.scopeNotebookIDs(ctx, userID, scope)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.notes.SearchByContent(ctx, userID, query, notebookIDs, limit, offset)
+}
+
+// Search runs a ranked full-text search over the user's notes.
+func (s *NoteService) Search(ctx context.Context, userID uint64, query string, scope domain.NoteScope, limit, offset int) ([]*domain.NoteSearchResult, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	query = strings.TrimSpace(query)
+	if query == "" || len(query) > validator.MaxSearchQueryLength {
+		return nil, 0, domain.ErrInvalidSearchQuery
+	}
+	notebookIDs, err := s.scopeNotebookIDs(ctx, userID, scope)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.notes.Search(ctx, userID, query, notebookIDs, limit, offset)
+}
+
+// scopeNotebookIDs resolves a scope to the notebooks whose notes it covers,
+// nil meaning all of the user's own notes.
+func (s *NoteService) scopeNotebookIDs(ctx context.Context, userID uint64, scope domain.NoteScope) ([]uint64, error) {
+	if scope.NotebookID == 0 {
+		return nil, nil
+	}
+	if _, err := authorizeNotebook(ctx, s.notebooks, userID, scope.NotebookID, domain.AccessView); err != nil {
+		return nil, err
+	}
+	if !scope.Recursive {
+		return []uint64{scope.NotebookID}, nil
+	}
+	return s.notebooks.SubtreeIDs(ctx, scope.NotebookID)
+}
+
+func (s *NoteService) GetUserNoteCount(ctx context.Context, userID uint64) (int64, error) {
+	return s.notes.CountByUserID(ctx, userID)
+}
+
+// authorizeNote checks that the user has at least the access need to the
+// note, which may be in the trash. Every note operation goes through it.
+func authorizeNote(ctx context.Context, notes *repository.NoteRepo, userID, noteID uint64, need domain.NoteAccess) error {
+	if userID == 0 || noteID == 0 {
+		return domain.ErrInvalidID
+	}
+	access, err := notes.AccessLevel(ctx, userID, noteID)
+	if err != nil {
+		return err
+	}
+	if access < need {
+		return domain.ErrNoteAccessDenied
+	}
+	return nil
+}
+
+// normalizeTags trims, lowercases and de-duplicates tag names, keeping the
+// order in which they were first given.
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ notebookService = (*NotebookService)(nil)
+
+// NotebookService manages the tree of notebooks notes are filed in. Changes
+// to a user's tree take a lock on it, so moves checked to be free of cycles
+// stay that way.
+type NotebookService struct {
+	tx        repository.Transactor
+	notebooks *repository.NotebookRepo
+}
+
+func NewNotebookService(tx repository.Transactor, notebooks *repository.NotebookRepo) *NotebookService {
+	return &NotebookService{
+		tx:        tx,
+		notebooks: notebooks,
+	}
+}
+
+// Create adds a notebook at the end of parentID's sub-notebooks, or of the
+// top level when parentID is nil.
+func (s *NotebookService) Create(ctx context.Context, userID uint64, name string, parentID *uint64) (*domain.Notebook, error) {
+	if userID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	name = strings.TrimSpace(name)
+	if _, err := validator.IsValidNotebookName(name); err != nil {
+		return nil, err
+	}
+
+	nb := &domain.Notebook{
+		UserID:   userID,
+		ParentID: parentID,
+		Name:     name,
+	}
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.notebooks.LockTree(ctx, userID); err != nil {
+			return err
+		}
+		if parentID != nil {
+			if _, err := authorizeNotebook(ctx, s.notebooks, userID, *parentID, domain.AccessOwner); err != nil {
+				return err
+			}
+		}
+		return s.notebooks.Create(ctx, nb)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nb, nil
+}
+
+// List returns all of the user's notebooks, siblings in order.
+func (s *NotebookService) List(ctx context.Context, userID uint64) ([]*domain.Notebook, error) {
+	if userID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	return s.notebooks.ListByUserID(ctx, userID)
+}
+
+// GetByID returns one of the user's notebooks or one shared with them.
+func (s *NotebookService) GetByID(ctx context.Context, userID, notebookID uint64) (*domain.Notebook, error) {
+	return authorizeNotebook(ctx, s.notebooks, userID, notebookID, domain.AccessView)
+}
+
+func (s *NotebookService) Rename(ctx context.Context, userID, notebookID uint64, name string) (*domain.Notebook, error) {
+	name = strings.TrimSpace(name)
+	if _, err := validator.IsValidNotebookName(name); err != nil {
+		return nil, err
+	}
+
+	var nb *domain.Notebook
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if nb, err = authorizeNotebook(ctx, s.notebooks, userID, notebookID, domain.AccessOwner); err != nil {
+			return err
+		}
+		nb.Name = name
+		return s.notebooks.Rename(ctx, nb)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nb, nil
+}
+
+// Move places a notebook and everything in it under parentID (nil for the top
+// level) at position among its new siblings, or after them when position is
+// nil. A notebook cannot be moved into itself or one of its sub-notebooks.
+func (s *NotebookService) Move(ctx context.Context, userID, notebookID uint64, parentID *uint64, position *int) (*domain.Notebook, error) {
+	pos := math.MaxInt32
+	if position != nil {
+		if *position < 0 {
+			return nil, domain.ErrInvalidInput
+		}
+		pos = min(*position, math.MaxInt32)
+	}
+
+	var nb *domain.Notebook
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.notebooks.LockTree(ctx, userID); err != nil {
+			return err
+		}
+		var err error
+		if nb, err = authorizeNotebook(ctx, s.notebooks, userID, notebookID, domain.AccessOwner); err != nil {
+			return err
+		}
+		if parentID != nil {
+			if _, err := authorizeNotebook(ctx, s.notebooks, userID, *parentID, domain.AccessOwner); err != nil {
+				return err
+			}
+			subtree, err := s.notebooks.SubtreeIDs(ctx, notebookID)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(subtree, *parentID) {
+				return domain.ErrNotebookCycle
+			}
+		}
+		return s.notebooks.Move(ctx, nb, parentID, pos)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nb, nil
+}
+
+// Delete removes a notebook. With NotebookDeleteMoveNotes its notes and
+// sub-notebooks move up into its parent; with NotebookDeleteTrashNotes the
+// sub-notebooks are deleted too and all their notes go to the trash.
+func (s *NotebookService) Delete(ctx context.Context, userID, notebookID uint64, mode domain.NotebookDeleteMode) error {
+	switch mode {
+	case domain.NotebookDeleteMoveNotes, domain.NotebookDeleteTrashNotes:
+	default:
+		return domain.ErrInvalidInput
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.notebooks.LockTree(ctx, userID); err != nil {
+			return err
+		}
+		nb, err := authorizeNotebook(ctx, s.notebooks, userID, notebookID, domain.AccessOwner)
+		if err != nil {
+			return err
+		}
+
+		if mode == domain.NotebookDeleteMoveNotes {
+			return s.notebooks.DeleteMovingContents(ctx, nb)
+		}
+		subtree, err := s.notebooks.SubtreeIDs(ctx, notebookID)
+		if err != nil {
+			return err
+		}
+		_, err = s.notebooks.DeleteTrashingContents(ctx, nb, subtree)
+		return err
+	})
+}
+
+// authorizeNotebook returns the notebook with the given id if the user has at
+// least the access need to the notes in it. Only owners can change notebooks
+// and file notes in them; shares let others work with the notes inside.
+func authorizeNotebook(ctx context.Context, notebooks *repository.NotebookRepo, userID, notebookID uint64, need domain.NoteAccess) (*domain.Notebook, error) {
+	if userID == 0 || notebookID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	access, err := notebooks.AccessLevel(ctx, userID, notebookID)
+	if err != nil {
+		return nil, err
+	}
+	if access < need {
+		return nil, domain.ErrNotebookAccessDenied
+	}
+	return notebooks.GetByID(ctx, notebookID)
+}
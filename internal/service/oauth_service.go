@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+)
+
+var _ oauthService = (*OAuthService)(nil)
+
+const (
+	maxOAuthClientNameLength = 100
+	maxRedirectURIs          = 10
+)
+
+// OAuthService makes the API an OAuth 2.0 authorization server. Users
+// register third-party apps as clients; an app sends a user to the consent
+// screen, trades the authorization code it gets back for tokens limited to
+// the approved scopes, and can introspect (RFC 7662) and revoke (RFC 7009)
+// them. Only the authorization code flow with PKCE (S256) is offered.
+//
+// Every token pair lives in a session of its own, so an app's access ends
+// like any login: on revocation, password change or suspension.
+type OAuthService struct {
+	tx      repository.Transactor
+	clients *repository.OAuthClientRepo
+	codes   *repository.OAuthCodeRepo
+	grants  *repository.OAuthGrantRepo
+	users   *repository.UserRepo
+	tokens  *TokenService
+	codeTTL time.Duration
+}
+
+func NewOAuthService(tx repository.Transactor, clients *repository.OAuthClientRepo, codes *repository.OAuthCodeRepo, grants *repository.OAuthGrantRepo, users *repository.UserRepo, tokens *TokenService, codeTTL time.Duration) *OAuthService {
+	return &OAuthService{
+		tx:      tx,
+		clients: clients,
+		codes:   codes,
+		grants:  grants,
+		users:   users,
+		tokens:  tokens,
+		codeTTL: codeTTL,
+	}
+}
+
+// RegisterClient registers an app owned by the user. Confidential clients
+// get a secret, returned only here.
+func (s *OAuthService) RegisterClient(ctx context.Context, ownerID uint64, name string, redirectURIs, scopes []string, confidential bool) (*domain.OAuthClient, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxOAuthClientNameLength {
+		return nil, "", domain.ErrInvalidInput
+	}
+	if len(redirectURIs) == 0 || len(redirectURIs) > maxRedirectURIs {
+		return nil, "", domain.ErrOAuthInvalidRedirectURI
+	}
+	for _, uri := range redirectURIs {
+		if !validRedirectURI(uri) {
+			return nil, "", domain.ErrOAuthInvalidRedirectURI
+		}
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &domain.OAuthClient{
+		ID:           strings.ToLower(rand.Text()),
+		OwnerID:      ownerID,
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+	}
+	var secret string
+	if confidential {
+		if secret, client.SecretHash, err = auth.NewOpaqueToken(); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := s.clients.Create(ctx, client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+func (s *OAuthService) ListClients(ctx context.Context, ownerID uint64) ([]*domain.OAuthClient, error) {
+	return s.clients.ListByOwner(ctx, ownerID)
+}
+
+// DeleteClient removes an app and with it every user's access through it.
+func (s *OAuthService) DeleteClient(ctx context.Context, ownerID uint64, clientID string) error {
+	return s.clients.Delete(ctx, ownerID, clientID)
+}
+
+// Authorize validates a client's request for the consent screen. It returns
+// the client, the scopes asked for and whether the user approved them all
+// before.
+func (s *OAuthService) Authorize(ctx context.Context, userID uint64, req domain.AuthorizationRequest) (*domain.OAuthClient, []string, bool, error) {
+	client, _, scopes, err := s.validateRequest(ctx, req)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	grant, err := s.grants.Get(ctx, userID, client.ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrOAuthGrantNotFound) {
+			return client, scopes, false, nil
+		}
+		return nil, nil, false, err
+	}
+	return client, scopes, grant.Covers(scopes), nil
+}
+
+// Decide records the user's answer on the consent screen and returns where
+// to send them: back to the client with an authorization code, or with
+// error=access_denied if they declined.
+func (s *OAuthService) Decide(ctx context.Context, userID uint64, req domain.AuthorizationRequest, approved bool) (string, error) {
+	client, redirectURI, scopes, err := s.validateRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !approved {
+		return withQuery(redirectURI, "error", "access_denied", "state", req.State), nil
+	}
+
+	code, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.grants.Grant(ctx, userID, client.ID, scopes); err != nil {
+			return err
+		}
+		return s.codes.Create(ctx, &domain.OAuthAuthorizationCode{
+			CodeHash:         hash,
+			ClientID:         client.ID,
+			UserID:           userID,
+			RedirectURI:      redirectURI,
+			RedirectURIGiven: req.RedirectURI != "",
+			Scopes:           scopes,
+			CodeChallenge:    req.CodeChallenge,
+			ExpiresAt:        time.Now().Add(s.codeTTL),
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return withQuery(redirectURI, "code", code, "state", req.State), nil
+}
+
+// validateRequest checks an authorization request and resolves its redirect
+// URI, which may be left out when the client registered only one, and its
+// scopes, which default to all the client may ask for.
+func (s *OAuthService) validateRequest(ctx context.Context, req domain.AuthorizationRequest) (*domain.OAuthClient, string, []string, error) {
+	client, err := s.clients.GetByID(ctx, req.ClientID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return nil, "", nil, domain.ErrOAuthInvalidRedirectURI
+	}
+	if req.ResponseType != "code" {
+		return nil, "", nil, domain.ErrInvalidInput
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, "", nil, domain.ErrOAuthPKCERequired
+	}
+
+	scopes := client.Scopes
+	if len(req.Scopes) > 0 {
+		if scopes, err = normalizeScopes(req.Scopes); err != nil {
+			return nil, "", nil, err
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(client.Scopes, scope) {
+				return nil, "", nil, domain.ErrInvalidScope
+			}
+		}
+	}
+	return client, redirectURI, scopes, nil
+}
+
+// Exchange redeems an authorization code for tokens (grant_type
+// authorization_code). redirectURI must be the one the authorization request
+// named, and may only be left out if that request left it out too (RFC 6749
+// section 4.1.3).
+func (s *OAuthService) Exchange(ctx context.Context, clientID, clientSecret, code, redirectURI, verifier string, info domain.ClientInfo) (*domain.TokenPair, error) {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" || verifier == "" {
+		return nil, domain.ErrOAuthInvalidGrant
+	}
+
+	// consumed outside of any transaction so a failed attempt still burns it
+	grant, err := s.codes.Consume(ctx, auth.HashOpaqueToken(code))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case grant.ClientID != client.ID,
+		time.Now().After(grant.ExpiresAt),
+		(grant.RedirectURIGiven || redirectURI != "") && redirectURI != grant.RedirectURI,
+		subtle.ConstantTimeCompare([]byte(auth.PKCEChallenge(verifier)), []byte(grant.CodeChallenge)) != 1:
+		return nil, domain.ErrOAuthInvalidGrant
+	}
+	// the user may have revoked the app since approving
+	if _, err := s.grants.Get(ctx, grant.UserID, client.ID); err != nil {
+		if errors.Is(err, domain.ErrOAuthGrantNotFound) {
+			return nil, domain.ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+
+	pair, err := s.tokens.IssueForClient(ctx, grant.UserID, client, grant.Scopes, info)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) || errors.Is(err, domain.ErrAccountSuspended) {
+			return nil, domain.ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Refresh rotates a refresh token issued to the client (grant_type
+// refresh_token).
+func (s *OAuthService) Refresh(ctx context.Context, clientID, clientSecret, refreshToken string) (*domain.TokenPair, error) {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := s.tokens.RefreshForClient(ctx, refreshToken, client.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrMissingToken),
+			errors.Is(err, domain.ErrInvalidToken),
+			errors.Is(err, domain.ErrExpiredToken),
+			errors.Is(err, domain.ErrRevokedToken),
+			errors.Is(err, domain.ErrTokenReused),
+			errors.Is(err, domain.ErrAccountSuspended):
+			return nil, domain.ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Introspect describes an access or refresh token to the client it was
+// issued to. Tokens that are not live or belong to someone else are simply
+// inactive.
+func (s *OAuthService) Introspect(ctx context.Context, clientID, clientSecret, token, hint string) (*domain.TokenIntrospection, error) {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	result, session, err := s.lookupToken(ctx, token, hint)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || sessionClientID(session) != client.ID {
+		return &domain.TokenIntrospection{}, nil
+	}
+
+	user, err := s.users.GetByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return &domain.TokenIntrospection{}, nil
+		}
+		return nil, err
+	}
+	result.Active = true
+	result.ClientID = client.ID
+	result.UserID = user.ID
+	result.Username = user.Username
+	result.Scopes = session.Scopes
+	return result, nil
+}
+
+// Revoke ends the session of an access or refresh token issued to the
+// client. As RFC 7009 asks, unknown tokens are not an error.
+func (s *OAuthService) Revoke(ctx context.Context, clientID, clientSecret, token, hint string) error {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return err
+	}
+	result, session, err := s.lookupToken(ctx, token, hint)
+	if err != nil || result == nil || sessionClientID(session) != client.ID {
+		return err
+	}
+	if err := s.tokens.RevokeSession(ctx, session.UserID, session.ID); err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// lookupToken finds the live session of an access or refresh token, trying
+// the kind hinted at first. The result is nil if the token is not live.
+func (s *OAuthService) lookupToken(ctx context.Context, token, hint string) (*domain.TokenIntrospection, *domain.Session, error) {
+	if token == "" {
+		return nil, nil, nil
+	}
+	lookups := []func() (*domain.TokenIntrospection, *domain.Session, error){
+		func() (*domain.TokenIntrospection, *domain.Session, error) {
+			claims, session, err := s.tokens.AccessTokenSession(ctx, token)
+			if err != nil {
+				return nil, nil, err
+			}
+			return &domain.TokenIntrospection{
+				TokenType: "Bearer",
+				IssuedAt:  claims.IssuedAt.Time,
+				ExpiresAt: claims.ExpiresAt.Time,
+			}, session, nil
+		},
+		func() (*domain.TokenIntrospection, *domain.Session, error) {
+			refresh, session, err := s.tokens.RefreshTokenSession(ctx, token)
+			if err != nil {
+				return nil, nil, err
+			}
+			return &domain.TokenIntrospection{
+				TokenType: "refresh_token",
+				IssuedAt:  refresh.CreatedAt,
+				ExpiresAt: refresh.ExpiresAt,
+			}, session, nil
+		},
+	}
+	if hint == "refresh_token" {
+		slices.Reverse(lookups)
+	}
+	for _, lookup := range lookups {
+		result, session, err := lookup()
+		switch {
+		case err == nil:
+			return result, session, nil
+		case !errors.Is(err, domain.ErrInvalidToken):
+			return nil, nil, err
+		}
+	}
+	return nil, nil, nil
+}
+
+// ListAuthorizedApps returns the apps the user granted access to.
+func (s *OAuthService) ListAuthorizedApps(ctx context.Context, userID uint64) ([]*domain.OAuthGrant, error) {
+	return s.grants.ListByUserID(ctx, userID)
+}
+
+// RevokeAuthorizedApp withdraws the user's consent for an app and ends its
+// sessions; it has to ask again to regain access.
+func (s *OAuthService) RevokeAuthorizedApp(ctx context.Context, userID uint64, clientID string) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.grants.Delete(ctx, userID, clientID); err != nil {
+			return err
+		}
+		return s.tokens.RevokeClientSessions(ctx, userID, clientID)
+	})
+}
+
+// authenticateClient checks the client's credentials: the secret for
+// confidential clients, none for public ones.
+func (s *OAuthService) authenticateClient(ctx context.Context, clientID, clientSecret string) (*domain.OAuthClient, error) {
+	if clientID == "" {
+		return nil, domain.ErrOAuthInvalidClient
+	}
+	client, err := s.clients.GetByID(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, domain.ErrOAuthClientNotFound) {
+			return nil, domain.ErrOAuthInvalidClient
+		}
+		return nil, err
+	}
+	if !client.Confidential() {
+		if clientSecret != "" {
+			return nil, domain.ErrOAuthInvalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashOpaqueToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, domain.ErrOAuthInvalidClient
+	}
+	return client, nil
+}
+
+// validRedirectURI accepts absolute URIs without a fragment: https ones,
+// http ones on the loopback interface and private-use schemes of native
+// apps, such as com.example.app:/callback (RFC 8252).
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	return strings.Contains(u.Scheme, ".")
+}
+
+// withQuery adds query parameters, given as name/value pairs, to a URI.
+// Empty values are left out.
+func withQuery(uri string, pairs ...string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	q := u.Query()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			q.Set(pairs[i], pairs[i+1])
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
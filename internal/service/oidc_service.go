@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ oidcService = (*OIDCService)(nil)
+
+// usernameAttempts bounds the search for a free username for new accounts.
+const usernameAttempts = 5
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// OIDCService logs users in through external OpenID Connect providers. The
+// provider's account is matched to a user by its linked identity, then by
+// verified email address; failing both, a new account is created. Accounts
+// created this way have no password until their owner resets one.
+type OIDCService struct {
+	tx         repository.Transactor
+	users      oidcUserStore
+	identities oidcIdentityStore
+	states     *repository.OIDCStateRepo
+	audit      auditRecorder
+	tokens     *TokenService
+	twoFactor  *TwoFactorService
+	providers  map[string]*auth.OIDCProvider
+	stateTTL   time.Duration
+}
+
+// oidcUserStore, oidcIdentityStore and auditRecorder are the parts of the
+// repositories that resolveUser works with.
+type oidcUserStore interface {
+	Create(ctx context.Context, user *domain.User) error
+	GetByID(ctx context.Context, id uint64) (*domain.User, error)
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+	ExistsByUsername(ctx context.Context, username string) (bool, error)
+	MarkEmailVerified(ctx context.Context, id uint64, email string) error
+}
+
+type oidcIdentityStore interface {
+	Create(ctx context.Context, identity *domain.Identity) error
+	GetBySubject(ctx context.Context, provider, subject string) (*domain.Identity, error)
+	TouchLogin(ctx context.Context, id uint64, email string) error
+}
+
+type auditRecorder interface {
+	Record(ctx context.Context, entry *domain.AuditEntry) error
+}
+
+func NewOIDCService(tx repository.Transactor, users *repository.UserRepo, identities *repository.IdentityRepo, states *repository.OIDCStateRepo, audit *repository.AuditRepo, tokens *TokenService, twoFactor *TwoFactorService, providers []*auth.OIDCProvider, stateTTL time.Duration) *OIDCService {
+	byName := make(map[string]*auth.OIDCProvider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &OIDCService{
+		tx:         tx,
+		users:      users,
+		identities: identities,
+		states:     states,
+		audit:      audit,
+		tokens:     tokens,
+		twoFactor:  twoFactor,
+		providers:  byName,
+		stateTTL:   stateTTL,
+	}
+}
+
+// Providers returns the names of the configured providers.
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start begins a login with the named provider and returns the URL to send
+// the user to.
+func (s *OIDCService) Start(ctx context.Context, provider string) (string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", domain.ErrIdentityProviderNotFound
+	}
+
+	state, stateHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := auth.NewPKCEVerifier()
+	if err != nil {
+		return "", err
+	}
+	nonce, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+	if err := s.states.Create(ctx, &domain.OIDCLoginState{
+		StateHash:    stateHash,
+		Provider:     provider,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(s.stateTTL),
+	}); err != nil {
+		return "", err
+	}
+	return authURL, nil
+}
+
+// Callback completes a login the provider sent back with code and state.
+// Like a password login it may stop at a two-factor challenge.
+func (s *OIDCService) Callback(ctx context.Context, state, code string, client domain.ClientInfo) (*domain.LoginResult, error) {
+	if state == "" || code == "" {
+		return nil, domain.ErrExternalLoginFailed
+	}
+	login, err := s.states.Consume(ctx, auth.HashOpaqueToken(state))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidToken) {
+			return nil, domain.ErrExternalLoginFailed
+		}
+		return nil, err
+	}
+	p, ok := s.providers[login.Provider]
+	if !ok || time.Now().After(login.ExpiresAt) {
+		return nil, domain.ErrExternalLoginFailed
+	}
+
+	identity, err := p.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.resolveUser(ctx, login.Provider, identity, client.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case user.Suspended():
+		return nil, domain.ErrAccountSuspended
+	case user.PasswordResetRequired:
+		return nil, domain.ErrPasswordResetRequired
+	}
+	if user.TwoFactorEnabled() {
+		token, expiresAt, err := s.twoFactor.StartChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.LoginResult{User: user, ChallengeToken: token, ChallengeExpiresAt: expiresAt}, nil
+	}
+
+	tokens, err := s.tokens.Issue(ctx, user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.LoginResult{User: user, Tokens: tokens}, nil
+}
+
+// resolveUser finds or creates the user an external identity belongs to.
+func (s *OIDCService) resolveUser(ctx context.Context, provider string, identity *auth.OIDCIdentity, ip string) (*domain.User, error) {
+	email := normalizeEmail(identity.Email)
+	var user *domain.User
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		linked, err := s.identities.GetBySubject(ctx, provider, identity.Subject)
+		switch {
+		case err == nil:
+			user, err = s.users.GetByID(ctx, linked.UserID)
+			if err != nil {
+				if errors.Is(err, domain.ErrUserNotFound) {
+					return domain.ErrExternalLoginFailed
+				}
+				return err
+			}
+			return s.identities.TouchLogin(ctx, linked.ID, email)
+		case !errors.Is(err, domain.ErrIdentityNotFound):
+			return err
+		}
+
+		if !identity.EmailVerified {
+			return domain.ErrExternalEmailNotVerified
+		}
+		if _, err := validator.IsValidEmail(email); err != nil {
+			return domain.ErrExternalEmailNotVerified
+		}
+
+		user, err = s.users.GetByEmail(ctx, email)
+		switch {
+		case err == nil:
+			// otherwise whoever registered the address, perhaps not its
+			// owner, would keep a password to the linked account
+			if !user.EmailVerified() {
+				return domain.ErrIdentityLinkUnverified
+			}
+			if err := s.audit.Record(ctx, &domain.AuditEntry{
+				UserID:  &user.ID,
+				Action:  domain.AuditIdentityLink,
+				IP:      ip,
+				Details: fmt.Sprintf("provider=%s subject=%s", provider, identity.Subject),
+			}); err != nil {
+				return err
+			}
+		case errors.Is(err, domain.ErrUserNotFound):
+			if user, err = s.createUser(ctx, email, identity); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return s.identities.Create(ctx, &domain.Identity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    email,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createUser opens a passwordless account with the verified email address
+// and a username derived from the identity.
+func (s *OIDCService) createUser(ctx context.Context, email string, identity *auth.OIDCIdentity) (*domain.User, error) {
+	username, err := s.freeUsername(ctx, identity.PreferredUsername, email)
+	if err != nil {
+		return nil, err
+	}
+	user := &domain.User{
+		Email:    email,
+		Username: username,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	if err := s.users.MarkEmailVerified(ctx, user.ID, email); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return user, nil
+}
+
+// freeUsername picks an unused username from the preferred one or the local
+// part of the email address, adding a random number if it is taken.
+func (s *OIDCService) freeUsername(ctx context.Context, preferred, email string) (string, error) {
+	base := usernameInvalidChars.ReplaceAllString(preferred, "")
+	if len(base) < validator.MinUsernameLength {
+		local, _, _ := strings.Cut(email, "@")
+		base = usernameInvalidChars.ReplaceAllString(local, "")
+	}
+	if len(base) < validator.MinUsernameLength {
+		base = "user"
+	}
+	// leave room for the suffix
+	if max := validator.MaxUsernameLength - 5; len(base) > max {
+		base = base[:max]
+	}
+
+	candidate := base
+	for range usernameAttempts {
+		taken, err := s.users.ExistsByUsername(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s-%04d", base, n.Int64())
+	}
+	return "", domain.ErrUsernameAlreadyExists
+}
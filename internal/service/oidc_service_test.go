@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+// inlineTx runs transactions as plain calls.
+type inlineTx struct{}
+
+func (inlineTx) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type fakeUsers struct {
+	users []*domain.User
+}
+
+func (f *fakeUsers) Create(ctx context.Context, user *domain.User) error {
+	user.ID = uint64(len(f.users) + 1)
+	f.users = append(f.users, user)
+	return nil
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id uint64) (*domain.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (f *fakeUsers) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (f *fakeUsers) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	for _, u := range f.users {
+		if u.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeUsers) MarkEmailVerified(ctx context.Context, id uint64, email string) error {
+	u, err := f.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+	return nil
+}
+
+type fakeIdentities struct {
+	identities []*domain.Identity
+	touched    []uint64
+}
+
+func (f *fakeIdentities) Create(ctx context.Context, identity *domain.Identity) error {
+	identity.ID = uint64(len(f.identities) + 1)
+	f.identities = append(f.identities, identity)
+	return nil
+}
+
+func (f *fakeIdentities) GetBySubject(ctx context.Context, provider, subject string) (*domain.Identity, error) {
+	for _, i := range f.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return i, nil
+		}
+	}
+	return nil, domain.ErrIdentityNotFound
+}
+
+func (f *fakeIdentities) TouchLogin(ctx context.Context, id uint64, email string) error {
+	f.touched = append(f.touched, id)
+	return nil
+}
+
+type fakeAudit struct {
+	entries []*domain.AuditEntry
+}
+
+func (f *fakeAudit) Record(ctx context.Context, entry *domain.AuditEntry) error {
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func newTestOIDCService() (*OIDCService, *fakeUsers, *fakeIdentities, *fakeAudit) {
+	users, identities, audit := &fakeUsers{}, &fakeIdentities{}, &fakeAudit{}
+	return &OIDCService{
+		tx:         inlineTx{},
+		users:      users,
+		identities: identities,
+		audit:      audit,
+	}, users, identities, audit
+}
+
+func verifiedAt() *time.Time {
+	t := time.Now().Add(-time.Hour)
+	return &t
+}
+
+func TestOIDCFirstLoginCreatesAccount(t *testing.T) {
+	s, users, identities, _ := newTestOIDCService()
+
+	user, err := s.resolveUser(context.Background(), "google", &auth.OIDCIdentity{
+		Subject:           "sub-1",
+		Email:             "Alice@Example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice",
+	}, "203.0.113.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users.users) != 1 || user.Email != "alice@example.com" || user.Username != "alice" {
+		t.Fatalf("created user = %+v", user)
+	}
+	if !user.EmailVerified() || user.Password != "" {
+		t.Fatalf("new account should have a verified address and no password: %+v", user)
+	}
+	if len(identities.identities) != 1 || identities.identities[0].UserID != user.ID {
+		t.Fatalf("identities = %+v", identities.identities)
+	}
+}
+
+func TestOIDCFirstLoginPicksFreeUsername(t *testing.T) {
+	s, users, _, _ := newTestOIDCService()
+	users.users = append(users.users, &domain.User{ID: 1, Email: "other@example.com", Username: "alice"})
+
+	user, err := s.resolveUser(context.Background(), "google", &auth.OIDCIdentity{
+		Subject:           "sub-1",
+		Email:             "alice@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 1 || user.Username == "alice" {
+		t.Fatalf("user = %+v, want a new account with another username", user)
+	}
+}
+
+func TestOIDCLinksVerifiedAccountByEmail(t *testing.T) {
+	s, users, identities, audit := newTestOIDCService()
+	existing := &domain.User{ID: 1, Email: "alice@example.com", Username: "alice", EmailVerifiedAt: verifiedAt()}
+	users.users = append(users.users, existing)
+
+	user, err := s.resolveUser(context.Background(), "google", &auth.OIDCIdentity{
+		Subject:       "sub-1",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+	}, "203.0.113.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != existing.ID || len(users.users) != 1 {
+		t.Fatalf("user = %+v, want the existing account", user)
+	}
+	if len(identities.identities) != 1 || identities.identities[0].UserID != existing.ID {
+		t.Fatalf("identities = %+v", identities.identities)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != domain.AuditIdentityLink {
+		t.Fatalf("audit = %+v, want an identity link entry", audit.entries)
+	}
+}
+
+func TestOIDCDoesNotLinkWithoutVerifiedEmails(t *testing.T) {
+	tests := []struct {
+		name          string
+		localVerified bool
+		idpVerified   bool
+		want          error
+	}{
+		{"provider did not verify the address", true, false, domain.ErrExternalEmailNotVerified},
+		{"local account did not verify the address", false, true, domain.ErrIdentityLinkUnverified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, users, identities, _ := newTestOIDCService()
+			existing := &domain.User{ID: 1, Email: "alice@example.com", Username: "alice"}
+			if tt.localVerified {
+				existing.EmailVerifiedAt = verifiedAt()
+			}
+			users.users = append(users.users, existing)
+
+			_, err := s.resolveUser(context.Background(), "google", &auth.OIDCIdentity{
+				Subject:       "sub-1",
+				Email:         "alice@example.com",
+				EmailVerified: tt.idpVerified,
+			}, "")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if len(identities.identities) != 0 || len(users.users) != 1 {
+				t.Fatalf("nothing should be linked or created: identities %+v, users %d", identities.identities, len(users.users))
+			}
+		})
+	}
+}
+
+func TestOIDCReturningIdentityUsesLinkedAccount(t *testing.T) {
+	s, users, identities, _ := newTestOIDCService()
+	users.users = append(users.users, &domain.User{ID: 1, Email: "alice@example.com", Username: "alice"})
+	identities.identities = append(identities.identities, &domain.Identity{ID: 7, UserID: 1, Provider: "google", Subject: "sub-1"})
+
+	// the address at the provider changed and is no longer verified: the
+	// link, not the address, decides
+	user, err := s.resolveUser(context.Background(), "google", &auth.OIDCIdentity{
+		Subject: "sub-1",
+		Email:   "alice@new.example",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 1 || len(identities.touched) != 1 || identities.touched[0] != 7 {
+		t.Fatalf("user = %+v, touched = %v", user, identities.touched)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/mail"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ passwordResetService = (*PasswordResetService)(nil)
+
+// PasswordResetService lets users who forgot their password set a new one
+// through a single-use link sent by email.
+type PasswordResetService struct {
+	tx       repository.Transactor
+	users    *repository.UserRepo
+	resets   *repository.PasswordResetRepo
+	tokens   *TokenService
+	hasher   *auth.PasswordHasher
+	policy   *validator.PasswordPolicy
+	mailer   mail.Mailer
+	resetURL string
+	ttl      time.Duration
+}
+
+// NewPasswordResetService creates the service. The mailed link is resetURL
+// with the token appended as the "token" query parameter.
+func NewPasswordResetService(tx repository.Transactor, users *repository.UserRepo, resets *repository.PasswordResetRepo, tokens *TokenService, hasher *auth.PasswordHasher, policy *validator.PasswordPolicy, mailer mail.Mailer, resetURL string, ttl time.Duration) *PasswordResetService {
+	return &PasswordResetService{
+		tx:       tx,
+		users:    users,
+		resets:   resets,
+		tokens:   tokens,
+		hasher:   hasher,
+		policy:   policy,
+		mailer:   mailer,
+		resetURL: resetURL,
+		ttl:      ttl,
+	}
+}
+
+// RequestReset mails a reset link if an account with the email exists. It
+// succeeds either way so callers cannot probe for registered addresses.
+func (s *PasswordResetService) RequestReset(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.issue(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your account. "+
+				"Open the link below within %s to choose a new one:\n\n%s\n\n"+
+				"If it wasn't you, ignore this email; your password stays the same.\n",
+			user.Username, s.ttl, withToken(s.resetURL, token),
+		),
+	})
+}
+
+// SendRequiredReset mails a reset link to a user an administrator required
+// to choose a new password, once the caller's transaction commits.
+func (s *PasswordResetService) SendRequiredReset(ctx context.Context, user *domain.User) error {
+	token, err := s.issue(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return sendAfterCommit(ctx, s.mailer, mail.Message{
+		To:      user.Email,
+		Subject: "Choose a new password",
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nAn administrator requires you to choose a new password before you can log in again. "+
+				"Open the link below within %s to set it:\n\n%s\n",
+			user.Username, s.ttl, withToken(s.resetURL, token),
+		),
+	})
+}
+
+func (s *PasswordResetService) issue(ctx context.Context, userID uint64) (string, error) {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.resets.Create(ctx, &domain.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ResetPassword sets a new password with a mailed token and ends all of the
+// user's sessions. Unknown, used and expired tokens are all rejected.
+func (s *PasswordResetService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if token == "" {
+		return domain.ErrMissingToken
+	}
+	if _, err := validator.IsValidPassword(newPassword); err != nil {
+		return err
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		reset, err := s.resets.GetByHashForUpdate(ctx, auth.HashOpaqueToken(token))
+		if err != nil {
+			return err
+		}
+		switch {
+		case reset.UsedAt != nil:
+			return domain.ErrInvalidToken
+		case time.Now().After(reset.ExpiresAt):
+			return domain.ErrExpiredToken
+		}
+
+		user, err := s.users.GetByID(ctx, reset.UserID)
+		if err != nil {
+			if errors.Is(err, domain.ErrUserNotFound) {
+				return domain.ErrInvalidToken
+			}
+			return err
+		}
+		// a rejected password leaves the token usable for another try
+		if err := s.policy.Check(newPassword, user.Email, user.Username); err != nil {
+			return err
+		}
+		hashed, err := s.hasher.Hash(newPassword)
+		if err != nil {
+			return err
+		}
+
+		if err := s.resets.MarkUsed(ctx, reset.ID); err != nil {
+			return err
+		}
+		if err := s.users.UpdatePassword(ctx, user.ID, hashed); err != nil {
+			return err
+		}
+		return s.tokens.RevokeAllSessions(ctx, reset.UserID)
+	})
+}
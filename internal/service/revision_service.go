@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/maqsatto/Notes-API/internal/diff"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ revisionService = (*RevisionService)(nil)
+
+type RevisionService struct {
+	notes     *NoteService
+	revisions *repository.RevisionRepo
+}
+
+func NewRevisionService(notes *NoteService, revisions *repository.RevisionRepo) *RevisionService {
+	return &RevisionService{
+		notes:     notes,
+		revisions: revisions,
+	}
+}
+
+func (s *RevisionService) List(ctx context.Context, userID, noteID uint64, limit, offset int) ([]*domain.NoteRevision, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	if _, err := s.notes.GetByID(ctx, userID, noteID); err != nil {
+		return nil, 0, err
+	}
+	return s.revisions.ListByNoteID(ctx, noteID, limit, offset)
+}
+
+func (s *RevisionService) Get(ctx context.Context, userID, noteID uint64, revision int) (*domain.NoteRevision, error) {
+	if revision <= 0 {
+		return nil, domain.ErrInvalidID
+	}
+	if _, err := s.notes.GetByID(ctx, userID, noteID); err != nil {
+		return nil, err
+	}
+	return s.revisions.Get(ctx, noteID, revision)
+}
+
+// Diff returns a unified line diff of the content of two revisions of a note.
+func (s *RevisionService) Diff(ctx context.Context, userID, noteID uint64, from, to int) (*domain.NoteRevision, *domain.NoteRevision, string, error) {
+	fromRev, err := s.Get(ctx, userID, noteID, from)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	toRev, err := s.revisions.Get(ctx, noteID, to)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	d, err := diff.Unified(
+		fmt.Sprintf("revision %d", from),
+		fmt.Sprintf("revision %d", to),
+		fromRev.Content,
+		toRev.Content,
+		diff.DefaultContext,
+	)
+	if errors.Is(err, diff.ErrTooLarge) {
+		return nil, nil, "", domain.ErrRevisionDiffTooLarge
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return fromRev, toRev, d, nil
+}
+
+// Restore writes an earlier revision back to the note. The restore itself is
+// recorded as a new revision, so it can be undone.
+func (s *RevisionService) Restore(ctx context.Context, userID, noteID uint64, revision int) (*domain.Note, error) {
+	rev, err := s.Get(ctx, userID, noteID, revision)
+	if err != nil {
+		return nil, err
+	}
+	return s.notes.Update(ctx, userID, noteID, 0, rev.Title, rev.Content, rev.Tags)
+}
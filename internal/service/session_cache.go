@@ -0,0 +1,90 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// sessionCacheMaxEntries bounds the cache; expired entries are swept once it
+// is reached.
+const sessionCacheMaxEntries = 10000
+
+// sessionCache remembers which sessions were recently found to be live so
+// that authenticating a request does not need a database round trip. A
+// session revoked by another process is noticed within ttl; revocations in
+// this process evict the entry once they commit.
+type sessionCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]sessionCacheEntry
+	// evictions counts evict calls. A lookup that started before an
+	// eviction may have read the session before it was revoked, so put
+	// drops its result.
+	evictions uint64
+}
+
+type sessionCacheEntry struct {
+	userID  uint64
+	expires time.Time
+}
+
+func newSessionCache(ttl time.Duration) *sessionCache {
+	return &sessionCache{
+		ttl:     ttl,
+		entries: make(map[string]sessionCacheEntry),
+	}
+}
+
+// get returns the owner of a session cached as live.
+func (c *sessionCache) get(sessionID string) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[sessionID]
+	if !ok || time.Now().After(e.expires) {
+		return 0, false
+	}
+	return e.userID, true
+}
+
+// generation is passed to put by a lookup about to read a session from the
+// database.
+func (c *sessionCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
+
+// put caches a session read after generation returned gen, unless something
+// was evicted since.
+func (c *sessionCache) put(sessionID string, userID uint64, gen uint64) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.evictions != gen {
+		return
+	}
+	now := time.Now()
+	if len(c.entries) >= sessionCacheMaxEntries {
+		for id, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= sessionCacheMaxEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[sessionID] = sessionCacheEntry{userID: userID, expires: now.Add(c.ttl)}
+}
+
+func (c *sessionCache) evict(sessionIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictions++
+	for _, id := range sessionIDs {
+		delete(c.entries, id)
+	}
+}
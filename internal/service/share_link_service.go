@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ shareLinkService = (*ShareLinkService)(nil)
+
+// ShareLinkService manages public links that let anyone read a note without
+// an account. Links stop working as soon as the note goes to the trash.
+type ShareLinkService struct {
+	links   *repository.ShareLinkRepo
+	notes   *repository.NoteRepo
+	hasher  *auth.PasswordHasher
+	guard   *LoginGuard
+	baseURL string
+}
+
+func NewShareLinkService(links *repository.ShareLinkRepo, notes *repository.NoteRepo, hasher *auth.PasswordHasher, guard *LoginGuard, baseURL string) *ShareLinkService {
+	return &ShareLinkService{
+		links:   links,
+		notes:   notes,
+		hasher:  hasher,
+		guard:   guard,
+		baseURL: baseURL,
+	}
+}
+
+// Create issues a link to one of the owner's notes and returns it along with
+// its URL. The token in the URL is not stored and cannot be shown again.
+// An empty password leaves the link unprotected and a nil expiresAt makes it
+// last until it is revoked.
+func (s *ShareLinkService) Create(ctx context.Context, ownerID, noteID uint64, password string, expiresAt *time.Time) (*domain.ShareLink, string, error) {
+	if len(password) > validator.MaxPasswordLength {
+		return nil, "", domain.ErrPasswordTooLong
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", domain.ErrInvalidInput
+	}
+	if err := authorizeNote(ctx, s.notes, ownerID, noteID, domain.AccessOwner); err != nil {
+		return nil, "", err
+	}
+	if _, err := s.notes.GetByID(ctx, noteID); err != nil {
+		return nil, "", err
+	}
+
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	link := &domain.ShareLink{
+		NoteID:    noteID,
+		CreatedBy: ownerID,
+		TokenHash: hash,
+		ExpiresAt: expiresAt,
+	}
+	if password != "" {
+		if link.PasswordHash, err = s.hasher.Hash(password); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := s.links.Create(ctx, link); err != nil {
+		return nil, "", err
+	}
+	return link, strings.TrimRight(s.baseURL, "/") + "/" + token, nil
+}
+
+// List returns the note's links that can still be opened.
+func (s *ShareLinkService) List(ctx context.Context, ownerID, noteID uint64) ([]*domain.ShareLink, error) {
+	if err := authorizeNote(ctx, s.notes, ownerID, noteID, domain.AccessOwner); err != nil {
+		return nil, err
+	}
+	return s.links.ListActiveByNote(ctx, noteID)
+}
+
+func (s *ShareLinkService) Revoke(ctx context.Context, ownerID, noteID, linkID uint64) error {
+	if linkID == 0 {
+		return domain.ErrInvalidID
+	}
+	if err := authorizeNote(ctx, s.notes, ownerID, noteID, domain.AccessOwner); err != nil {
+		return err
+	}
+	return s.links.Delete(ctx, noteID, linkID)
+}
+
+// Open resolves a link token to its note and counts the view. Password
+// guesses are throttled per link and per client address like logins.
+func (s *ShareLinkService) Open(ctx context.Context, token, password, ip string) (*domain.Note, *domain.ShareLink, error) {
+	if token == "" {
+		return nil, nil, domain.ErrShareLinkNotFound
+	}
+	link, err := s.links.GetActiveByTokenHash(ctx, auth.HashOpaqueToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if link.HasPassword() {
+		if password == "" {
+			return nil, nil, domain.ErrShareLinkPasswordRequired
+		}
+		identifier := "share-link:" + strconv.FormatUint(link.ID, 10)
+		if err := s.guard.Check(ctx, identifier, ip); err != nil {
+			return nil, nil, err
+		}
+		if ok, _ := s.hasher.Verify(link.PasswordHash, password); !ok {
+			if err := s.guard.Fail(ctx, identifier, ip, nil); err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, domain.ErrShareLinkWrongPassword
+		}
+		if err := s.guard.Succeed(ctx, identifier); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	note, err := s.notes.GetByID(ctx, link.NoteID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoteNotFound) {
+			return nil, nil, domain.ErrShareLinkNotFound
+		}
+		return nil, nil, err
+	}
+	if link.ViewCount, err = s.links.RecordView(ctx, link.ID); err != nil {
+		return nil, nil, err
+	}
+	return note, link, nil
+}
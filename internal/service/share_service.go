@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ shareService = (*ShareService)(nil)
+
+// ShareService lets owners share notes and notebooks with other users. A
+// notebook share covers every note and notebook nested in the notebook.
+type ShareService struct {
+	shares    *repository.ShareRepo
+	notes     *repository.NoteRepo
+	notebooks *repository.NotebookRepo
+	users     *repository.UserRepo
+}
+
+func NewShareService(shares *repository.ShareRepo, notes *repository.NoteRepo, notebooks *repository.NotebookRepo, users *repository.UserRepo) *ShareService {
+	return &ShareService{
+		shares:    shares,
+		notes:     notes,
+		notebooks: notebooks,
+		users:     users,
+	}
+}
+
+// ShareNote gives the user named by who, a username or email address, role
+// on one of the owner's notes. Sharing with the same user again changes
+// their role. It reports whether the share is new.
+func (s *ShareService) ShareNote(ctx context.Context, ownerID, noteID uint64, who string, role domain.ShareRole) (*domain.Share, bool, error) {
+	if !role.Valid() {
+		return nil, false, domain.ErrInvalidShareRole
+	}
+	if err := authorizeNote(ctx, s.notes, ownerID, noteID, domain.AccessOwner); err != nil {
+		return nil, false, err
+	}
+	if _, err := s.notes.GetByID(ctx, noteID); err != nil {
+		return nil, false, err
+	}
+	return s.grant(ctx, ownerID, who, role, &domain.Share{NoteID: &noteID})
+}
+
+// ShareNotebook gives the user named by who role on one of the owner's
+// notebooks, and so on every note in it and in its sub-notebooks.
+func (s *ShareService) ShareNotebook(ctx context.Context, ownerID, notebookID uint64, who string, role domain.ShareRole) (*domain.Share, bool, error) {
+	if !role.Valid() {
+		return nil, false, domain.ErrInvalidShareRole
+	}
+	if _, err := authorizeNotebook(ctx, s.notebooks, ownerID, notebookID, domain.AccessOwner); err != nil {
+		return nil, false, err
+	}
+	return s.grant(ctx, ownerID, who, role, &domain.Share{NotebookID: &notebookID})
+}
+
+func (s *ShareService) ListNoteShares(ctx context.Context, ownerID, noteID uint64) ([]*domain.Share, error) {
+	if err := authorizeNote(ctx, s.notes, ownerID, noteID, domain.AccessOwner); err != nil {
+		return nil, err
+	}
+	return s.shares.ListByNote(ctx, noteID)
+}
+
+func (s *ShareService) ListNotebookShares(ctx context.Context, ownerID, notebookID uint64) ([]*domain.Share, error) {
+	if _, err := authorizeNotebook(ctx, s.notebooks, ownerID, notebookID, domain.AccessOwner); err != nil {
+		return nil, err
+	}
+	return s.shares.ListByNotebook(ctx, notebookID)
+}
+
+// RevokeNoteShare removes granteeID's share of a note. The owner can revoke
+// any share, and users can give up shares they were given.
+func (s *ShareService) RevokeNoteShare(ctx context.Context, userID, noteID, granteeID uint64) error {
+	if granteeID == 0 {
+		return domain.ErrInvalidID
+	}
+	if granteeID != userID {
+		if err := authorizeNote(ctx, s.notes, userID, noteID, domain.AccessOwner); err != nil {
+			return err
+		}
+	}
+	return s.shares.RevokeNote(ctx, noteID, granteeID)
+}
+
+// RevokeNotebookShare removes granteeID's share of a notebook, like
+// RevokeNoteShare.
+func (s *ShareService) RevokeNotebookShare(ctx context.Context, userID, notebookID, granteeID uint64) error {
+	if granteeID == 0 {
+		return domain.ErrInvalidID
+	}
+	if granteeID != userID {
+		if _, err := authorizeNotebook(ctx, s.notebooks, userID, notebookID, domain.AccessOwner); err != nil {
+			return err
+		}
+	}
+	return s.shares.RevokeNotebook(ctx, notebookID, granteeID)
+}
+
+// ListSharedNotes lists the notes other users shared with the user, directly
+// or through a notebook.
+func (s *ShareService) ListSharedNotes(ctx context.Context, userID uint64, limit, offset int) ([]*domain.SharedNote, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return s.notes.ListSharedWith(ctx, userID, limit, offset)
+}
+
+// ListSharedNotebooks returns the notebooks other users shared with the user.
+func (s *ShareService) ListSharedNotebooks(ctx context.Context, userID uint64) ([]*domain.SharedNotebook, error) {
+	if userID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	return s.shares.ListNotebooksSharedWith(ctx, userID)
+}
+
+func (s *ShareService) grant(ctx context.Context, ownerID uint64, who string, role domain.ShareRole, share *domain.Share) (*domain.Share, bool, error) {
+	grantee, err := s.findUser(ctx, who)
+	if err != nil {
+		return nil, false, err
+	}
+	if grantee.ID == ownerID {
+		return nil, false, domain.ErrShareWithOwner
+	}
+
+	share.UserID = grantee.ID
+	share.Username = grantee.Username
+	share.Email = grantee.Email
+	share.Role = role
+	share.CreatedBy = ownerID
+	created, err := s.shares.Grant(ctx, share)
+	if err != nil {
+		return nil, false, err
+	}
+	return share, created, nil
+}
+
+// findUser looks a user up by email address when who has an @ in it, and by
+// username otherwise.
+func (s *ShareService) findUser(ctx context.Context, who string) (*domain.User, error) {
+	who = strings.TrimSpace(who)
+	if who == "" {
+		return nil, domain.ErrInvalidInput
+	}
+	if strings.Contains(who, "@") {
+		return s.users.GetByEmail(ctx, normalizeEmail(who))
+	}
+	return s.users.GetByUsername(ctx, who)
+}
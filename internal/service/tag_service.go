@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ tagService = (*TagService)(nil)
+
+type TagService struct {
+	tags *repository.TagRepo
+}
+
+func NewTagService(tags *repository.TagRepo) *TagService {
+	return &TagService{
+		tags: tags,
+	}
+}
+
+func (s *TagService) ListTags(ctx context.Context, userID uint64) ([]*domain.Tag, error) {
+	if userID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	return s.tags.ListUserTags(ctx, userID)
+}
+
+func (s *TagService) ListPopularTags(ctx context.Context, userID uint64, limit int) ([]*domain.Tag, error) {
+	if userID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	if _, err := validator.IsValidPagination(limit, 0); err != nil {
+		return nil, err
+	}
+	return s.tags.ListPopularTags(ctx, userID, limit)
+}
+
+// RenameTag renames oldName to newName on all of the user's notes, merging
+// into newName when the user already has such a tag.
+func (s *TagService) RenameTag(ctx context.Context, userID uint64, oldName, newName string) (*domain.Tag, bool, error) {
+	if userID == 0 {
+		return nil, false, domain.ErrInvalidID
+	}
+	oldName = strings.ToLower(strings.TrimSpace(oldName))
+	newName = strings.ToLower(strings.TrimSpace(newName))
+	if _, err := validator.IsValidTags([]string{oldName, newName}); err != nil {
+		return nil, false, err
+	}
+	if oldName == newName {
+		return nil, false, domain.ErrNothingToUpdate
+	}
+
+	merged, err := s.tags.Rename(ctx, userID, oldName, newName)
+	if err != nil {
+		return nil, false, err
+	}
+	tag, err := s.tags.GetByName(ctx, userID, newName)
+	if err != nil {
+		return nil, false, err
+	}
+	return tag, merged, nil
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+)
+
+var _ tokenService = (*TokenService)(nil)
+
+// maxDeviceLength caps the client supplied device name of a session.
+const maxDeviceLength = 100
+
+// TokenService manages login sessions and the access/refresh token pairs
+// issued for them. Refresh tokens are single use: each refresh rotates the
+// token within its session, and presenting an already rotated token revokes
+// the whole session.
+type TokenService struct {
+	tx         repository.Transactor
+	users      *repository.UserRepo
+	roles      *repository.RoleRepo
+	sessions   *repository.SessionRepo
+	tokens     *repository.RefreshTokenRepo
+	jwt        *auth.JWTManager
+	refreshTTL time.Duration
+	cache      *sessionCache
+}
+
+// NewTokenService creates the service. Session liveness checks are cached for
+// cacheTTL; zero disables the cache.
+func NewTokenService(tx repository.Transactor, users *repository.UserRepo, roles *repository.RoleRepo, sessions *repository.SessionRepo, tokens *repository.RefreshTokenRepo, jwt *auth.JWTManager, refreshTTL, cacheTTL time.Duration) *TokenService {
+	return &TokenService{
+		tx:         tx,
+		users:      users,
+		roles:      roles,
+		sessions:   sessions,
+		tokens:     tokens,
+		jwt:        jwt,
+		refreshTTL: refreshTTL,
+		cache:      newSessionCache(cacheTTL),
+	}
+}
+
+// Issue opens a new session for the user, typically on login. Suspended
+// users get ErrAccountSuspended.
+func (s *TokenService) Issue(ctx context.Context, userID uint64, client domain.ClientInfo) (*domain.TokenPair, error) {
+	return s.open(ctx, &domain.Session{
+		UserID:    userID,
+		Device:    truncate(client.Device, maxDeviceLength),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+	})
+}
+
+// IssueForClient opens a session for an OAuth client acting for the user
+// within scopes. Its tokens carry no role permissions.
+func (s *TokenService) IssueForClient(ctx context.Context, userID uint64, oauthClient *domain.OAuthClient, scopes []string, client domain.ClientInfo) (*domain.TokenPair, error) {
+	return s.open(ctx, &domain.Session{
+		UserID:    userID,
+		ClientID:  &oauthClient.ID,
+		Scopes:    scopes,
+		Device:    truncate(oauthClient.Name, maxDeviceLength),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+	})
+}
+
+func (s *TokenService) open(ctx context.Context, session *domain.Session) (*domain.TokenPair, error) {
+	var pair *domain.TokenPair
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := s.users.GetByID(ctx, session.UserID)
+		if err != nil {
+			return err
+		}
+		if user.Suspended() {
+			return domain.ErrAccountSuspended
+		}
+		session.ExpiresAt = time.Now().Add(s.refreshTTL)
+		if err := s.sessions.Create(ctx, session); err != nil {
+			return err
+		}
+		pair, err = s.issue(ctx, user, session, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Refresh rotates a refresh token of a login session.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	return s.refresh(ctx, refreshToken, "")
+}
+
+// RefreshForClient rotates a refresh token the OAuth client was issued.
+// Tokens of other clients and of login sessions are rejected.
+func (s *TokenService) RefreshForClient(ctx context.Context, refreshToken, clientID string) (*domain.TokenPair, error) {
+	return s.refresh(ctx, refreshToken, clientID)
+}
+
+func (s *TokenService) refresh(ctx context.Context, refreshToken, clientID string) (*domain.TokenPair, error) {
+	if refreshToken == "" {
+		return nil, domain.ErrMissingToken
+	}
+
+	var (
+		pair   *domain.TokenPair
+		reused *domain.RefreshToken
+	)
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		current, err := s.tokens.GetByHashForUpdate(ctx, auth.HashOpaqueToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		switch {
+		case current.RevokedAt != nil:
+			return domain.ErrRevokedToken
+		case current.UsedAt != nil:
+			reused = current
+			return domain.ErrTokenReused
+		case time.Now().After(current.ExpiresAt):
+			return domain.ErrExpiredToken
+		}
+
+		user, err := s.users.GetByID(ctx, current.UserID)
+		if err != nil {
+			if errors.Is(err, domain.ErrUserNotFound) {
+				return domain.ErrInvalidToken
+			}
+			return err
+		}
+		if user.Suspended() {
+			return domain.ErrAccountSuspended
+		}
+		if err := s.tokens.MarkUsed(ctx, current.ID); err != nil {
+			return err
+		}
+		session, err := s.sessions.Extend(ctx, current.FamilyID, time.Now().Add(s.refreshTTL))
+		if err != nil {
+			if errors.Is(err, domain.ErrSessionNotFound) {
+				return domain.ErrRevokedToken
+			}
+			return err
+		}
+		if sessionClientID(session) != clientID {
+			return domain.ErrInvalidToken
+		}
+		pair, err = s.issue(ctx, user, session, &current.ID)
+		return err
+	})
+	if errors.Is(err, domain.ErrTokenReused) && reused != nil {
+		// the transaction was rolled back, revoke outside of it so it sticks
+		if rerr := s.revokeSession(ctx, reused.UserID, reused.FamilyID); rerr != nil && !errors.Is(rerr, domain.ErrSessionNotFound) {
+			return nil, errors.Join(err, rerr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Revoke ends the session the refresh token belongs to. Unknown tokens are
+// ignored so logout is idempotent.
+func (s *TokenService) Revoke(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return domain.ErrMissingToken
+	}
+	current, err := s.tokens.GetByHashForUpdate(ctx, auth.HashOpaqueToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidToken) {
+			return nil
+		}
+		return err
+	}
+	if err := s.revokeSession(ctx, current.UserID, current.FamilyID); err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// CheckSession reports whether an access token's session is still live. It
+// is called on every authenticated request, so live sessions are cached.
+func (s *TokenService) CheckSession(ctx context.Context, userID uint64, sessionID string) error {
+	if owner, ok := s.cache.get(sessionID); ok {
+		if owner != userID {
+			return domain.ErrInvalidToken
+		}
+		return nil
+	}
+
+	gen := s.cache.generation()
+	owner, err := s.sessions.Touch(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return domain.ErrRevokedToken
+		}
+		return err
+	}
+	if owner != userID {
+		return domain.ErrInvalidToken
+	}
+	s.cache.put(sessionID, owner, gen)
+	return nil
+}
+
+func (s *TokenService) ListSessions(ctx context.Context, userID uint64) ([]*domain.Session, error) {
+	return s.sessions.ListActiveByUserID(ctx, userID)
+}
+
+func (s *TokenService) RevokeSession(ctx context.Context, userID uint64, sessionID string) error {
+	if sessionID == "" {
+		return domain.ErrSessionNotFound
+	}
+	return s.revokeSession(ctx, userID, sessionID)
+}
+
+// RevokeAllSessions logs the user out everywhere. It joins the caller's
+// transaction, if any.
+func (s *TokenService) RevokeAllSessions(ctx context.Context, userID uint64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		ids, err := s.sessions.RevokeAllForUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.tokens.RevokeAllForUser(ctx, userID); err != nil {
+			return err
+		}
+		repository.AfterCommit(ctx, func() { s.cache.evict(ids...) })
+		return nil
+	})
+}
+
+// RevokeClientSessions ends the user's sessions with an OAuth client. It
+// joins the caller's transaction, if any.
+func (s *TokenService) RevokeClientSessions(ctx context.Context, userID uint64, clientID string) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		ids, err := s.sessions.RevokeForClient(ctx, userID, clientID)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := s.tokens.RevokeFamily(ctx, id); err != nil {
+				return err
+			}
+		}
+		repository.AfterCommit(ctx, func() { s.cache.evict(ids...) })
+		return nil
+	})
+}
+
+// AccessTokenSession returns the claims of a valid access token and its live
+// session. Tokens that are not live give ErrInvalidToken.
+func (s *TokenService) AccessTokenSession(ctx context.Context, accessToken string) (*auth.Claims, *domain.Session, error) {
+	claims, err := s.jwt.ParseAndValidate(accessToken)
+	if err != nil {
+		return nil, nil, domain.ErrInvalidToken
+	}
+	session, err := s.sessions.GetActive(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return nil, nil, domain.ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+	if session.UserID != claims.UserID {
+		return nil, nil, domain.ErrInvalidToken
+	}
+	return claims, session, nil
+}
+
+// RefreshTokenSession returns a usable refresh token and its live session.
+// Used, revoked, expired and unknown tokens give ErrInvalidToken.
+func (s *TokenService) RefreshTokenSession(ctx context.Context, refreshToken string) (*domain.RefreshToken, *domain.Session, error) {
+	current, err := s.tokens.GetByHashForUpdate(ctx, auth.HashOpaqueToken(refreshToken))
+	if err != nil {
+		return nil, nil, err
+	}
+	if current.UsedAt != nil || current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return nil, nil, domain.ErrInvalidToken
+	}
+	session, err := s.sessions.GetActive(ctx, current.FamilyID)
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return nil, nil, domain.ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+	return current, session, nil
+}
+
+func (s *TokenService) revokeSession(ctx context.Context, userID uint64, sessionID string) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.sessions.Revoke(ctx, userID, sessionID); err != nil {
+			return err
+		}
+		if err := s.tokens.RevokeFamily(ctx, sessionID); err != nil {
+			return err
+		}
+		repository.AfterCommit(ctx, func() { s.cache.evict(sessionID) })
+		return nil
+	})
+}
+
+func (s *TokenService) issue(ctx context.Context, user *domain.User, session *domain.Session, parentID *uint64) (*domain.TokenPair, error) {
+	now := time.Now()
+	access, err := s.accessToken(ctx, user, session)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	stored := &domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  session.ID,
+		ParentID:  parentID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(s.refreshTTL),
+	}
+	if err := s.tokens.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:      access,
+		AccessExpiresAt:  now.Add(s.jwt.TTL()),
+		RefreshToken:     refresh,
+		RefreshExpiresAt: stored.ExpiresAt,
+		Scopes:           session.Scopes,
+	}, nil
+}
+
+// accessToken grants a login session the permissions of the user's role and
+// an OAuth client's session its scopes.
+func (s *TokenService) accessToken(ctx context.Context, user *domain.User, session *domain.Session) (string, error) {
+	if session.ClientID != nil {
+		return s.jwt.GenerateClientToken(user.ID, session.ID, *session.ClientID, session.Scopes)
+	}
+	role, err := s.roles.Get(ctx, user.Role)
+	if err != nil {
+		return "", err
+	}
+	return s.jwt.GenerateToken(user.ID, session.ID, role.Name, role.Permissions)
+}
+
+// sessionClientID returns the OAuth client of a session, empty for logins.
+func sessionClientID(session *domain.Session) string {
+	if session.ClientID == nil {
+		return ""
+	}
+	return *session.ClientID
+}
+
+// truncate cuts s to at most n runes.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
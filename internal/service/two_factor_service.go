@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+)
+
+var _ twoFactorService = (*TwoFactorService)(nil)
+
+const (
+	recoveryCodeCount = 10
+	// maxChallengeAttempts is how many wrong codes a login challenge takes
+	// before it is discarded and the password step must be repeated.
+	maxChallengeAttempts = 5
+)
+
+// TwoFactorService manages TOTP enrollment, recovery codes and the second
+// step of logins for users who enabled two-factor authentication.
+type TwoFactorService struct {
+	tx           repository.Transactor
+	users        *repository.UserRepo
+	twoFactor    *repository.TwoFactorRepo
+	tokens       *TokenService
+	guard        *LoginGuard
+	hasher       *auth.PasswordHasher
+	issuer       string
+	challengeTTL time.Duration
+}
+
+func NewTwoFactorService(tx repository.Transactor, users *repository.UserRepo, twoFactor *repository.TwoFactorRepo, tokens *TokenService, guard *LoginGuard, hasher *auth.PasswordHasher, issuer string, challengeTTL time.Duration) *TwoFactorService {
+	return &TwoFactorService{
+		tx:           tx,
+		users:        users,
+		twoFactor:    twoFactor,
+		tokens:       tokens,
+		guard:        guard,
+		hasher:       hasher,
+		issuer:       issuer,
+		challengeTTL: challengeTTL,
+	}
+}
+
+// Status reports whether two-factor authentication is on and how many
+// recovery codes are left.
+func (s *TwoFactorService) Status(ctx context.Context, userID uint64) (bool, int, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return false, 0, err
+	}
+	if !user.TwoFactorEnabled() {
+		return false, 0, nil
+	}
+	left, err := s.twoFactor.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return false, 0, err
+	}
+	return true, left, nil
+}
+
+// Enroll generates a new secret awaiting confirmation. Calling it again
+// before confirming replaces the secret.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID uint64) (*domain.TwoFactorEnrollment, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, domain.ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SetPendingTOTPSecret(ctx, userID, secret); err != nil {
+		if errors.Is(err, domain.ErrStateViolation) {
+			return nil, domain.ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+	return &domain.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    auth.TOTPURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables two-factor authentication once the user proves the
+// authenticator works, and returns the recovery codes. They are shown only
+// this once.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uint64, code string) ([]string, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case user.TwoFactorEnabled():
+		return nil, domain.ErrTwoFactorAlreadyEnabled
+	case user.TOTPSecret == "":
+		return nil, domain.ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, domain.ErrInvalidTOTPCode
+	}
+
+	var codes []string
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.users.EnableTOTP(ctx, userID, step); err != nil {
+			if errors.Is(err, domain.ErrStateViolation) {
+				return domain.ErrTwoFactorAlreadyEnabled
+			}
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes invalidates the remaining recovery codes and
+// returns a fresh set after re-authentication.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uint64, password, code string) ([]string, error) {
+	var codes []string
+	err := s.reauthenticated(ctx, userID, password, code, func(ctx context.Context) error {
+		var err error
+		codes, err = s.replaceRecoveryCodes(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off after re-authentication with
+// the password and a current code or a recovery code.
+func (s *TwoFactorService) Disable(ctx context.Context, userID uint64, password, code string) error {
+	return s.reauthenticated(ctx, userID, password, code, func(ctx context.Context) error {
+		if err := s.users.DisableTOTP(ctx, userID); err != nil {
+			return err
+		}
+		return s.twoFactor.DeleteRecoveryCodes(ctx, userID)
+	})
+}
+
+// StartChallenge creates the short-lived token the client exchanges, along
+// with a code, for a session in CompleteLogin.
+func (s *TwoFactorService) StartChallenge(ctx context.Context, userID uint64) (string, time.Time, error) {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	challenge := &domain.LoginChallenge{
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	}
+	if err := s.twoFactor.CreateChallenge(ctx, challenge); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, challenge.ExpiresAt, nil
+}
+
+// CompleteLogin exchanges a login challenge and a TOTP or recovery code for
+// a new session.
+func (s *TwoFactorService) CompleteLogin(ctx context.Context, challengeToken, code string, client domain.ClientInfo) (*domain.LoginResult, error) {
+	if challengeToken == "" {
+		return nil, domain.ErrMissingToken
+	}
+
+	var (
+		result  *domain.LoginResult
+		codeErr error
+	)
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		challenge, err := s.twoFactor.GetChallengeForUpdate(ctx, auth.HashOpaqueToken(challengeToken))
+		if err != nil {
+			return err
+		}
+		if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxChallengeAttempts {
+			// commit the deletion rather than rolling it back with an error
+			codeErr = domain.ErrExpiredToken
+			return s.twoFactor.DeleteChallenge(ctx, challenge.ID)
+		}
+
+		user, err := s.users.GetByID(ctx, challenge.UserID)
+		if err != nil {
+			if errors.Is(err, domain.ErrUserNotFound) {
+				return domain.ErrInvalidToken
+			}
+			return err
+		}
+		if err := s.verifyCode(ctx, user, code); err != nil {
+			if errors.Is(err, domain.ErrInvalidTOTPCode) {
+				codeErr = err
+				return s.twoFactor.RecordChallengeAttempt(ctx, challenge.ID)
+			}
+			return err
+		}
+
+		if err := s.twoFactor.DeleteChallenge(ctx, challenge.ID); err != nil {
+			return err
+		}
+		tokens, err := s.tokens.Issue(ctx, user.ID, client)
+		if err != nil {
+			return err
+		}
+		result = &domain.LoginResult{User: user, Tokens: tokens}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if codeErr != nil {
+		return nil, codeErr
+	}
+	return result, nil
+}
+
+// reauthenticated runs fn in a transaction once the user proved who they are
+// with the password and a second factor. Failures are throttled by the
+// LoginGuard like logins, against the same account counter, and a wrong
+// password and a wrong code both give ErrInvalidCredentials.
+func (s *TwoFactorService) reauthenticated(ctx context.Context, userID uint64, password, code string, fn func(ctx context.Context) error) error {
+	identifier := userIdentifier(userID)
+	if err := s.guard.Check(ctx, identifier, ""); err != nil {
+		return err
+	}
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.reauthenticate(ctx, userID, password, code); err != nil {
+			return err
+		}
+		return fn(ctx)
+	})
+	if errors.Is(err, domain.ErrInvalidCredentials) {
+		// recorded outside the transaction, which was rolled back
+		if err := s.guard.Fail(ctx, identifier, "", &userID); err != nil {
+			return err
+		}
+		return domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		return err
+	}
+	return s.guard.Succeed(ctx, identifier)
+}
+
+// reauthenticate checks the password and a second factor of a user with
+// two-factor authentication enabled. Both are checked, so that the response
+// does not tell which one was wrong.
+func (s *TwoFactorService) reauthenticate(ctx context.Context, userID uint64, password, code string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled() {
+		return domain.ErrTwoFactorNotEnabled
+	}
+	passwordOK, _ := s.hasher.Verify(user.Password, password)
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		if errors.Is(err, domain.ErrInvalidTOTPCode) {
+			return domain.ErrInvalidCredentials
+		}
+		return err
+	}
+	if !passwordOK {
+		return domain.ErrInvalidCredentials
+	}
+	return nil
+}
+
+// verifyCode accepts a current TOTP code, each time step only once, or an
+// unused recovery code.
+func (s *TwoFactorService) verifyCode(ctx context.Context, user *domain.User, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return domain.ErrInvalidTOTPCode
+	}
+	if isDigits(code) {
+		step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return domain.ErrInvalidTOTPCode
+		}
+		return s.users.UseTOTPStep(ctx, user.ID, step)
+	}
+	return s.twoFactor.UseRecoveryCode(ctx, user.ID, auth.HashRecoveryCode(code))
+}
+
+func (s *TwoFactorService) replaceRecoveryCodes(ctx context.Context, userID uint64) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := auth.NewRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i], hashes[i] = code, auth.HashRecoveryCode(code)
+	}
+	if err := s.twoFactor.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
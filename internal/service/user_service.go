@@ -1 +1,340 @@
 package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/maqsatto/Notes-API/internal/auth"
+	"github.com/maqsatto/Notes-API/internal/domain"
+	"github.com/maqsatto/Notes-API/internal/repository"
+	"github.com/maqsatto/Notes-API/internal/validator"
+)
+
+var _ userService = (*UserService)(nil)
+
+type UserService struct {
+	tx        repository.Transactor
+	users     *repository.UserRepo
+	notes     *repository.NoteRepo
+	tokens    *TokenService
+	twoFactor *TwoFactorService
+	emails    *EmailService
+	guard     *LoginGuard
+	hasher    *auth.PasswordHasher
+	policy    *validator.PasswordPolicy
+}
+
+func NewUserService(tx repository.Transactor, users *repository.UserRepo, notes *repository.NoteRepo, tokens *TokenService, twoFactor *TwoFactorService, emails *EmailService, guard *LoginGuard, hasher *auth.PasswordHasher, policy *validator.PasswordPolicy) *UserService {
+	return &UserService{
+		tx:        tx,
+		users:     users,
+		notes:     notes,
+		tokens:    tokens,
+		twoFactor: twoFactor,
+		emails:    emails,
+		guard:     guard,
+		hasher:    hasher,
+		policy:    policy,
+	}
+}
+
+func (s *UserService) Register(ctx context.Context, username, email, password string) (*domain.User, error) {
+	username = strings.TrimSpace(username)
+	email = normalizeEmail(email)
+	if _, err := validator.IsValidUsername(username); err != nil {
+		return nil, err
+	}
+	if err := validator.ValidateUserRegister(email, username, password); err != nil {
+		return nil, err
+	}
+	if err := s.policy.Check(password, email, username); err != nil {
+		return nil, err
+	}
+
+	if taken, err := s.users.ExistsByEmail(ctx, email); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, domain.ErrEmailAlreadyExists
+	}
+	if taken, err := s.users.ExistsByUsername(ctx, username); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, domain.ErrUsernameAlreadyExists
+	}
+
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{
+		Email:    email,
+		Username: username,
+		Password: hashed,
+	}
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.users.Create(ctx, user); err != nil {
+			return err
+		}
+		return s.emails.SendVerification(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login authenticates by email when the identifier contains an "@" and by
+// username otherwise, and opens a session for the client. Users with
+// two-factor authentication get a login challenge instead of tokens. Unknown
+// users and wrong passwords both yield ErrInvalidCredentials; repeated
+// failures are throttled by the LoginGuard.
+func (s *UserService) Login(ctx context.Context, emailOrUsername, password string, client domain.ClientInfo) (*domain.LoginResult, error) {
+	emailOrUsername = strings.TrimSpace(emailOrUsername)
+	if _, err := validator.IsEmptyString(emailOrUsername); err != nil {
+		return nil, domain.ErrInvalidCredentials
+	}
+	if _, err := validator.IsEmptyString(password); err != nil {
+		return nil, domain.ErrInvalidCredentials
+	}
+	var (
+		user *domain.User
+		err  error
+	)
+	if strings.Contains(emailOrUsername, "@") {
+		user, err = s.users.GetByEmail(ctx, normalizeEmail(emailOrUsername))
+	} else {
+		user, err = s.users.GetByUsername(ctx, emailOrUsername)
+	}
+	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return nil, err
+	}
+
+	// failures are counted against the account, whichever of its names was
+	// typed; unknown names are counted as typed
+	identifier := "name:" + emailOrUsername
+	var userID *uint64
+	if user != nil {
+		identifier = userIdentifier(user.ID)
+		userID = &user.ID
+	}
+	if err := s.guard.Check(ctx, identifier, client.IP); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		s.hasher.VerifyDummy(password)
+		if err := s.guard.Fail(ctx, identifier, client.IP, nil); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrInvalidCredentials
+	}
+	ok, rehash := s.hasher.Verify(user.Password, password)
+	if !ok {
+		if err := s.guard.Fail(ctx, identifier, client.IP, userID); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrInvalidCredentials
+	}
+	if rehash {
+		if err := s.upgradePasswordHash(ctx, user, password); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.guard.Succeed(ctx, identifier); err != nil {
+		return nil, err
+	}
+	switch {
+	case user.Suspended():
+		return nil, domain.ErrAccountSuspended
+	case user.PasswordResetRequired:
+		return nil, domain.ErrPasswordResetRequired
+	}
+
+	if user.TwoFactorEnabled() {
+		token, expiresAt, err := s.twoFactor.StartChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.LoginResult{User: user, ChallengeToken: token, ChallengeExpiresAt: expiresAt}, nil
+	}
+
+	tokens, err := s.tokens.Issue(ctx, user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.LoginResult{User: user, Tokens: tokens}, nil
+}
+
+// upgradePasswordHash rehashes a verified password with the current
+// algorithm and parameters.
+func (s *UserService) upgradePasswordHash(ctx context.Context, user *domain.User, password string) error {
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := s.users.ReplacePasswordHash(ctx, user.ID, user.Password, hashed); err != nil {
+		return err
+	}
+	user.Password = hashed
+	return nil
+}
+
+// UpdateProfile changes the username and/or email. Empty values keep the
+// current ones. A new email is not applied here: a confirmation link is sent
+// to it and the address changes once the link is used.
+func (s *UserService) UpdateProfile(ctx context.Context, userID uint64, username, email string) (*domain.User, error) {
+	user, err := s.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	username = strings.TrimSpace(username)
+	email = normalizeEmail(email)
+	changeUsername := username != "" && username != user.Username
+	changeEmail := email != "" && email != user.Email
+	if !changeUsername && !changeEmail {
+		return nil, domain.ErrNothingToUpdate
+	}
+
+	if changeUsername {
+		if _, err := validator.IsValidUsername(username); err != nil {
+			return nil, err
+		}
+		if taken, err := s.users.ExistsByUsername(ctx, username); err != nil {
+			return nil, err
+		} else if taken {
+			return nil, domain.ErrUsernameAlreadyExists
+		}
+	}
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if changeEmail {
+			if err := s.emails.RequestChange(ctx, user, email); err != nil {
+				return err
+			}
+		}
+		if changeUsername {
+			user.Username = username
+			if err := s.users.Update(ctx, userID, user); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword sets a new password and ends all of the user's sessions.
+func (s *UserService) ChangePassword(ctx context.Context, userID uint64, oldPassword, newPassword string) error {
+	user, err := s.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if ok, _ := s.hasher.Verify(user.Password, oldPassword); !ok {
+		return domain.ErrPasswordMismatch
+	}
+	if _, err := validator.IsValidPassword(newPassword); err != nil {
+		return err
+	}
+	if err := s.policy.Check(newPassword, user.Email, user.Username); err != nil {
+		return err
+	}
+
+	hashed, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.users.UpdatePassword(ctx, userID, hashed); err != nil {
+			return err
+		}
+		return s.tokens.RevokeAllSessions(ctx, userID)
+	})
+}
+
+// UpdateSearchLanguage sets the Postgres text search configuration used to
+// index and query the user's notes, reindexing the existing ones.
+func (s *UserService) UpdateSearchLanguage(ctx context.Context, userID uint64, language string) error {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if _, err := validator.IsEmptyString(language); err != nil {
+		return domain.ErrInvalidSearchLanguage
+	}
+	exists, err := s.notes.SearchLanguageExists(ctx, language)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrInvalidSearchLanguage
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.users.UpdateSearchLanguage(ctx, userID, language); err != nil {
+			return err
+		}
+		return s.notes.SetSearchLanguage(ctx, userID, language)
+	})
+}
+
+func (s *UserService) DeleteAccount(ctx context.Context, userID uint64) error {
+	if userID == 0 {
+		return domain.ErrInvalidID
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.users.SoftDelete(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.notes.SoftDeleteByUserID(ctx, userID); err != nil {
+			return err
+		}
+		return s.tokens.RevokeAllSessions(ctx, userID)
+	})
+}
+
+func (s *UserService) PermanentDeleteAccount(ctx context.Context, userID uint64) error {
+	if userID == 0 {
+		return domain.ErrInvalidID
+	}
+	return s.users.HardDelete(ctx, userID)
+}
+
+func (s *UserService) GetByID(ctx context.Context, userID uint64) (*domain.User, error) {
+	if userID == 0 {
+		return nil, domain.ErrInvalidID
+	}
+	return s.users.GetByID(ctx, userID)
+}
+
+func (s *UserService) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return s.users.GetByEmail(ctx, normalizeEmail(email))
+}
+
+func (s *UserService) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	return s.users.GetByUsername(ctx, strings.TrimSpace(username))
+}
+
+func (s *UserService) ListUsers(ctx context.Context, limit, offset int) ([]*domain.User, int64, error) {
+	if _, err := validator.IsValidPagination(limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return s.users.List(ctx, limit, offset)
+}
+
+func (s *UserService) IsEmailTaken(ctx context.Context, email string) (bool, error) {
+	return s.users.ExistsByEmail(ctx, normalizeEmail(email))
+}
+
+func (s *UserService) IsUsernameTaken(ctx context.Context, username string) (bool, error) {
+	return s.users.ExistsByUsername(ctx, strings.TrimSpace(username))
+}
+
+func (s *UserService) GetTotalUserCount(ctx context.Context) (uint64, error) {
+	return s.users.Count(ctx)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// hibpPrefixLength is the length of the SHA-1 prefix the range files are
+// split by.
+const hibpPrefixLength = 5
+
+// BreachedPasswords looks passwords up in a local copy of the Have I Been
+// Pwned password list in its range format: one file per 5 character SHA-1
+// prefix, named <PREFIX>.txt, with lines of <SUFFIX>:<COUNT>, as written by
+// the official downloader. Only the file of the password's prefix is read.
+type BreachedPasswords struct {
+	dir      string
+	minCount int
+}
+
+// NewBreachedPasswords opens the range files in dir. Passwords seen fewer
+// than minCount times are accepted.
+func NewBreachedPasswords(dir string, minCount int) (*BreachedPasswords, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached passwords: %s is not a directory", dir)
+	}
+	return &BreachedPasswords{
+		dir:      dir,
+		minCount: max(minCount, 1),
+	}, nil
+}
+
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:hibpPrefixLength], hash[hibpPrefixLength:]
+
+	f, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		s, count, ok := strings.Cut(strings.TrimSpace(sc.Text()), ":")
+		if !ok || !strings.EqualFold(s, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return false, fmt.Errorf("breached passwords %s.txt: %w", prefix, err)
+		}
+		return n >= b.minCount, nil
+	}
+	return false, sc.Err()
+}
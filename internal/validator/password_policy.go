@@ -0,0 +1,100 @@
+package validator
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"unicode"
+
+	"github.com/maqsatto/Notes-API/internal/domain"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// PasswordPolicy rejects passwords that satisfy IsValidPassword but are
+// still easy to guess: known breached passwords, common passwords and
+// passwords built from the account's username or email address.
+type PasswordPolicy struct {
+	common   map[string]struct{}
+	breached *BreachedPasswords
+	personal bool
+}
+
+// NewPasswordPolicy creates a policy. breached may be nil to skip the
+// breach lookup.
+func NewPasswordPolicy(rejectCommon, rejectPersonal bool, breached *BreachedPasswords) *PasswordPolicy {
+	p := &PasswordPolicy{
+		breached: breached,
+		personal: rejectPersonal,
+	}
+	if rejectCommon {
+		p.common = parseCommonPasswords(commonPasswordList)
+	}
+	return p
+}
+
+// Check runs the policy for the password of the account with the given
+// email and username.
+func (p *PasswordPolicy) Check(password, email, username string) error {
+	if p.personal && containsPersonalInfo(password, email, username) {
+		return domain.ErrPasswordContainsPersonalInfo
+	}
+	if p.common != nil && p.isCommon(password) {
+		return domain.ErrPasswordTooCommon
+	}
+	if p.breached != nil {
+		found, err := p.breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if found {
+			return domain.ErrPasswordBreached
+		}
+	}
+	return nil
+}
+
+// isCommon matches the password ignoring case, and again without the
+// digits and symbols commonly added around a word to pass the character
+// class rules ("Password1!").
+func (p *PasswordPolicy) isCommon(password string) bool {
+	lower := strings.ToLower(password)
+	if _, ok := p.common[lower]; ok {
+		return true
+	}
+	base := strings.TrimFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	_, ok := p.common[base]
+	return ok && base != ""
+}
+
+// minPersonalInfoLength keeps very short usernames and local parts from
+// rejecting unrelated passwords.
+const minPersonalInfoLength = 3
+
+func containsPersonalInfo(password, email, username string) bool {
+	lower := strings.ToLower(password)
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	local, _, _ = strings.Cut(local, "+")
+	for _, v := range []string{strings.ToLower(username), local} {
+		if len(v) >= minPersonalInfoLength && strings.Contains(lower, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCommonPasswords(list string) map[string]struct{} {
+	set := make(map[string]struct{})
+	sc := bufio.NewScanner(strings.NewReader(list))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "# ") {
+			continue
+		}
+		set[strings.ToLower(line)] = struct{}{}
+	}
+	return set
+}
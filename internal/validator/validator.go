@@ -10,12 +10,18 @@ import (
 )
 
 const (
-	MinPasswordLength    = 8
-	MaxPasswordLength    = 128
-	MinUsernameLength    = 3
-	MaxUsernameLength    = 50
-	MaxNoteTitleLength   = 200
-	MaxNoteContentLength = 50000
+	MinPasswordLength     = 8
+	MaxPasswordLength     = 128
+	MinUsernameLength     = 3
+	MaxUsernameLength     = 50
+	MaxNoteTitleLength    = 200
+	MaxNoteContentLength  = 50000
+	MaxTagsPerNote        = 20
+	MaxTagLength          = 100
+	MaxNotebookNameLength = 100
+	MaxCommentLength      = 5000
+	MaxPageLimit          = 100
+	MaxSearchQueryLength  = 500
 )
 
 func ValidateUserRegister(email, username, password string) error {
@@ -58,6 +64,31 @@ func IsValidNote(title, content string) error {
 	return nil
 }
 
+func IsValidTags(tags []string) (bool, error) {
+	if len(tags) > MaxTagsPerNote {
+		return false, domain.ErrTooManyTags
+	}
+	for _, tag := range tags {
+		if _, err := IsEmptyString(tag); err != nil {
+			return false, domain.ErrInvalidTags
+		}
+		if len(tag) > MaxTagLength {
+			return false, domain.ErrInvalidTags
+		}
+	}
+	return true, nil
+}
+
+func IsValidPagination(limit, offset int) (bool, error) {
+	if limit <= 0 || limit > MaxPageLimit {
+		return false, domain.ErrInvalidLimit
+	}
+	if offset < 0 {
+		return false, domain.ErrInvalidOffset
+	}
+	return true, nil
+}
+
 func IsValidEmail(email string) (bool, error) {
 	if len(email) > 255 {
 		return false, domain.ErrInvalidEmail
@@ -176,6 +207,26 @@ func IsValidTitle(title string) (bool, error) {
 	return true, nil
 }
 
+func IsValidNotebookName(name string) (bool, error) {
+	if _, err := IsEmptyString(name); err != nil {
+		return false, domain.ErrInvalidNotebookName
+	}
+	if len(name) > MaxNotebookNameLength {
+		return false, domain.ErrInvalidNotebookName
+	}
+	return true, nil
+}
+
+func IsValidComment(body string) (bool, error) {
+	if _, err := IsEmptyString(body); err != nil {
+		return false, domain.ErrInvalidComment
+	}
+	if len(body) > MaxCommentLength {
+		return false, domain.ErrInvalidComment
+	}
+	return true, nil
+}
+
 func IsValidContent(content string) (bool, error) {
 	if _, err := IsEmptyString(content); err != nil {
 		return false, domain.ErrNoteContentEmpty